@@ -0,0 +1,140 @@
+package sidb
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Types with up to this many entries are counted exactly by ApproxCount.
+const approxCountExactLimit = 10000
+
+// ApproxCount recounts a cached count in the background once it is older
+// than this.
+const approxCountMaxAge = time.Minute
+
+// Counts of large types are cached here by ApproxCount, so they survive
+// restarts.
+const countsTableSQL = `CREATE TABLE IF NOT EXISTS counts (
+	"type" TEXT NOT NULL PRIMARY KEY,
+	"count" INTEGER NOT NULL,
+	"countedAt" INTEGER NOT NULL
+) WITHOUT ROWID`
+
+type CountEstimate struct {
+	Count int64
+	// Exact is set when Count was taken by this call. Otherwise it is the
+	// count as of CountedAt and is off by the writes made since.
+	Exact     bool
+	CountedAt int64
+	// Estimated is set when the type has not been counted yet and Count is
+	// a rough estimate from the table statistics, or a lower bound when
+	// there are none.
+	Estimated bool
+}
+
+// ApproxCount returns the number of entries of a type, quickly even for types
+// with millions of entries. Counts of large types are cached and returned
+// as they are, and refreshed in the background when they are more than a
+// minute old. Types without a cached count are counted exactly if they have
+// up to 10,000 entries, and otherwise estimated while they are counted in
+// the background.
+func (db *Database) ApproxCount(entryType string) (CountEstimate, error) {
+	estimate, cached, err := db.cachedCount(entryType)
+	if err != nil || estimate.Exact {
+		return estimate, err
+	}
+
+	if !cached || time.Duration(db.now()-estimate.CountedAt)*time.Millisecond > approxCountMaxAge {
+		go db.refreshCountInBackground(entryType)
+	}
+	return estimate, nil
+}
+
+// cachedCount returns the type's cached count, if any. Otherwise it counts
+// the type exactly if it is small, and estimates it if not.
+func (db *Database) cachedCount(entryType string) (estimate CountEstimate, cached bool, err error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	if db.connection == nil {
+		return estimate, false, ErrNoDbConnection
+	}
+
+	ctx, done := db.readContext()
+	defer done()
+
+	err = db.connection.QueryRowContext(ctx, "SELECT count, countedAt FROM counts WHERE type = ?", entryType).Scan(&estimate.Count, &estimate.CountedAt)
+	if err != sql.ErrNoRows {
+		return estimate, err == nil, err
+	}
+
+	err = db.connection.QueryRowContext(ctx, "SELECT COUNT(*) FROM (SELECT 1 FROM entries WHERE type = ? AND deletedAt IS NULL LIMIT ?)", entryType, approxCountExactLimit+1).Scan(&estimate.Count)
+	if err != nil {
+		return estimate, false, err
+	}
+	if estimate.Count <= approxCountExactLimit {
+		return CountEstimate{Count: estimate.Count, Exact: true, CountedAt: db.now()}, false, nil
+	}
+
+	// sqlite_stat1 exists once ANALYZE or PRAGMA optimize has run, and gives
+	// the average number of entries per type
+	var stat string
+	err = db.connection.QueryRowContext(ctx, "SELECT stat FROM sqlite_stat1 WHERE tbl = 'entries' AND idx = 'idx_entries_key'").Scan(&stat)
+	if err == nil {
+		var rows, perType int64
+		if _, err := fmt.Sscan(stat, &rows, &perType); err == nil {
+			estimate.Count = max(estimate.Count, perType)
+		}
+	}
+	estimate.Estimated = true
+	estimate.CountedAt = db.now()
+	return estimate, false, nil
+}
+
+// refreshCount counts the type exactly and caches the result.
+func (db *Database) refreshCount(entryType string) (CountEstimate, error) {
+	count, err := db.CountWhere(QueryParams{Type: &entryType})
+	if err != nil {
+		return CountEstimate{}, err
+	}
+	estimate := CountEstimate{Count: count, Exact: true, CountedAt: db.now()}
+
+	defer db.lockWrite(false)()
+
+	if db.connection == nil {
+		return estimate, ErrNoDbConnection
+	}
+
+	ctx, done := db.writeContext()
+	defer done()
+
+	_, err = db.connection.ExecContext(ctx, "INSERT INTO counts (type, count, countedAt) VALUES (?, ?, ?) ON CONFLICT (type) DO UPDATE SET count = excluded.count, countedAt = excluded.countedAt", entryType, estimate.Count, estimate.CountedAt)
+	return estimate, err
+}
+
+func (db *Database) refreshCountInBackground(entryType string) {
+	db.countsMutex.Lock()
+	if db.refreshingCounts[entryType] {
+		db.countsMutex.Unlock()
+		return
+	}
+	if db.refreshingCounts == nil {
+		db.refreshingCounts = make(map[string]bool)
+	}
+	db.refreshingCounts[entryType] = true
+	db.countsMutex.Unlock()
+
+	defer func() {
+		db.countsMutex.Lock()
+		delete(db.refreshingCounts, entryType)
+		db.countsMutex.Unlock()
+	}()
+
+	// A failed refresh leaves the old count for the next call to retry
+	db.refreshCount(entryType)
+}
+
+func (store *Store[T]) ApproxCount() (CountEstimate, error) {
+	return store.db.ApproxCount(store.entryType)
+}
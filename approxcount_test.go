@@ -0,0 +1,88 @@
+package sidb
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestApproxCount(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_approx_count"
+	var now atomic.Int64
+	now.Store(1000)
+	db, err := Init(namespace, name, WithClock(func() int64 { return now.Load() }))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	if err := db.Upsert(EntryInput{Type: "small", Key: "a", Value: []byte("a")}); err != nil {
+		t.Fatalf("Failed to upsert: %v", err)
+	}
+	estimate, err := db.ApproxCount("small")
+	if err != nil {
+		t.Fatalf("Failed ApproxCount: %v", err)
+	}
+	if estimate.Count != 1 || !estimate.Exact {
+		t.Errorf("Expected an exact count of 1, got %+v", estimate)
+	}
+
+	// Inserted in SQL, since going through BulkUpsert would dominate the test
+	insert := `WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < ?)
+		INSERT INTO entries (key, type, timestamp, value) SELECT 'key_' || (i + ?), 'large', 1, x'00' FROM n`
+	if _, err := db.connection.Exec(insert, approxCountExactLimit+10, 0); err != nil {
+		t.Fatalf("Failed to insert entries: %v", err)
+	}
+
+	estimate, err = db.ApproxCount("large")
+	if err != nil {
+		t.Fatalf("Failed ApproxCount: %v", err)
+	}
+	if estimate.Count != approxCountExactLimit+1 || estimate.Exact || !estimate.Estimated {
+		t.Errorf("Expected a lower bound while the type is counted, got %+v", estimate)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for estimate.Estimated && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		if estimate, err = db.ApproxCount("large"); err != nil {
+			t.Fatalf("Failed ApproxCount: %v", err)
+		}
+	}
+	if estimate.Count != approxCountExactLimit+10 || estimate.Estimated {
+		t.Errorf("Expected the background count, got %+v", estimate)
+	}
+
+	if _, err := db.connection.Exec(insert, 5, approxCountExactLimit+10); err != nil {
+		t.Fatalf("Failed to insert entries: %v", err)
+	}
+	estimate, err = db.ApproxCount("large")
+	if err != nil {
+		t.Fatalf("Failed ApproxCount: %v", err)
+	}
+	if estimate.Count != approxCountExactLimit+10 || estimate.Exact || estimate.CountedAt != 1000 {
+		t.Errorf("Expected the cached count, got %+v", estimate)
+	}
+
+	// An old count is returned once more while it is refreshed
+	now.Add(2 * time.Minute.Milliseconds())
+	estimate, err = db.ApproxCount("large")
+	if err != nil {
+		t.Fatalf("Failed ApproxCount: %v", err)
+	}
+	if estimate.Count != approxCountExactLimit+10 {
+		t.Errorf("Expected the stale count, got %+v", estimate)
+	}
+
+	deadline = time.Now().Add(5 * time.Second)
+	for estimate.Count != approxCountExactLimit+15 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		if estimate, err = db.ApproxCount("large"); err != nil {
+			t.Fatalf("Failed ApproxCount: %v", err)
+		}
+	}
+	if estimate.Count != approxCountExactLimit+15 || estimate.CountedAt != now.Load() {
+		t.Errorf("Expected the refreshed count, got %+v", estimate)
+	}
+}
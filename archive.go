@@ -0,0 +1,143 @@
+package sidb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+)
+
+var ErrArchiveToSelf = errors.New("cannot archive a database into itself")
+
+// Archive moves the entries matching params into dest, replacing any entries
+// there with the same type and key. Immutable entries cannot be deleted and
+// stay in db. The copy and the delete run in one transaction, so a failure
+// leaves both databases untouched and readers never see the entries in both
+// or neither. SQLite does not make commits across WAL databases atomic on a
+// crash though: one interrupting the commit can leave the entries in both
+// databases, and running the same Archive again completes the move.
+func (db *Database) Archive(params QueryParams, dest *Database) (archived int64, err error) {
+	defer translateImmutable(&err)
+
+	if dest == db || dest.Path == db.Path {
+		return 0, ErrArchiveToSelf
+	}
+
+	defer lockWriters(db, dest, params.matchedTypes()...)()
+
+	if db.connection == nil || dest.connection == nil {
+		return 0, ErrNoDbConnection
+	}
+
+	ctx, done := db.writeContext()
+	defer done()
+
+	query, args, err := db.selectQuery(params, "key, type, immutable")
+	if err != nil {
+		return 0, err
+	}
+	where := "(key, type) IN (SELECT key, type FROM (" + query + ") WHERE immutable = 0)"
+
+	// ATTACH only applies to one connection of the pool
+	conn, err := db.connection.Conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "ATTACH DATABASE ? AS archive", dest.Path); err != nil {
+		return 0, err
+	}
+	// Detached even after a timeout, so the pooled connection can be reused
+	defer conn.ExecContext(context.Background(), "DETACH DATABASE archive")
+
+	columns, err := sharedColumns(ctx, conn, "archive")
+	if err != nil {
+		return 0, err
+	}
+	columnList := strings.Join(columns, ", ")
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "INSERT INTO archive.entries ("+columnList+") SELECT "+columnList+" FROM main.entries WHERE "+where+replaceColumns(columns), args...)
+	if err != nil {
+		return 0, err
+	}
+	copied, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	statement, statementArgs := db.deleteStatement(where, args)
+	if _, err := tx.ExecContext(ctx, statement, statementArgs...); err != nil {
+		return 0, err
+	}
+	return copied, tx.Commit()
+}
+
+// lockWriters takes the write locks of two databases for a bulk write to
+// entryTypes, in a stable order, so that operations spanning both in opposite
+// directions cannot deadlock.
+func lockWriters(a *Database, b *Database, entryTypes ...string) func() {
+	if b.Path < a.Path {
+		a, b = b, a
+	}
+	unlockA := a.lockWrite(true, entryTypes...)
+	unlockB := b.lockWrite(true, entryTypes...)
+	return func() {
+		unlockB()
+		unlockA()
+	}
+}
+
+// sharedColumns returns the quoted columns the entries tables of the main and
+// the attached schema have in common.
+func sharedColumns(ctx context.Context, conn *sql.Conn, schema string) ([]string, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT name FROM pragma_table_info('entries') WHERE name IN (SELECT name FROM pragma_table_info('entries', ?))", schema)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			return nil, err
+		}
+		columns = append(columns, `"`+column+`"`)
+	}
+	return columns, rows.Err()
+}
+
+// replaceColumns returns the upsert clause replacing every column of an
+// existing entry. Unlike INSERT OR REPLACE it runs the update triggers, so
+// immutable entries are not replaced.
+func replaceColumns(columns []string) string {
+	var assignments []string
+	for _, column := range columns {
+		assignments = append(assignments, column+" = excluded."+column)
+	}
+	return " ON CONFLICT(key, type) DO UPDATE SET " + strings.Join(assignments, ", ")
+}
+
+func execInTx(ctx context.Context, conn *sql.Conn, query string, args []interface{}) (int64, error) {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
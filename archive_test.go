@@ -0,0 +1,115 @@
+package sidb
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestArchive(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	db, err := Init(namespace, "test_archive_hot")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	archive, err := Init(namespace, "test_archive_2024")
+	if err != nil {
+		t.Fatalf("Failed to initialize archive: %v", err)
+	}
+	defer archive.Drop()
+
+	var entries []EntryInput
+	for i := 0; i < 10; i++ {
+		entries = append(entries, EntryInput{Type: "event", Key: fmt.Sprintf("event_%d", i), Value: []byte("data"), Timestamp: ptr(int64(i))})
+	}
+	entries = append(entries, EntryInput{Type: "event", Key: "pinned", Value: []byte("data"), Timestamp: ptr(int64(0)), Immutable: true})
+	if err := db.BulkUpsert(entries); err != nil {
+		t.Fatalf("Failed to put entries: %v", err)
+	}
+
+	eventType := "event"
+	before := int64(4)
+	archived, err := db.Archive(QueryParams{Type: &eventType, To: &before}, archive)
+	if err != nil {
+		t.Fatalf("Failed to archive: %v", err)
+	}
+	if archived != 5 {
+		t.Errorf("Expected 5 archived entries, got %d", archived)
+	}
+
+	hot, _ := db.Count()
+	cold, _ := archive.Count()
+	// The immutable entry stays behind
+	if hot != 6 || cold != 5 {
+		t.Errorf("Expected 6 hot and 5 archived entries, got %d and %d", hot, cold)
+	}
+
+	entry, err := archive.Get("event", "event_3")
+	if err != nil || entry == nil || entry.Timestamp != 3 || string(entry.Value) != "data" {
+		t.Errorf("Expected event_3 to be archived as is, got %+v (%v)", entry, err)
+	}
+
+	// The attached archive is detached again
+	if _, err := db.Archive(QueryParams{Type: &eventType}, archive); err != nil {
+		t.Fatalf("Failed to archive again: %v", err)
+	}
+	if cold, _ := archive.Count(); cold != 10 {
+		t.Errorf("Expected 10 archived entries, got %d", cold)
+	}
+}
+
+func TestArchiveLocking(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	a, err := Init(namespace, "test_archive_a")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer a.Drop()
+
+	b, err := Init(namespace, "test_archive_b")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer b.Drop()
+
+	eventType := "event"
+	if _, err := a.Archive(QueryParams{Type: &eventType}, a); err != ErrArchiveToSelf {
+		t.Errorf("Expected ErrArchiveToSelf, got %v", err)
+	}
+
+	for i := 0; i < 50; i++ {
+		key := fmt.Sprintf("event_%d", i)
+		if err := a.Upsert(EntryInput{Type: eventType, Key: key, Value: []byte("a")}); err != nil {
+			t.Fatalf("Failed to put entry: %v", err)
+		}
+		if err := b.Upsert(EntryInput{Type: eventType, Key: key, Value: []byte("b")}); err != nil {
+			t.Fatalf("Failed to put entry: %v", err)
+		}
+	}
+
+	// Archives in opposite directions must not deadlock
+	errs := make(chan error, 2)
+	for _, pair := range [][2]*Database{{a, b}, {b, a}} {
+		go func() {
+			for i := 0; i < 20; i++ {
+				if _, err := pair[0].Archive(QueryParams{Type: &eventType}, pair[1]); err != nil {
+					errs <- err
+					return
+				}
+			}
+			errs <- nil
+		}()
+	}
+	for range 2 {
+		if err := <-errs; err != nil {
+			t.Errorf("Failed to archive: %v", err)
+		}
+	}
+
+	countA, _ := a.Count()
+	countB, _ := b.Count()
+	if countA+countB != 50 {
+		t.Errorf("Expected 50 entries across both databases, got %d and %d", countA, countB)
+	}
+}
@@ -0,0 +1,158 @@
+package sidb
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+const (
+	defaultBusyRetries = 5
+	defaultBusyBackoff = 10 * time.Millisecond
+)
+
+// WithBusyTimeout sets how long SQLite waits for another process to release
+// the file before a statement fails with "database is locked", 5 seconds by
+// default.
+func WithBusyTimeout(timeout time.Duration) Option {
+	return func(options *Options) {
+		options.busyTimeout = timeout
+	}
+}
+
+// WithBusyRetries sets how many times a write that still finds the file
+// locked is retried, waiting backoff before the first retry and twice as long
+// before each next one. By default writes are retried 5 times starting at
+// 10ms. A negative retries turns retrying off.
+//
+// Retries cover the cases the busy timeout does not, such as a transaction
+// that cannot upgrade to a write lock. Statements are retried on their own,
+// so a transaction whose snapshot went stale still fails and must be redone.
+func WithBusyRetries(retries int, backoff time.Duration) Option {
+	return func(options *Options) {
+		options.busyRetries = retries
+		options.busyBackoff = backoff
+	}
+}
+
+func (options *Options) busyParams() []string {
+	if options.busyTimeout <= 0 {
+		return nil
+	}
+	return []string{fmt.Sprintf("_busy_timeout=%d", options.busyTimeout.Milliseconds())}
+}
+
+type busyRetry struct {
+	retries int
+	backoff time.Duration
+}
+
+func (options *Options) busyRetry() busyRetry {
+	retry := busyRetry{retries: options.busyRetries, backoff: options.busyBackoff}
+	if retry.retries == 0 {
+		retry.retries = defaultBusyRetries
+	}
+	if retry.backoff <= 0 {
+		retry.backoff = defaultBusyBackoff
+	}
+	return retry
+}
+
+func isBusy(err error) bool {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) || sqliteErr.ExtendedCode == sqlite3.ErrBusySnapshot {
+		return false
+	}
+	return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+}
+
+// do runs fn until it succeeds, fails with something other than a busy error,
+// runs out of retries or ctx is done.
+func (retry busyRetry) do(ctx context.Context, fn func() error) error {
+	backoff := retry.backoff
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if attempt >= retry.retries || !isBusy(err) {
+			return err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+// retryConn retries the statements that write, and the BEGIN and COMMIT
+// around them, when they fail because the file is locked.
+type retryConn struct {
+	*sqlite3.SQLiteConn
+	retry busyRetry
+}
+
+func (conn *retryConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (result driver.Result, err error) {
+	err = conn.retry.do(ctx, func() error {
+		result, err = conn.SQLiteConn.ExecContext(ctx, query, args)
+		return err
+	})
+	return result, err
+}
+
+func (conn *retryConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	stmt, err := conn.SQLiteConn.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &retryStmt{SQLiteStmt: stmt.(*sqlite3.SQLiteStmt), retry: conn.retry}, nil
+}
+
+func (conn *retryConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	err := conn.retry.do(ctx, func() error {
+		_, err := conn.SQLiteConn.BeginTx(ctx, opts)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return retryTx{conn}, nil
+}
+
+type retryStmt struct {
+	*sqlite3.SQLiteStmt
+	retry busyRetry
+}
+
+func (stmt *retryStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (result driver.Result, err error) {
+	err = stmt.retry.do(ctx, func() error {
+		result, err = stmt.SQLiteStmt.ExecContext(ctx, args)
+		return err
+	})
+	return result, err
+}
+
+// retryTx replaces the driver's transaction, whose Commit rolls back as soon
+// as COMMIT fails even though a busy COMMIT can be retried.
+type retryTx struct {
+	conn *retryConn
+}
+
+func (tx retryTx) Commit() error {
+	_, err := tx.conn.ExecContext(context.Background(), "COMMIT", nil)
+	if err != nil {
+		tx.Rollback()
+	}
+	return err
+}
+
+func (tx retryTx) Rollback() error {
+	_, err := tx.conn.SQLiteConn.ExecContext(context.Background(), "ROLLBACK", nil)
+	return err
+}
@@ -0,0 +1,71 @@
+package sidb
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBusyRetries(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_busy_retries"
+	holder, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer holder.Drop()
+
+	// Another handle on the same file stands in for another process
+	patient, err := Init(namespace, name, WithBusyTimeout(time.Millisecond), WithBusyRetries(10, 5*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer patient.Close()
+	impatient, err := Init(namespace, name, WithBusyTimeout(time.Millisecond), WithBusyRetries(-1, 0))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer impatient.Close()
+
+	release := holdWriteLock(t, holder)
+	if err := impatient.Upsert(EntryInput{Type: "note", Key: "a", Value: []byte("a")}); !isBusy(err) {
+		t.Fatalf("Expected a busy error without retries, got %v", err)
+	}
+	go release(50 * time.Millisecond)
+	if err := patient.Upsert(EntryInput{Type: "note", Key: "b", Value: []byte("b")}); err != nil {
+		t.Fatalf("Expected the write to be retried until the lock was released, got %v", err)
+	}
+
+	// Writes in transactions retry their statements
+	release = holdWriteLock(t, holder)
+	go release(50 * time.Millisecond)
+	if err := patient.BulkUpsert([]EntryInput{{Type: "note", Key: "c", Value: []byte("c")}}); err != nil {
+		t.Fatalf("Expected BulkUpsert to be retried until the lock was released, got %v", err)
+	}
+
+	count, err := holder.Count()
+	if err != nil {
+		t.Fatalf("Failed to count: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 entries, got %d", count)
+	}
+}
+
+// holdWriteLock takes the file's write lock from a connection of db and
+// returns a function releasing it after a delay.
+func holdWriteLock(t *testing.T, db *Database) func(time.Duration) {
+	ctx := context.Background()
+	conn, err := db.connection.Conn(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get connection: %v", err)
+	}
+	if _, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+		t.Fatalf("Failed to take the write lock: %v", err)
+	}
+	return func(delay time.Duration) {
+		time.Sleep(delay)
+		conn.ExecContext(ctx, "COMMIT")
+		conn.Close()
+	}
+}
@@ -0,0 +1,104 @@
+package sidb
+
+import (
+	"sync"
+	"time"
+)
+
+// A CacheStore keeps loaded values in a Store, each with an expiry time kept
+// in its sorting index, so the store's own sorting index derivation is not
+// used.
+type CacheStore[T any] struct {
+	store   *Store[T]
+	options CacheOptions
+
+	mutex      sync.Mutex
+	refreshing map[string]bool
+}
+
+type CacheOptions struct {
+	TTL time.Duration
+	// How long past expiry a value is still returned while it is reloaded in
+	// the background. Zero always reloads expired values before returning.
+	StaleWhileRevalidate time.Duration
+}
+
+func MakeCacheStore[T any](store *Store[T], options CacheOptions) *CacheStore[T] {
+	return &CacheStore[T]{store: store, options: options, refreshing: make(map[string]bool)}
+}
+
+func (cache *CacheStore[T]) GetOrLoad(key string, loader func() (T, error)) (T, error) {
+	return cache.GetOrLoadWithTTL(key, cache.options.TTL, loader)
+}
+
+// GetOrLoadWithTTL returns the cached value for key, calling loader to fill
+// or refresh it. A value loaded by this call expires after ttl.
+func (cache *CacheStore[T]) GetOrLoadWithTTL(key string, ttl time.Duration, loader func() (T, error)) (T, error) {
+	var zero T
+	db := cache.store.db
+
+	entry, err := db.Get(cache.store.entryType, cache.store.key(key))
+	if err != nil {
+		return zero, err
+	}
+
+	now := db.now()
+	if entry != nil && entry.SortingIndex != nil {
+		expiresAt := *entry.SortingIndex
+		if now < expiresAt+cache.options.StaleWhileRevalidate.Milliseconds() {
+			value, err := cache.store.decode(key, entry.Value)
+			if err != nil {
+				return zero, err
+			}
+			if now >= expiresAt {
+				cache.refresh(key, ttl, loader)
+			}
+			return value, nil
+		}
+	}
+
+	return cache.load(key, ttl, loader)
+}
+
+func (cache *CacheStore[T]) load(key string, ttl time.Duration, loader func() (T, error)) (T, error) {
+	value, err := loader()
+	if err != nil {
+		return value, err
+	}
+	return value, cache.Set(key, value, ttl)
+}
+
+// refresh reloads key in the background, unless it is already being reloaded.
+// Errors are dropped and the stale value is kept.
+func (cache *CacheStore[T]) refresh(key string, ttl time.Duration, loader func() (T, error)) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if cache.refreshing[key] {
+		return
+	}
+	cache.refreshing[key] = true
+
+	go func() {
+		cache.load(key, ttl, loader)
+
+		cache.mutex.Lock()
+		delete(cache.refreshing, key)
+		cache.mutex.Unlock()
+	}()
+}
+
+// Set stores value under key, expiring after ttl.
+func (cache *CacheStore[T]) Set(key string, value T, ttl time.Duration) error {
+	input, err := cache.store.entryInput(StoreEntryInput[T]{Key: key, Value: value})
+	if err != nil {
+		return err
+	}
+	expiresAt := cache.store.db.now() + ttl.Milliseconds()
+	input.SortingIndex = &expiresAt
+	return cache.store.db.Upsert(input)
+}
+
+func (cache *CacheStore[T]) Invalidate(key string) error {
+	return cache.store.Delete(key)
+}
@@ -0,0 +1,82 @@
+package sidb
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCacheStore(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_cache"
+	now := int64(1000)
+	db, err := Init(namespace, name, WithClock(func() int64 { return now }))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	store := MakeStore(db, "test_type", serializeTestItem, deserializeTestItem, nil)
+	cache := MakeCacheStore(store, CacheOptions{TTL: time.Second, StaleWhileRevalidate: time.Second})
+
+	loads := 0
+	refreshed := make(chan struct{}, 1)
+	loader := func() (testItem, error) {
+		loads++
+		if loads > 1 {
+			defer func() { refreshed <- struct{}{} }()
+		}
+		return testItem{Name: "item", Value: loads}, nil
+	}
+
+	for i := 0; i < 2; i++ {
+		item, err := cache.GetOrLoad("key", loader)
+		if err != nil {
+			t.Fatalf("Failed to get or load: %v", err)
+		}
+		if item.Value != 1 || loads != 1 {
+			t.Errorf("Expected the first load to be cached, got %+v after %d loads", item, loads)
+		}
+	}
+
+	// Expired but within the stale window: the stale value comes back at once
+	now += 1500
+	item, err := cache.GetOrLoad("key", loader)
+	if err != nil {
+		t.Fatalf("Failed to get or load: %v", err)
+	}
+	if item.Value != 1 {
+		t.Errorf("Expected the stale value, got %+v", item)
+	}
+	select {
+	case <-refreshed:
+	case <-time.After(time.Second):
+		t.Fatalf("Expected a background refresh")
+	}
+	for {
+		cache.mutex.Lock()
+		refreshing := len(cache.refreshing)
+		cache.mutex.Unlock()
+		if refreshing == 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	item, err = cache.GetOrLoad("key", loader)
+	if err != nil {
+		t.Fatalf("Failed to get or load: %v", err)
+	}
+	if item.Value != 2 {
+		t.Errorf("Expected the refreshed value, got %+v", item)
+	}
+
+	// Past the stale window the value is reloaded before returning
+	now += 5000
+	item, err = cache.GetOrLoadWithTTL("key", time.Minute, loader)
+	<-refreshed
+	if err != nil {
+		t.Fatalf("Failed to get or load: %v", err)
+	}
+	if item.Value != 3 {
+		t.Errorf("Expected a fresh load, got %+v", item)
+	}
+}
@@ -0,0 +1,32 @@
+package sidb
+
+import "time"
+
+// Calendar ranges are returned as inclusive millisecond bounds, matching the
+// From/To semantics of QueryParams. Weeks start on Monday.
+
+func DayRange(t time.Time, location *time.Location) (int64, int64) {
+	t = inLocation(t, location)
+	start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	return start.UnixMilli(), start.AddDate(0, 0, 1).UnixMilli() - 1
+}
+
+func WeekRange(t time.Time, location *time.Location) (int64, int64) {
+	t = inLocation(t, location)
+	daysSinceMonday := (int(t.Weekday()) + 6) % 7
+	start := time.Date(t.Year(), t.Month(), t.Day()-daysSinceMonday, 0, 0, 0, 0, t.Location())
+	return start.UnixMilli(), start.AddDate(0, 0, 7).UnixMilli() - 1
+}
+
+func MonthRange(t time.Time, location *time.Location) (int64, int64) {
+	t = inLocation(t, location)
+	start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	return start.UnixMilli(), start.AddDate(0, 1, 0).UnixMilli() - 1
+}
+
+func inLocation(t time.Time, location *time.Location) time.Time {
+	if location == nil {
+		return t
+	}
+	return t.In(location)
+}
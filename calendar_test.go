@@ -0,0 +1,54 @@
+package sidb
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWeekRange(t *testing.T) {
+	location := time.FixedZone("UTC-5", -5*60*60)
+
+	// Sunday 2024-03-10 22:00 in UTC-5 is already Monday in UTC
+	from, to := WeekRange(time.Date(2024, 3, 11, 3, 0, 0, 0, time.UTC), location)
+
+	expectedFrom := time.Date(2024, 3, 4, 0, 0, 0, 0, location).UnixMilli()
+	expectedTo := time.Date(2024, 3, 11, 0, 0, 0, 0, location).UnixMilli() - 1
+	if from != expectedFrom || to != expectedTo {
+		t.Errorf("Expected range [%d, %d], got [%d, %d]", expectedFrom, expectedTo, from, to)
+	}
+}
+
+func TestQueryByDay(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_calendar"
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	location := time.FixedZone("UTC+9", 9*60*60)
+	entryType := "test_type"
+	err = db.BulkUpsert([]EntryInput{
+		// 2024-03-10 23:30 UTC is 2024-03-11 08:30 in UTC+9
+		{Type: entryType, Value: []byte("data_1"), Key: "key_1", Timestamp: ptr(time.Date(2024, 3, 10, 23, 30, 0, 0, time.UTC).UnixMilli())},
+		{Type: entryType, Value: []byte("data_2"), Key: "key_2", Timestamp: ptr(time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC).UnixMilli())},
+		{Type: entryType, Value: []byte("data_3"), Key: "key_3", Timestamp: ptr(time.Date(2024, 3, 11, 16, 0, 0, 0, time.UTC).UnixMilli())},
+	})
+	if err != nil {
+		t.Fatalf("Failed to put entries: %v", err)
+	}
+
+	day := time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC)
+	entries, err := db.Query(QueryParams{Type: &entryType, Day: &day, Location: location})
+	if err != nil {
+		t.Fatalf("Failed to query entries: %v", err)
+	}
+
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(entries))
+	}
+	if entries[0].Key != "key_1" {
+		t.Errorf("Unexpected entry: %+v", entries[0])
+	}
+}
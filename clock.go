@@ -0,0 +1,36 @@
+package sidb
+
+import (
+	"sync"
+	"time"
+)
+
+// A Clock returns the current time in Unix milliseconds. It stamps entries
+// written without a timestamp, as well as createdAt, updatedAt and deletedAt.
+type Clock func() int64
+
+func SystemClock() int64 {
+	return time.Now().UnixMilli()
+}
+
+// MonotonicClock returns a clock that never repeats or goes back: when the
+// system clock has not advanced since the last reading, it returns the last
+// reading plus one millisecond.
+func MonotonicClock() Clock {
+	var mutex sync.Mutex
+	var last int64
+	return func() int64 {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		last = max(last+1, SystemClock())
+		return last
+	}
+}
+
+func (db *Database) now() int64 {
+	if db.options.clock != nil {
+		return db.options.clock()
+	}
+	return SystemClock()
+}
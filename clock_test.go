@@ -0,0 +1,44 @@
+package sidb
+
+import "testing"
+
+func TestWithClock(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_clock"
+	now := int64(1000)
+	db, err := Init(namespace, name, WithClock(func() int64 { return now }))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	entryType := "test_type"
+	if err := db.Upsert(EntryInput{Type: entryType, Value: []byte("data"), Key: "key"}); err != nil {
+		t.Fatalf("Failed to put entry: %v", err)
+	}
+
+	now = 2000
+	if err := db.Update(EntryInput{Type: entryType, Value: []byte("changed"), Key: "key"}); err != nil {
+		t.Fatalf("Failed to update entry: %v", err)
+	}
+
+	entry, err := db.Get(entryType, "key")
+	if err != nil {
+		t.Fatalf("Failed to get entry: %v", err)
+	}
+	if entry.Timestamp != 1000 || entry.CreatedAt != 1000 || entry.UpdatedAt != 2000 {
+		t.Errorf("Expected times from the clock, got %+v", entry)
+	}
+}
+
+func TestMonotonicClock(t *testing.T) {
+	clock := MonotonicClock()
+	last := clock()
+	for i := 0; i < 1000; i++ {
+		next := clock()
+		if next <= last {
+			t.Fatalf("Expected %d to be after %d", next, last)
+		}
+		last = next
+	}
+}
@@ -0,0 +1,333 @@
+// Command sidbgen generates typed store constructors and helpers for structs
+// marked with a sidb:store directive:
+//
+//	//sidb:store type=notes grouping=Folder sortingIndex=Position index=Author maxAge=720h
+//	type Note struct {
+//		Folder   string
+//		Position int64
+//		Author   string
+//		Text     string
+//	}
+//
+// type is the entry type, defaulting to the lowercased struct name. grouping
+// names a string field stored as the entry grouping and sortingIndex an int64
+// field stored as the sorting index. index lists comma separated fields to
+// index: CreateNoteIndexes creates an index on each and QueryNoteByAuthor
+// looks entries up by it. maxAge and maxPerGrouping define a retention rule
+// returned by NoteRetention. Values are encoded as JSON. Run it in a package
+// directory, typically from go:generate; it writes sidb_gen.go.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const directive = "//sidb:store"
+
+const outputName = "sidb_gen.go"
+
+type storeDefinition struct {
+	Name           string
+	EntryType      string
+	Grouping       string
+	SortingIndex   string
+	Indexes        []indexDefinition
+	MaxAge         time.Duration
+	MaxPerGrouping int
+}
+
+type indexDefinition struct {
+	Field     string
+	FieldType string
+	JSONKey   string
+}
+
+// Fields of these types can be indexed, as json_extract returns them as
+// SQLite values they compare equal to.
+var indexableTypes = map[string]bool{"string": true, "int": true, "int64": true, "float64": true, "bool": true}
+
+func main() {
+	dir := "."
+	if len(os.Args) > 1 {
+		dir = os.Args[1]
+	}
+
+	packageName, definitions, err := parseDir(dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(definitions) == 0 {
+		log.Fatalf("no %s directives found in %s", directive, dir)
+	}
+
+	source, err := generate(packageName, definitions)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, outputName), source, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func parseDir(dir string) (string, []storeDefinition, error) {
+	fileSet := token.NewFileSet()
+	packages, err := parser.ParseDir(fileSet, dir, func(info os.FileInfo) bool {
+		return info.Name() != outputName && !strings.HasSuffix(info.Name(), "_test.go")
+	}, parser.ParseComments)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(packages) != 1 {
+		return "", nil, fmt.Errorf("expected one package in %s, found %d", dir, len(packages))
+	}
+
+	var packageName string
+	var definitions []storeDefinition
+	for name, pkg := range packages {
+		packageName = name
+		for _, file := range pkg.Files {
+			found, err := parseFile(file)
+			if err != nil {
+				return "", nil, err
+			}
+			definitions = append(definitions, found...)
+		}
+	}
+
+	sort.Slice(definitions, func(i, j int) bool { return definitions[i].Name < definitions[j].Name })
+	return packageName, definitions, nil
+}
+
+func parseFile(file *ast.File) ([]storeDefinition, error) {
+	var definitions []storeDefinition
+	for _, decl := range file.Decls {
+		genDecl, ok := decl.(*ast.GenDecl)
+		if !ok || genDecl.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range genDecl.Specs {
+			typeSpec := spec.(*ast.TypeSpec)
+			// A declaration without parentheses keeps its comment on the
+			// declaration rather than the spec
+			doc := typeSpec.Doc
+			if doc == nil && !genDecl.Lparen.IsValid() {
+				doc = genDecl.Doc
+			}
+			if doc == nil {
+				continue
+			}
+			for _, comment := range doc.List {
+				if !strings.HasPrefix(comment.Text, directive) {
+					continue
+				}
+				structType, ok := typeSpec.Type.(*ast.StructType)
+				if !ok {
+					return nil, fmt.Errorf("%s: %s must be a struct", directive, typeSpec.Name.Name)
+				}
+				definition, err := parseDirective(typeSpec.Name.Name, strings.TrimPrefix(comment.Text, directive), structType)
+				if err != nil {
+					return nil, err
+				}
+				definitions = append(definitions, definition)
+			}
+		}
+	}
+	return definitions, nil
+}
+
+func parseDirective(name string, arguments string, structType *ast.StructType) (storeDefinition, error) {
+	definition := storeDefinition{Name: name, EntryType: strings.ToLower(name)}
+
+	fields := make(map[string]string)
+	jsonKeys := make(map[string]string)
+	for _, field := range structType.Fields.List {
+		var tag reflect.StructTag
+		if field.Tag != nil {
+			if unquoted, err := strconv.Unquote(field.Tag.Value); err == nil {
+				tag = reflect.StructTag(unquoted)
+			}
+		}
+		for _, fieldName := range field.Names {
+			if ident, ok := field.Type.(*ast.Ident); ok {
+				fields[fieldName.Name] = ident.Name
+			}
+			jsonKeys[fieldName.Name] = fieldName.Name
+			if jsonName, _, _ := strings.Cut(tag.Get("json"), ","); jsonName != "" {
+				jsonKeys[fieldName.Name] = jsonName
+			}
+		}
+	}
+
+	for _, argument := range strings.Fields(arguments) {
+		option, value, ok := strings.Cut(argument, "=")
+		if !ok {
+			return definition, fmt.Errorf("%s on %s: expected option=value, got %q", directive, name, argument)
+		}
+		switch option {
+		case "type":
+			definition.EntryType = value
+		case "grouping":
+			if fields[value] != "string" {
+				return definition, fmt.Errorf("%s on %s: grouping field %s must be a string", directive, name, value)
+			}
+			definition.Grouping = value
+		case "sortingIndex":
+			if fields[value] != "int64" {
+				return definition, fmt.Errorf("%s on %s: sortingIndex field %s must be an int64", directive, name, value)
+			}
+			definition.SortingIndex = value
+		case "index":
+			for _, field := range strings.Split(value, ",") {
+				if !indexableTypes[fields[field]] {
+					return definition, fmt.Errorf("%s on %s: index field %s must be a string, number or bool", directive, name, field)
+				}
+				if jsonKeys[field] == "-" {
+					return definition, fmt.Errorf("%s on %s: index field %s is not encoded", directive, name, field)
+				}
+				definition.Indexes = append(definition.Indexes, indexDefinition{Field: field, FieldType: fields[field], JSONKey: jsonKeys[field]})
+			}
+		case "maxAge":
+			maxAge, err := time.ParseDuration(value)
+			if err != nil || maxAge <= 0 {
+				return definition, fmt.Errorf("%s on %s: invalid maxAge %q", directive, name, value)
+			}
+			definition.MaxAge = maxAge
+		case "maxPerGrouping":
+			maxPerGrouping, err := strconv.Atoi(value)
+			if err != nil || maxPerGrouping <= 0 {
+				return definition, fmt.Errorf("%s on %s: invalid maxPerGrouping %q", directive, name, value)
+			}
+			definition.MaxPerGrouping = maxPerGrouping
+		default:
+			return definition, fmt.Errorf("%s on %s: unknown option %q", directive, name, option)
+		}
+	}
+	return definition, nil
+}
+
+var outputTemplate = template.Must(template.New(outputName).Parse(`// Code generated by sidbgen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"encoding/json"
+
+	"github.com/germtb/sidb"
+)
+{{range .Stores}}{{$store := .}}
+// Make{{.Name}}Store returns the store of {{.Name}} entries, of type "{{.EntryType}}".
+func Make{{.Name}}Store(db *sidb.Database) *sidb.Store[{{.Name}}] {
+	serialize := func(value {{.Name}}) ([]byte, error) {
+		return json.Marshal(value)
+	}
+	deserialize := func(data []byte) ({{.Name}}, error) {
+		var value {{.Name}}
+		err := json.Unmarshal(data, &value)
+		return value, err
+	}
+{{- if .SortingIndex}}
+	sortingIndex := func(value {{.Name}}) *int64 {
+		return &value.{{.SortingIndex}}
+	}
+	return sidb.MakeStore(db, "{{.EntryType}}", serialize, deserialize, sortingIndex)
+{{- else}}
+	return sidb.MakeStore(db, "{{.EntryType}}", serialize, deserialize, nil)
+{{- end}}
+}
+
+// Upsert{{.Name}} writes value under key{{if .Grouping}}, grouped by its {{.Grouping}}{{end}}.
+func Upsert{{.Name}}(store *sidb.Store[{{.Name}}], key string, value {{.Name}}) error {
+	return store.Upsert(sidb.StoreEntryInput[{{.Name}}]{Key: key, Value: value{{if .Grouping}}, Grouping: value.{{.Grouping}}{{end}}})
+}
+{{- if .Grouping}}
+
+// Query{{.Name}}By{{.Grouping}} returns the {{.Name}} entries with the given {{.Grouping}}.
+func Query{{.Name}}By{{.Grouping}}(store *sidb.Store[{{.Name}}], grouping string) ([]{{.Name}}, error) {
+	return store.Query(sidb.StoreQueryParams{Grouping: &grouping{{if .SortingIndex}}, SortField: sidb.SortBySortingIndex, SortOrder: sidb.Ascending{{end}}})
+}
+{{- end}}
+{{- if .Indexes}}
+
+// Create{{.Name}}Indexes creates the indexes Query{{.Name}}By uses. It only
+// needs to run once per database, but running it again is harmless.
+func Create{{.Name}}Indexes(db *sidb.Database) error {
+{{- range .Indexes}}
+	if _, err := db.RawExec({{$store.IndexSQL .}}); err != nil {
+		return err
+	}
+{{- end}}
+	return nil
+}
+{{- range .Indexes}}
+
+// Query{{$store.Name}}By{{.Field}} returns the {{$store.Name}} entries whose {{.Field}} is value.
+func Query{{$store.Name}}By{{.Field}}(db *sidb.Database, value {{.FieldType}}) ([]{{$store.Name}}, error) {
+	entries, err := db.RawQuery({{$store.QuerySQL .}}, value)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]{{$store.Name}}, len(entries))
+	for i, entry := range entries {
+		if err := json.Unmarshal(entry.Value, &values[i]); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+{{- end}}
+{{- end}}
+{{- if or .MaxAge .MaxPerGrouping}}
+
+// {{.Name}}Retention returns the retention rule of {{.Name}} entries, to pass to sidb.Init.
+func {{.Name}}Retention() sidb.Option {
+	return sidb.WithRetention(sidb.RetentionRule{Type: "{{.EntryType}}"{{if .MaxAge}}, MaxAge: {{printf "%d" .MaxAge}} /* {{.MaxAge}} */{{end}}{{if .MaxPerGrouping}}, MaxPerGrouping: {{.MaxPerGrouping}}{{end}}})
+}
+{{- end}}
+{{end}}`))
+
+func (definition storeDefinition) indexExpression(index indexDefinition) string {
+	return fmt.Sprintf(`json_extract(CAST(value AS TEXT), '$."%s"')`, strings.ReplaceAll(index.JSONKey, "'", "''"))
+}
+
+func (definition storeDefinition) typeLiteral() string {
+	return "'" + strings.ReplaceAll(definition.EntryType, "'", "''") + "'"
+}
+
+// IndexSQL creates an index partial over the entry type, which QuerySQL
+// repeats as a literal so that SQLite can tell the index applies.
+func (definition storeDefinition) IndexSQL(index indexDefinition) string {
+	name := strings.ToLower(definition.Name + "_" + index.Field)
+	return strconv.Quote(fmt.Sprintf(`CREATE INDEX IF NOT EXISTS "sidbgen_%s" ON entries (type, %s) WHERE type = %s`,
+		name, definition.indexExpression(index), definition.typeLiteral()))
+}
+
+func (definition storeDefinition) QuerySQL(index indexDefinition) string {
+	return strconv.Quote(fmt.Sprintf("SELECT value FROM entries WHERE type = %s AND deletedAt IS NULL AND %s = ?",
+		definition.typeLiteral(), definition.indexExpression(index)))
+}
+
+func generate(packageName string, definitions []storeDefinition) ([]byte, error) {
+	var buffer bytes.Buffer
+	err := outputTemplate.Execute(&buffer, struct {
+		Package string
+		Stores  []storeDefinition
+	}{packageName, definitions})
+	if err != nil {
+		return nil, err
+	}
+	return format.Source(buffer.Bytes())
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/germtb/sidb"
+)
+
+func parseSource(t *testing.T, source string) ([]storeDefinition, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), "notes.go", source, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("Failed to parse source: %v", err)
+	}
+	return parseFile(file)
+}
+
+func TestGenerate(t *testing.T) {
+	definitions, err := parseSource(t, `package notes
+
+//sidb:store type=notes grouping=Folder sortingIndex=Position
+type Note struct {
+	Folder   string
+	Position int64
+}
+
+type Unmarked struct{}
+`)
+	if err != nil {
+		t.Fatalf("Failed to parse definitions: %v", err)
+	}
+	if len(definitions) != 1 {
+		t.Fatalf("Expected 1 definition, got %d", len(definitions))
+	}
+
+	source, err := generate("notes", definitions)
+	if err != nil {
+		t.Fatalf("Failed to generate: %v", err)
+	}
+	for _, expected := range []string{
+		`sidb.MakeStore(db, "notes", serialize, deserialize, sortingIndex)`,
+		"func UpsertNote(store *sidb.Store[Note], key string, value Note) error",
+		"func QueryNoteByFolder(store *sidb.Store[Note], grouping string) ([]Note, error)",
+	} {
+		if !strings.Contains(string(source), expected) {
+			t.Errorf("Expected generated code to contain %q, got:\n%s", expected, source)
+		}
+	}
+}
+
+func TestDirectiveErrors(t *testing.T) {
+	_, err := parseSource(t, `package notes
+
+//sidb:store sortingIndex=Position
+type Note struct {
+	Position string
+}
+`)
+	if err == nil || !strings.Contains(err.Error(), "must be an int64") {
+		t.Errorf("Expected a sortingIndex type error, got %v", err)
+	}
+}
+
+func TestGenerateGroupedDeclarations(t *testing.T) {
+	definitions, err := parseSource(t, `package notes
+
+type (
+	Unmarked struct{}
+
+	//sidb:store grouping=Type
+	Task struct {
+		Type string
+	}
+
+	//sidb:store grouping=Store
+	Item struct {
+		Store string
+	}
+)
+`)
+	if err != nil {
+		t.Fatalf("Failed to parse definitions: %v", err)
+	}
+	if len(definitions) != 2 || definitions[0].Name != "Task" || definitions[1].Name != "Item" {
+		t.Fatalf("Expected Task and Item, got %+v", definitions)
+	}
+
+	// Field names that are keywords or clash with other parameters must not
+	// leak into the generated parameters
+	source, err := generate("notes", definitions)
+	if err != nil {
+		t.Fatalf("Failed to generate: %v", err)
+	}
+	for _, expected := range []string{
+		"func QueryTaskByType(store *sidb.Store[Task], grouping string) ([]Task, error)",
+		"func QueryItemByStore(store *sidb.Store[Item], grouping string) ([]Item, error)",
+	} {
+		if !strings.Contains(string(source), expected) {
+			t.Errorf("Expected generated code to contain %q, got:\n%s", expected, source)
+		}
+	}
+}
+
+func TestGenerateIndexesAndRetention(t *testing.T) {
+	definitions, err := parseSource(t, `package notes
+
+//sidb:store type=notes index=Author,Stars maxAge=720h maxPerGrouping=50
+type Note struct {
+	Author string `+"`json:\"author\"`"+`
+	Stars  int64
+}
+`)
+	if err != nil {
+		t.Fatalf("Failed to parse definitions: %v", err)
+	}
+
+	source, err := generate("notes", definitions)
+	if err != nil {
+		t.Fatalf("Failed to generate: %v", err)
+	}
+	for _, expected := range []string{
+		"func CreateNoteIndexes(db *sidb.Database) error",
+		"func QueryNoteByAuthor(db *sidb.Database, value string) ([]Note, error)",
+		"func QueryNoteByStars(db *sidb.Database, value int64) ([]Note, error)",
+		`sidb.WithRetention(sidb.RetentionRule{Type: "notes", MaxAge: 2592000000000000 /* 720h0m0s */, MaxPerGrouping: 50})`,
+	} {
+		if !strings.Contains(string(source), expected) {
+			t.Errorf("Expected generated code to contain %q, got:\n%s", expected, source)
+		}
+	}
+
+	// The generated statements run against a real database
+	db, err := sidb.Init([]string{"test_namespace"}, "test_sidbgen")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	definition := definitions[0]
+	for _, index := range definition.Indexes {
+		statement, _ := strconv.Unquote(definition.IndexSQL(index))
+		if _, err := db.RawExec(statement); err != nil {
+			t.Fatalf("Failed to create index: %v", err)
+		}
+	}
+	err = db.BulkUpsert([]sidb.EntryInput{
+		{Type: "notes", Key: "a", Value: []byte(`{"author":"ada","Stars":3}`)},
+		{Type: "notes", Key: "b", Value: []byte(`{"author":"grace","Stars":5}`)},
+		{Type: "other", Key: "c", Value: []byte(`{"author":"ada"}`)},
+	})
+	if err != nil {
+		t.Fatalf("Failed to put entries: %v", err)
+	}
+
+	query, _ := strconv.Unquote(definition.QuerySQL(definition.Indexes[0]))
+	entries, err := db.RawQuery(query, "ada")
+	if err != nil || len(entries) != 1 || !strings.Contains(string(entries[0].Value), "ada") {
+		t.Errorf("Expected one entry by ada, got %+v, %v", entries, err)
+	}
+}
+
+func TestRetentionDirectiveErrors(t *testing.T) {
+	_, err := parseSource(t, `package notes
+
+//sidb:store index=Tags
+type Note struct {
+	Tags []string
+}
+`)
+	if err == nil || !strings.Contains(err.Error(), "must be a string, number or bool") {
+		t.Errorf("Expected an index type error, got %v", err)
+	}
+
+	_, err = parseSource(t, `package notes
+
+//sidb:store maxAge=soon
+type Note struct{}
+`)
+	if err == nil || !strings.Contains(err.Error(), "invalid maxAge") {
+		t.Errorf("Expected a maxAge error, got %v", err)
+	}
+}
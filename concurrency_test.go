@@ -0,0 +1,97 @@
+package sidb
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// benchmarkReads measures single entry reads, optionally while another
+// goroutine keeps running bulk writes.
+func benchmarkReads(b *testing.B, withWriter bool) {
+	db, err := Init([]string{"test_namespace"}, "bench_concurrency")
+	if err != nil {
+		b.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	var entries []EntryInput
+	for i := 0; i < 1000; i++ {
+		entries = append(entries, EntryInput{Type: "item", Key: fmt.Sprintf("key_%d", i), Value: []byte("data")})
+	}
+	if err := db.BulkUpsert(entries); err != nil {
+		b.Fatalf("Failed to put entries: %v", err)
+	}
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	if withWriter {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if err := db.BulkUpsert(entries); err != nil {
+					b.Errorf("Failed to put entries: %v", err)
+					return
+				}
+			}
+		}()
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := db.Get("item", fmt.Sprintf("key_%d", i%1000)); err != nil {
+			b.Fatalf("Failed to get entry: %v", err)
+		}
+	}
+	b.StopTimer()
+
+	close(stop)
+	wg.Wait()
+}
+
+func BenchmarkReads(b *testing.B) {
+	benchmarkReads(b, false)
+}
+
+func BenchmarkReadsDuringBulkWrites(b *testing.B) {
+	benchmarkReads(b, true)
+}
+
+func TestReadsDoNotWaitForWrites(t *testing.T) {
+	db, err := Init([]string{"test_namespace"}, "test_concurrency")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	if err := db.Upsert(EntryInput{Type: "item", Key: "key", Value: []byte("data")}); err != nil {
+		t.Fatalf("Failed to put entry: %v", err)
+	}
+
+	// Hold the write lock as a long write would
+	unlock := db.lockWrite(true, "item")
+	defer unlock()
+
+	read := make(chan error)
+	go func() {
+		_, err := db.Get("item", "key")
+		read <- err
+	}()
+
+	select {
+	case err := <-read:
+		if err != nil {
+			t.Errorf("Failed to get entry: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Expected a read to go ahead while a write is in progress")
+	}
+}
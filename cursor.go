@@ -0,0 +1,71 @@
+package sidb
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// Cursors are opaque tokens encoding the position of an entry in timestamp
+// order. Unlike offsets, they keep pointing at the same place while entries
+// are inserted or deleted around them.
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+var ErrCursorUnsupported = errors.New("cursors require SortByTimestamp")
+
+type cursor struct {
+	Timestamp int64  `json:"t"`
+	Key       string `json:"k"`
+	Type      string `json:"y"`
+}
+
+func CursorFor(entry DbEntry) string {
+	encoded, _ := json.Marshal(cursor{Timestamp: entry.Timestamp, Key: entry.Key, Type: entry.Type})
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+func decodeCursor(token string) (cursor, error) {
+	var decoded cursor
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return decoded, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		return decoded, ErrInvalidCursor
+	}
+	return decoded, nil
+}
+
+// QueryPage runs the query and returns the cursor for the following page, or
+// an empty cursor once the results are exhausted.
+func (db *Database) QueryPage(params QueryParams) ([]DbEntry, string, error) {
+	entries, err := db.Query(params)
+	if err != nil {
+		return nil, "", err
+	}
+	return entries, nextCursor(params, entries), nil
+}
+
+func nextCursor(params QueryParams, entries []DbEntry) string {
+	if len(entries) == 0 || params.Limit == nil || len(entries) < *params.Limit {
+		return ""
+	}
+	return CursorFor(entries[len(entries)-1])
+}
+
+func (store *Store[T]) QueryPage(params StoreQueryParams) ([]T, string, error) {
+	entries, next, err := store.db.QueryPage(store.queryParams(params))
+	if err != nil {
+		return nil, "", err
+	}
+
+	results := make([]T, 0, len(entries))
+	for _, entry := range entries {
+		value, err := store.decode(entry.Key, entry.Value)
+		if err != nil {
+			return nil, "", err
+		}
+		results = append(results, value)
+	}
+	return results, next, nil
+}
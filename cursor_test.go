@@ -0,0 +1,76 @@
+package sidb
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestQueryPageSurvivesInserts(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_cursor"
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	entryType := "test_type"
+	var entries []EntryInput
+	for i := 0; i < 6; i++ {
+		// Pairs of entries share a timestamp to exercise the key tie-breaker
+		entries = append(entries, EntryInput{Type: entryType, Value: []byte("data"), Key: fmt.Sprintf("key_%d", i), Timestamp: ptr(int64(100 + i/2))})
+	}
+	if err := db.BulkUpsert(entries); err != nil {
+		t.Fatalf("Failed to put entries: %v", err)
+	}
+
+	limit := 2
+	params := QueryParams{Type: &entryType, Limit: &limit, SortField: SortByTimestamp, SortOrder: Descending}
+
+	var seen []string
+	for {
+		page, next, err := db.QueryPage(params)
+		if err != nil {
+			t.Fatalf("Failed to query page: %v", err)
+		}
+		for _, entry := range page {
+			seen = append(seen, entry.Key)
+		}
+		if next == "" {
+			break
+		}
+		params.After = next
+
+		// A newer entry arriving mid-pagination must not shift later pages
+		if len(seen) == 2 {
+			if err := db.Upsert(EntryInput{Type: entryType, Value: []byte("data"), Key: "key_new", Timestamp: ptr(int64(200))}); err != nil {
+				t.Fatalf("Failed to put entry: %v", err)
+			}
+		}
+	}
+
+	expected := []string{"key_5", "key_4", "key_3", "key_2", "key_1", "key_0"}
+	if fmt.Sprint(seen) != fmt.Sprint(expected) {
+		t.Errorf("Expected %v, got %v", expected, seen)
+	}
+}
+
+func TestQueryInvalidCursor(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_cursor"
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	_, err = db.Query(QueryParams{After: "not a cursor"})
+	if err != ErrInvalidCursor {
+		t.Errorf("Expected ErrInvalidCursor, got %v", err)
+	}
+
+	_, err = db.Query(QueryParams{After: CursorFor(DbEntry{}), SortField: SortBySortingIndex})
+	if err != ErrCursorUnsupported {
+		t.Errorf("Expected ErrCursorUnsupported, got %v", err)
+	}
+}
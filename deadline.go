@@ -0,0 +1,86 @@
+package sidb
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Reads and writes have separate deadlines, so a long bulk import is not
+// held to the limit meant for interactive queries. Deadlines cover the time
+// spent in SQLite, not the time spent waiting for the database lock.
+
+// WithReadTimeout interrupts reads that run for longer than timeout.
+func WithReadTimeout(timeout time.Duration) Option {
+	return func(options *Options) {
+		options.readTimeout = timeout
+	}
+}
+
+// WithWriteTimeout interrupts and rolls back writes that run for longer than
+// timeout.
+func WithWriteTimeout(timeout time.Duration) Option {
+	return func(options *Options) {
+		options.writeTimeout = timeout
+	}
+}
+
+type CancellationStats struct {
+	ReadTimeouts  int64
+	WriteTimeouts int64
+	Cancelled     int64 // Reads interrupted by CancelReads
+}
+
+func (db *Database) CancellationStats() CancellationStats {
+	return CancellationStats{
+		ReadTimeouts:  db.readTimeouts.Load(),
+		WriteTimeouts: db.writeTimeouts.Load(),
+		Cancelled:     db.cancelled.Load(),
+	}
+}
+
+// CancelReads interrupts every read in progress, which fail with
+// context.Canceled. Later reads are not affected.
+func (db *Database) CancelReads() {
+	db.readsMutex.Lock()
+	defer db.readsMutex.Unlock()
+
+	if db.cancelReads != nil {
+		db.cancelReads()
+	}
+	db.readsCtx, db.cancelReads = context.WithCancel(context.Background())
+}
+
+// readContext returns the context of a read and a function to call once the
+// read is done.
+func (db *Database) readContext() (context.Context, func()) {
+	db.readsMutex.Lock()
+	if db.readsCtx == nil {
+		db.readsCtx, db.cancelReads = context.WithCancel(context.Background())
+	}
+	ctx := db.readsCtx
+	db.readsMutex.Unlock()
+
+	return db.deadlineContext(ctx, db.options.readTimeout, &db.readTimeouts)
+}
+
+func (db *Database) writeContext() (context.Context, func()) {
+	return db.deadlineContext(context.Background(), db.options.writeTimeout, &db.writeTimeouts)
+}
+
+func (db *Database) deadlineContext(parent context.Context, timeout time.Duration, timeouts interface{ Add(int64) int64 }) (context.Context, func()) {
+	ctx, cancel := parent, context.CancelFunc(func() {})
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(parent, timeout)
+	}
+
+	return ctx, func() {
+		switch {
+		case errors.Is(ctx.Err(), context.DeadlineExceeded):
+			timeouts.Add(1)
+		case errors.Is(ctx.Err(), context.Canceled):
+			db.cancelled.Add(1)
+		}
+		cancel()
+	}
+}
@@ -0,0 +1,66 @@
+package sidb
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+const slowSelect = "WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c WHERE x < %d) SELECT CAST(MAX(x) AS TEXT) AS key FROM c"
+
+func TestReadAndWriteTimeouts(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_timeouts"
+	db, err := Init(namespace, name, WithReadTimeout(50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	if _, err := db.RawQuery(fmt.Sprintf(slowSelect, 1000000000)); err == nil {
+		t.Fatalf("Expected the slow read to time out")
+	}
+
+	// Writes are not held to the read timeout
+	_, err = db.RawExec("DELETE FROM entries WHERE key IN (" + fmt.Sprintf(slowSelect, 2000000) + ")")
+	if err != nil {
+		t.Fatalf("Expected the write to complete, got %v", err)
+	}
+
+	if stats := db.CancellationStats(); stats != (CancellationStats{ReadTimeouts: 1}) {
+		t.Errorf("Expected one read timeout, got %+v", stats)
+	}
+}
+
+func TestCancelReads(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_cancel_reads"
+	db, err := Init(namespace, name, WithWriteTimeout(50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	_, err = db.RawExec("DELETE FROM entries WHERE key IN (" + fmt.Sprintf(slowSelect, 1000000000) + ")")
+	if err == nil {
+		t.Fatalf("Expected the slow write to time out")
+	}
+
+	result := make(chan error)
+	go func() {
+		_, err := db.RawQuery(fmt.Sprintf(slowSelect, 1000000000))
+		result <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	db.CancelReads()
+
+	if err := <-result; err == nil {
+		t.Fatalf("Expected the cancelled read to fail")
+	}
+	if _, err := db.RawQuery("SELECT key FROM entries"); err != nil {
+		t.Errorf("Expected reads after CancelReads to work, got %v", err)
+	}
+	if stats := db.CancellationStats(); stats != (CancellationStats{WriteTimeouts: 1, Cancelled: 1}) {
+		t.Errorf("Expected a write timeout and a cancelled read, got %+v", stats)
+	}
+}
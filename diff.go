@@ -0,0 +1,105 @@
+package sidb
+
+import (
+	"crypto/sha256"
+	"sort"
+)
+
+// TypeDiff lists the keys of one type that differ between two databases.
+// Entries count as changed when their values differ; metadata is ignored.
+type TypeDiff struct {
+	Added   []string
+	Removed []string
+	Changed []string
+}
+
+// DiffReport maps each type with differences to its TypeDiff. Types whose
+// entries match are left out, so an empty report means the contents agree.
+type DiffReport map[string]TypeDiff
+
+func (db *Database) valueHashes() (map[string]map[string][sha256.Size]byte, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	if db.connection == nil {
+		return nil, ErrNoDbConnection
+	}
+
+	ctx, done := db.readContext()
+	defer done()
+
+	rows, err := db.connection.QueryContext(ctx, "SELECT type, key, value FROM entries WHERE deletedAt IS NULL")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hashes := make(map[string]map[string][sha256.Size]byte)
+	for rows.Next() {
+		var entryType, key string
+		var value []byte
+		if err := rows.Scan(&entryType, &key, &value); err != nil {
+			return nil, err
+		}
+		if hashes[entryType] == nil {
+			hashes[entryType] = make(map[string][sha256.Size]byte)
+		}
+		hashes[entryType][key] = sha256.Sum256(value)
+	}
+
+	return hashes, rows.Err()
+}
+
+// DiffDatabases compares every entry of a and b by key and value hash. Added
+// keys exist only in b, removed keys only in a. Keys are sorted.
+func DiffDatabases(a, b *Database) (DiffReport, error) {
+	hashesA, err := a.valueHashes()
+	if err != nil {
+		return nil, err
+	}
+	hashesB, err := b.valueHashes()
+	if err != nil {
+		return nil, err
+	}
+
+	report := make(DiffReport)
+	for entryType, keysA := range hashesA {
+		var diff TypeDiff
+		keysB := hashesB[entryType]
+		for key, hashA := range keysA {
+			hashB, ok := keysB[key]
+			if !ok {
+				diff.Removed = append(diff.Removed, key)
+			} else if hashA != hashB {
+				diff.Changed = append(diff.Changed, key)
+			}
+		}
+		for key := range keysB {
+			if _, ok := keysA[key]; !ok {
+				diff.Added = append(diff.Added, key)
+			}
+		}
+		if len(diff.Added)+len(diff.Removed)+len(diff.Changed) > 0 {
+			report[entryType] = diff
+		}
+	}
+
+	for entryType, keysB := range hashesB {
+		if _, ok := hashesA[entryType]; ok {
+			continue
+		}
+		var diff TypeDiff
+		for key := range keysB {
+			diff.Added = append(diff.Added, key)
+		}
+		report[entryType] = diff
+	}
+
+	for _, diff := range report {
+		sort.Strings(diff.Added)
+		sort.Strings(diff.Removed)
+		sort.Strings(diff.Changed)
+	}
+
+	return report, nil
+}
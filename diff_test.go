@@ -0,0 +1,62 @@
+package sidb
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffDatabases(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	a, err := Init(namespace, "test_diff_a")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer a.Drop()
+
+	b, err := Init(namespace, "test_diff_b")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer b.Drop()
+
+	err = a.BulkUpsert([]EntryInput{
+		{Type: "type_a", Value: []byte("same"), Key: "key_1"},
+		{Type: "type_a", Value: []byte("old"), Key: "key_2"},
+		{Type: "type_a", Value: []byte("removed"), Key: "key_3"},
+		{Type: "type_b", Value: []byte("same"), Key: "key_1"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to put entries: %v", err)
+	}
+	err = b.BulkUpsert([]EntryInput{
+		{Type: "type_a", Value: []byte("same"), Key: "key_1", Grouping: "moved"},
+		{Type: "type_a", Value: []byte("new"), Key: "key_2"},
+		{Type: "type_a", Value: []byte("added"), Key: "key_4"},
+		{Type: "type_b", Value: []byte("same"), Key: "key_1"},
+		{Type: "type_c", Value: []byte("added"), Key: "key_1"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to put entries: %v", err)
+	}
+
+	report, err := DiffDatabases(a, b)
+	if err != nil {
+		t.Fatalf("Failed to diff databases: %v", err)
+	}
+
+	expected := DiffReport{
+		"type_a": {Added: []string{"key_4"}, Removed: []string{"key_3"}, Changed: []string{"key_2"}},
+		"type_c": {Added: []string{"key_1"}},
+	}
+	if !reflect.DeepEqual(report, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, report)
+	}
+
+	report, err = DiffDatabases(a, a)
+	if err != nil {
+		t.Fatalf("Failed to diff database with itself: %v", err)
+	}
+	if len(report) != 0 {
+		t.Errorf("Expected empty report, got %+v", report)
+	}
+}
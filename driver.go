@@ -0,0 +1,65 @@
+package sidb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"math"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// Databases are opened through a driver that registers the SQL functions the
+// query builder relies on, since the bundled SQLite has no math functions.
+
+const driverName = "sidb_sqlite3"
+
+var sidbDriver = &sqlite3.SQLiteDriver{
+	ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+		return conn.RegisterFunc("sidb_decay", decayScore, true)
+	},
+}
+
+func init() {
+	sql.Register(driverName, sidbDriver)
+}
+
+// pragmaConnector opens connections through the sidb driver and runs the
+// pragmas the DSN has no parameter for on each of them, since the pool opens
+// new connections at any time.
+type pragmaConnector struct {
+	dsn     string
+	pragmas []string
+	retry   busyRetry
+}
+
+func (connector pragmaConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := sidbDriver.Open(connector.dsn)
+	if err != nil {
+		return nil, err
+	}
+	sqliteConn := conn.(*sqlite3.SQLiteConn)
+	for _, pragma := range connector.pragmas {
+		if _, err := sqliteConn.Exec(pragma, nil); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if connector.retry.retries < 0 {
+		return conn, nil
+	}
+	return &retryConn{SQLiteConn: sqliteConn, retry: connector.retry}, nil
+}
+
+func (connector pragmaConnector) Driver() driver.Driver {
+	return sidbDriver
+}
+
+// decayScore halves weight every halfLife milliseconds since timestamp.
+func decayScore(timestamp int64, now int64, halfLife int64, weight float64) float64 {
+	if halfLife <= 0 {
+		return weight
+	}
+	age := float64(now - timestamp)
+	return weight * math.Exp2(-age/float64(halfLife))
+}
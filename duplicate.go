@@ -0,0 +1,109 @@
+package sidb
+
+import (
+	"database/sql"
+	"errors"
+)
+
+var ErrEntryNotFound = errors.New("entry not found")
+var ErrKeyExists = errors.New("key already exists")
+
+// EntryOverrides replaces fields of a duplicated entry. Nil fields are copied.
+type EntryOverrides struct {
+	Value        []byte
+	Grouping     *string
+	Subgrouping  *string
+	SortingIndex *int64
+}
+
+// Duplicate copies an entry to newKey in one transaction. Unless overridden,
+// a copy of an entry with a sorting index is placed right after the original,
+// moving later entries of the same grouping down by one.
+func (db *Database) Duplicate(entryType string, key string, newKey string, overrides EntryOverrides) (_ *DbEntry, err error) {
+	defer translateImmutable(&err)
+
+	defer db.afterWrite(entryType)
+
+	defer db.lockWrite(false, entryType)()
+
+	if db.connection == nil {
+		return nil, ErrNoDbConnection
+	}
+
+	ctx, done := db.writeContext()
+	defer done()
+
+	tx, err := db.connection.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	original, err := db.scanEntry(tx.QueryRowContext(ctx, "SELECT "+db.entryColumns()+" FROM entries WHERE type = ? AND key = ? AND deletedAt IS NULL", entryType, key))
+	if err == sql.ErrNoRows {
+		return nil, ErrEntryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var exists int
+	err = tx.QueryRowContext(ctx, "SELECT 1 FROM entries WHERE type = ? AND key = ? AND deletedAt IS NULL", entryType, newKey).Scan(&exists)
+	if err == nil {
+		return nil, ErrKeyExists
+	}
+	if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	duplicate := EntryInput{
+		Type:         entryType,
+		Key:          newKey,
+		Value:        original.Value,
+		Grouping:     original.GetGrouping(),
+		Subgrouping:  original.Subgrouping,
+		SortingIndex: original.SortingIndex,
+	}
+	if overrides.Value != nil {
+		duplicate.Value = overrides.Value
+	}
+	if overrides.Grouping != nil {
+		duplicate.Grouping = *overrides.Grouping
+	}
+	if overrides.Subgrouping != nil {
+		duplicate.Subgrouping = *overrides.Subgrouping
+	}
+
+	if overrides.SortingIndex != nil {
+		duplicate.SortingIndex = overrides.SortingIndex
+	} else if original.SortingIndex != nil {
+		next := *original.SortingIndex + 1
+		duplicate.SortingIndex = &next
+
+		condition, args := groupingCondition(duplicate.Grouping)
+		args = append([]interface{}{entryType}, args...)
+		_, err := tx.ExecContext(ctx, "UPDATE entries SET sortingIndex = sortingIndex + 1, version = version + 1 WHERE type = ? AND deletedAt IS NULL AND "+condition+" AND sortingIndex > ?",
+			append(args, *original.SortingIndex)...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, upsertSQL(1), db.upsertArgs(duplicate, db.now())...); err != nil {
+		return nil, err
+	}
+
+	stored, err := db.scanEntry(tx.QueryRowContext(ctx, "SELECT "+db.entryColumns()+" FROM entries WHERE type = ? AND key = ?", entryType, newKey))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.checkQuotas(ctx, tx, entryType); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &stored, nil
+}
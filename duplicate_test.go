@@ -0,0 +1,63 @@
+package sidb
+
+import "testing"
+
+func TestDuplicate(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_duplicate"
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	entryType := "task"
+	err = db.BulkUpsert([]EntryInput{
+		{Type: entryType, Value: []byte("first"), Key: "a", Grouping: "list", SortingIndex: ptr(int64(0))},
+		{Type: entryType, Value: []byte("second"), Key: "b", Grouping: "list", SortingIndex: ptr(int64(1))},
+		{Type: entryType, Value: []byte("third"), Key: "c", Grouping: "list", SortingIndex: ptr(int64(2))},
+		{Type: entryType, Value: []byte("elsewhere"), Key: "d", Grouping: "other", SortingIndex: ptr(int64(5))},
+	})
+	if err != nil {
+		t.Fatalf("Failed to put entries: %v", err)
+	}
+
+	copied, err := db.Duplicate(entryType, "b", "b_copy", EntryOverrides{})
+	if err != nil {
+		t.Fatalf("Failed to duplicate entry: %v", err)
+	}
+	if string(copied.Value) != "second" || copied.GetGrouping() != "list" || *copied.SortingIndex != 2 {
+		t.Errorf("Unexpected duplicate: %+v", copied)
+	}
+
+	entries, err := db.Query(QueryParams{Type: &entryType, Grouping: ptr("list"), SortField: SortBySortingIndex, SortOrder: Ascending})
+	if err != nil {
+		t.Fatalf("Failed to query entries: %v", err)
+	}
+	expectedKeys := []string{"a", "b", "b_copy", "c"}
+	for i, entry := range entries {
+		if entry.Key != expectedKeys[i] || *entry.SortingIndex != int64(i) {
+			t.Errorf("At index %d, expected %s, got %s at %d", i, expectedKeys[i], entry.Key, *entry.SortingIndex)
+		}
+	}
+
+	other, err := db.Get(entryType, "d")
+	if err != nil || *other.SortingIndex != 5 {
+		t.Errorf("Expected entries of other groupings to keep their index, got %+v, %v", other, err)
+	}
+
+	if _, err := db.Duplicate(entryType, "a", "b_copy", EntryOverrides{}); err != ErrKeyExists {
+		t.Errorf("Expected ErrKeyExists, got %v", err)
+	}
+	if _, err := db.Duplicate(entryType, "missing", "new", EntryOverrides{}); err != ErrEntryNotFound {
+		t.Errorf("Expected ErrEntryNotFound, got %v", err)
+	}
+
+	moved, err := db.Duplicate(entryType, "a", "a_moved", EntryOverrides{Value: []byte("changed"), Grouping: ptr("other"), SortingIndex: ptr(int64(9))})
+	if err != nil {
+		t.Fatalf("Failed to duplicate entry with overrides: %v", err)
+	}
+	if string(moved.Value) != "changed" || moved.GetGrouping() != "other" || *moved.SortingIndex != 9 {
+		t.Errorf("Unexpected duplicate with overrides: %+v", moved)
+	}
+}
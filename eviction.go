@@ -0,0 +1,93 @@
+package sidb
+
+import (
+	"context"
+	"slices"
+)
+
+// afterWrite is deferred by write methods with the types they wrote, and runs
+// once the write lock has been released. Writes that cannot tell which types
+// they touched pass none, and every capped type is checked.
+func (db *Database) afterWrite(entryTypes ...string) {
+	db.evict(entryTypes)
+	db.checkThresholds()
+}
+
+// WithMaxEntries caps the number of entries of a type. After each write to
+// the type, the oldest entries by timestamp beyond the cap are deleted for
+// good, even with soft delete enabled. Immutable entries are neither evicted nor counted.
+// Touching entries when they are used turns this into least recently used
+// eviction.
+func WithMaxEntries(entryType string, max int) Option {
+	return func(options *Options) {
+		if options.maxEntries == nil {
+			options.maxEntries = make(map[string]int)
+		}
+		options.maxEntries[entryType] = max
+	}
+}
+
+func (db *Database) evict(entryTypes []string) {
+	if len(db.options.maxEntries) == 0 && len(db.options.quotas) == 0 {
+		return
+	}
+	if len(entryTypes) == 0 {
+		for entryType := range db.options.maxEntries {
+			entryTypes = append(entryTypes, entryType)
+		}
+		for entryType := range db.options.quotas {
+			entryTypes = append(entryTypes, entryType)
+		}
+	}
+
+	var capped []string
+	for _, entryType := range entryTypes {
+		if slices.Contains(capped, entryType) {
+			continue
+		}
+		if _, ok := db.options.maxEntries[entryType]; ok {
+			capped = append(capped, entryType)
+		} else if quota, ok := db.options.quotas[entryType]; ok && quota.policy == QuotaEvictOldest {
+			capped = append(capped, entryType)
+		}
+	}
+	if len(capped) == 0 {
+		return
+	}
+
+	defer db.lockWrite(true, capped...)()
+
+	if db.connection == nil {
+		return
+	}
+
+	ctx, done := db.writeContext()
+	defer done()
+
+	// Errors are ignored like threshold checks: the write itself succeeded
+	for _, entryType := range capped {
+		if max, ok := db.options.maxEntries[entryType]; ok && db.overCap(ctx, entryType, max) {
+			db.connection.ExecContext(ctx, `DELETE FROM entries WHERE type = ? AND key IN (
+				SELECT key FROM entries WHERE type = ? AND deletedAt IS NULL AND immutable = 0
+				ORDER BY timestamp DESC, key DESC LIMIT -1 OFFSET ?)`, entryType, entryType, max)
+		}
+		db.evictOverQuota(ctx, entryType)
+	}
+}
+
+// overCap counts no further than one past max, so checking a type under its
+// cap costs as much as the cap rather than the type.
+func (db *Database) overCap(ctx context.Context, entryType string, max int) bool {
+	var count int
+	err := db.connection.QueryRowContext(ctx, `SELECT COUNT(*) FROM (
+		SELECT 1 FROM entries WHERE type = ? AND deletedAt IS NULL AND immutable = 0 LIMIT ?)`, entryType, max+1).Scan(&count)
+	return err == nil && count > max
+}
+
+// matchedTypes returns the types params can match, or nil if it can match any.
+func (params QueryParams) matchedTypes() []string {
+	if params.Type != nil {
+		return []string{*params.Type}
+	}
+	return params.Types
+}
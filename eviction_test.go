@@ -0,0 +1,97 @@
+package sidb
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMaxEntries(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_eviction"
+	db, err := Init(namespace, name, WithMaxEntries("cache", 3))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	for i := 0; i < 5; i++ {
+		err := db.Upsert(EntryInput{Type: "cache", Value: []byte("data"), Key: fmt.Sprintf("key_%d", i), Timestamp: ptr(int64(i))})
+		if err != nil {
+			t.Fatalf("Failed to put entry: %v", err)
+		}
+	}
+	if err := db.Upsert(EntryInput{Type: "other_type", Value: []byte("data"), Key: "key"}); err != nil {
+		t.Fatalf("Failed to put entry: %v", err)
+	}
+
+	cacheType := "cache"
+	entries, err := db.Query(QueryParams{Type: &cacheType, SortOrder: Ascending})
+	if err != nil {
+		t.Fatalf("Failed to query entries: %v", err)
+	}
+	if len(entries) != 3 || entries[0].Key != "key_2" {
+		t.Errorf("Expected the 3 newest entries, got %+v", entries)
+	}
+
+	// Touching an old entry protects it from the next eviction
+	if _, err := db.Touch(cacheType, []string{"key_2"}); err != nil {
+		t.Fatalf("Failed to touch entry: %v", err)
+	}
+	if err := db.Upsert(EntryInput{Type: "cache", Value: []byte("data"), Key: "key_5", Timestamp: ptr(int64(5))}); err != nil {
+		t.Fatalf("Failed to put entry: %v", err)
+	}
+	exists, err := db.Exists(cacheType, "key_2")
+	if err != nil {
+		t.Fatalf("Failed to check entry: %v", err)
+	}
+	if !exists {
+		t.Errorf("Expected touched key_2 to survive eviction")
+	}
+
+	count, err := db.Count()
+	if err != nil {
+		t.Fatalf("Failed to count entries: %v", err)
+	}
+	if count != 4 {
+		t.Errorf("Expected 3 cache entries plus 1 other, got %d", count)
+	}
+}
+
+func TestEvictionOnlyChecksWrittenTypes(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_eviction_written_types"
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	for i := 0; i < 5; i++ {
+		if err := db.Upsert(EntryInput{Type: "cache", Value: []byte("data"), Key: fmt.Sprintf("key_%d", i), Timestamp: ptr(int64(i))}); err != nil {
+			t.Fatalf("Failed to put entry: %v", err)
+		}
+	}
+	db.Close()
+
+	capped, err := Init(namespace, name, WithMaxEntries("cache", 3))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer capped.Close()
+
+	cacheType := "cache"
+	if err := capped.Upsert(EntryInput{Type: "other_type", Value: []byte("data"), Key: "key"}); err != nil {
+		t.Fatalf("Failed to put entry: %v", err)
+	}
+	if count, _ := capped.CountWhere(QueryParams{Type: &cacheType}); count != 5 {
+		t.Errorf("Expected writes to other types to leave the cache alone, got %d entries", count)
+	}
+
+	// Raw SQL cannot tell which types it wrote, so every capped type is checked
+	if _, err := capped.RawExec("UPDATE entries SET value = 'raw' WHERE type = 'other_type'"); err != nil {
+		t.Fatalf("Failed to run raw exec: %v", err)
+	}
+	if count, _ := capped.CountWhere(QueryParams{Type: &cacheType}); count != 3 {
+		t.Errorf("Expected the cache to be evicted down to 3 entries, got %d", count)
+	}
+}
@@ -0,0 +1,25 @@
+package sidb
+
+import "time"
+
+// Export writes a consistent snapshot of the database to destPath, which must
+// not already exist. The copy is taken from a single read transaction, so it
+// never contains a partially applied write, and it does not hold the database
+// lock, so writers keep making progress while it runs.
+func (db *Database) Export(destPath string) error {
+	started := time.Now()
+
+	db.mutex.RLock()
+	connection := db.connection
+	db.mutex.RUnlock()
+
+	if connection == nil {
+		return ErrNoDbConnection
+	}
+
+	if _, err := connection.Exec("VACUUM INTO ?", destPath); err != nil {
+		return db.options.reportCorruption(db.Path, err)
+	}
+	db.emit(EventBackupCompleted, started, destPath)
+	return nil
+}
@@ -0,0 +1,141 @@
+package sidb
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+const exportManifestName = "manifest.json"
+
+type GroupingExport struct {
+	Grouping string `json:"grouping"` // Empty for ungrouped entries
+	File     string `json:"file"`
+	Entries  int64  `json:"entries"`
+}
+
+// ExportManifest lists the files written by ExportByGrouping. It is saved as
+// manifest.json in the export directory.
+type ExportManifest struct {
+	ExportedAt int64            `json:"exportedAt"`
+	Groupings  []GroupingExport `json:"groupings"`
+}
+
+// ExportByGrouping writes the live entries of each grouping to a database file
+// of its own in dir, with a manifest naming the grouping each file holds.
+// Files are numbered rather than named after groupings, which may contain
+// characters file systems reject. A file can be opened on its own or restored
+// with Import. Each file is a consistent snapshot of its grouping, but writes
+// made during the export can land between two files. The whole export counts
+// against the read timeout.
+func (db *Database) ExportByGrouping(dir string) (*ExportManifest, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	if db.connection == nil {
+		return nil, ErrNoDbConnection
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	manifestPath := path.Join(dir, exportManifestName)
+	if _, err := os.Stat(manifestPath); err == nil {
+		return nil, fmt.Errorf("%s already holds an export", dir)
+	}
+
+	ctx, done := db.readContext()
+	defer done()
+
+	groupings, err := db.groupingCounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// ATTACH only applies to one connection of the pool
+	conn, err := db.connection.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	manifest := &ExportManifest{ExportedAt: db.now()}
+	for i, grouping := range groupings {
+		grouping.File = fmt.Sprintf("grouping_%03d.db", i+1)
+		if grouping.Entries, err = exportGrouping(ctx, conn, grouping.Grouping, path.Join(dir, grouping.File)); err != nil {
+			return nil, err
+		}
+		manifest.Groupings = append(manifest.Groupings, grouping)
+	}
+
+	encoded, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(manifestPath, encoded, 0644); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+func (db *Database) groupingCounts(ctx context.Context) ([]GroupingExport, error) {
+	rows, err := db.connection.QueryContext(ctx, "SELECT COALESCE(grouping, ''), COUNT(*) FROM entries WHERE deletedAt IS NULL GROUP BY 1 ORDER BY 1")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var groupings []GroupingExport
+	for rows.Next() {
+		var grouping GroupingExport
+		if err := rows.Scan(&grouping.Grouping, &grouping.Entries); err != nil {
+			return nil, err
+		}
+		groupings = append(groupings, grouping)
+	}
+	return groupings, rows.Err()
+}
+
+// exportGrouping copies the live entries of a grouping to a new database file
+// and returns how many it copied.
+func exportGrouping(ctx context.Context, conn *sql.Conn, grouping string, destPath string) (int64, error) {
+	if _, err := os.Stat(destPath); err == nil {
+		return 0, fmt.Errorf("%s already exists", destPath)
+	}
+
+	if _, err := conn.ExecContext(ctx, "ATTACH DATABASE ? AS export", destPath); err != nil {
+		return 0, err
+	}
+	// Detached even after a timeout, so the pooled connection can be reused
+	defer conn.ExecContext(context.Background(), "DETACH DATABASE export")
+
+	// Indexes and the other tables are created by Init when the file is opened
+	if _, err := conn.ExecContext(ctx, fmt.Sprintf(entriesTableSQL, "export.entries")); err != nil {
+		return 0, err
+	}
+	columns, err := sharedColumns(ctx, conn, "export")
+	if err != nil {
+		return 0, err
+	}
+	columnList := strings.Join(columns, ", ")
+
+	condition, args := groupingCondition(grouping)
+	return execInTx(ctx, conn, "INSERT INTO export.entries ("+columnList+") SELECT "+columnList+" FROM main.entries WHERE deletedAt IS NULL AND "+condition, args)
+}
+
+// ReadExportManifest reads the manifest of an ExportByGrouping directory.
+func ReadExportManifest(dir string) (*ExportManifest, error) {
+	encoded, err := os.ReadFile(path.Join(dir, exportManifestName))
+	if err != nil {
+		return nil, err
+	}
+	var manifest ExportManifest
+	if err := json.Unmarshal(encoded, &manifest); err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
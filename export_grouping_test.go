@@ -0,0 +1,105 @@
+package sidb
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestExportByGrouping(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_export_by_grouping"
+	db, err := Init(namespace, name, WithSoftDelete())
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	err = db.BulkUpsert([]EntryInput{
+		{Type: "note", Key: "a", Value: []byte("a"), Grouping: "project/alpha"},
+		{Type: "note", Key: "b", Value: []byte("b"), Grouping: "project/alpha"},
+		{Type: "task", Key: "c", Value: []byte("c"), Grouping: "beta"},
+		{Type: "note", Key: "d", Value: []byte("d")},
+		{Type: "note", Key: "deleted", Value: []byte("e"), Grouping: "beta"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to put entries: %v", err)
+	}
+	if err := db.Delete("note", "deleted"); err != nil {
+		t.Fatalf("Failed to delete: %v", err)
+	}
+
+	dirPath, _, _ := databasePath(namespace, name)
+	dir := path.Join(dirPath, "test_export_by_grouping")
+	os.RemoveAll(dir)
+	defer os.RemoveAll(dir)
+
+	manifest, err := db.ExportByGrouping(dir)
+	if err != nil {
+		t.Fatalf("Failed ExportByGrouping: %v", err)
+	}
+	read, err := ReadExportManifest(dir)
+	if err != nil {
+		t.Fatalf("Failed to read manifest: %v", err)
+	}
+	if len(read.Groupings) != 3 || read.ExportedAt != manifest.ExportedAt {
+		t.Fatalf("Unexpected manifest: %+v", read)
+	}
+
+	expected := map[string]int64{"": 1, "beta": 1, "project/alpha": 2}
+	for _, grouping := range read.Groupings {
+		if grouping.Entries != expected[grouping.Grouping] {
+			t.Errorf("Expected %d entries in %q, got %d", expected[grouping.Grouping], grouping.Grouping, grouping.Entries)
+		}
+	}
+
+	// A single grouping can be restored on its own
+	restored, err := Init(namespace, "test_export_by_grouping_restored")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer restored.Drop()
+	var alpha GroupingExport
+	for _, grouping := range read.Groupings {
+		if grouping.Grouping == "project/alpha" {
+			alpha = grouping
+		}
+	}
+	report, err := restored.Import(path.Join(dir, alpha.File), ConflictSkip)
+	if err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+	if report.Inserted != 2 {
+		t.Errorf("Expected 2 restored entries, got %+v", report)
+	}
+	if entry, _ := restored.Get("note", "b"); entry == nil || entry.GetGrouping() != "project/alpha" {
+		t.Errorf("Unexpected restored entry: %+v", entry)
+	}
+
+	if _, err := db.ExportByGrouping(dir); err == nil {
+		t.Errorf("Expected exporting into the same directory again to fail")
+	}
+}
+
+func TestExportByGroupingReadTimeout(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_export_by_grouping_timeout"
+	db, err := Init(namespace, name, WithReadTimeout(time.Nanosecond))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	if err := db.Upsert(EntryInput{Type: "note", Key: "a", Value: []byte("a"), Grouping: "alpha"}); err != nil {
+		t.Fatalf("Failed to upsert: %v", err)
+	}
+
+	dir := t.TempDir()
+	if _, err := db.ExportByGrouping(dir); err == nil {
+		t.Fatalf("Expected the export to time out")
+	}
+	if stats := db.CancellationStats(); stats.ReadTimeouts != 1 {
+		t.Errorf("Expected one read timeout, got %+v", stats)
+	}
+}
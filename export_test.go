@@ -0,0 +1,62 @@
+package sidb
+
+import (
+	"database/sql"
+	"fmt"
+	"path"
+	"sync"
+	"testing"
+)
+
+func TestExportWhileWriting(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_export"
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	// Every write adds a pair of entries in one transaction, so a torn
+	// snapshot would contain an odd number of entries
+	entryType := "test_type"
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			err := db.BulkUpsert([]EntryInput{
+				{Type: entryType, Value: []byte("data"), Key: fmt.Sprintf("key_%d_a", i)},
+				{Type: entryType, Value: []byte("data"), Key: fmt.Sprintf("key_%d_b", i)},
+			})
+			if err != nil {
+				t.Errorf("Failed to put entries: %v", err)
+				return
+			}
+		}
+	}()
+
+	destPath := path.Join(t.TempDir(), "export.db")
+	if err := db.Export(destPath); err != nil {
+		t.Fatalf("Failed to export database: %v", err)
+	}
+	wg.Wait()
+
+	exported, err := sql.Open(driverName, destPath)
+	if err != nil {
+		t.Fatalf("Failed to open export: %v", err)
+	}
+	defer exported.Close()
+
+	var count int64
+	if err := exported.QueryRow("SELECT COUNT(*) FROM entries").Scan(&count); err != nil {
+		t.Fatalf("Failed to count exported entries: %v", err)
+	}
+	if count%2 != 0 {
+		t.Errorf("Expected a consistent snapshot, got %d entries", count)
+	}
+
+	if err := db.Export(destPath); err == nil {
+		t.Errorf("Expected exporting over an existing file to fail")
+	}
+}
@@ -0,0 +1,153 @@
+package sidb
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// ExtraColumn is a column of the entries table that this package does not
+// manage, such as one added by an application's own migration.
+type ExtraColumn struct {
+	Name string
+	// Definition is the column's type and constraints, such as "TEXT" or
+	// "INTEGER NOT NULL DEFAULT 0". When set, Init adds the column if it is
+	// missing.
+	Definition string
+	// Decode converts the value read from the column, which is nil, int64,
+	// float64, string or []byte. The value is kept as read when Decode is nil.
+	Decode func(value any) (any, error)
+}
+
+// WithExtraColumns makes reads return the given columns in DbEntry.Extra,
+// keyed by column name, and lets UpdateExtra write them. Upserts leave them
+// as they are.
+func WithExtraColumns(columns ...ExtraColumn) Option {
+	return func(options *Options) {
+		options.extraColumns = append(options.extraColumns, columns...)
+	}
+}
+
+// entryColumns returns the columns scanEntry reads, entryColumns followed by
+// the extra columns.
+func (db *Database) entryColumns() string {
+	if len(db.options.extraColumns) == 0 {
+		return entryColumns
+	}
+	columns := entryColumns
+	for _, column := range db.options.extraColumns {
+		columns += `, "` + column.Name + `"`
+	}
+	return columns
+}
+
+func (db *Database) getSQL() string {
+	if len(db.options.extraColumns) == 0 {
+		return getSQL
+	}
+	return "SELECT " + db.entryColumns() + " FROM entries WHERE type = ? AND key = ? AND deletedAt IS NULL"
+}
+
+// extraTargets returns the scan destinations for the extra columns.
+func (db *Database) extraTargets() []any {
+	if len(db.options.extraColumns) == 0 {
+		return nil
+	}
+	targets := make([]any, len(db.options.extraColumns))
+	for i := range targets {
+		targets[i] = new(any)
+	}
+	return targets
+}
+
+func (db *Database) decodeExtra(entry *DbEntry, targets []any) error {
+	if len(targets) == 0 {
+		return nil
+	}
+	entry.Extra = make(map[string]any, len(targets))
+	for i, column := range db.options.extraColumns {
+		value := *targets[i].(*any)
+		if column.Decode != nil {
+			decoded, err := column.Decode(value)
+			if err != nil {
+				return fmt.Errorf("column %s of %s entry %q: %w", column.Name, entry.Type, entry.Key, err)
+			}
+			value = decoded
+		}
+		entry.Extra[column.Name] = value
+	}
+	return nil
+}
+
+// addExtraColumns adds the extra columns that have a definition and are
+// missing from the entries table.
+func addExtraColumns(connection *sql.DB, columns []ExtraColumn) ([]string, error) {
+	var added []string
+	for _, column := range columns {
+		if column.Definition == "" {
+			continue
+		}
+		var exists int
+		err := connection.QueryRow("SELECT 1 FROM pragma_table_info('entries') WHERE name = ?", column.Name).Scan(&exists)
+		if err == nil {
+			continue
+		}
+		if err != sql.ErrNoRows {
+			return nil, err
+		}
+		if _, err := connection.Exec(fmt.Sprintf(`ALTER TABLE entries ADD COLUMN "%s" %s`, column.Name, column.Definition)); err != nil {
+			return nil, err
+		}
+		added = append(added, column.Name)
+	}
+	return added, nil
+}
+
+// UpdateExtra writes extra columns of an entry. Only columns registered with
+// WithExtraColumns can be written. Like any write, it bumps the entry's
+// version.
+func (db *Database) UpdateExtra(entryType string, key string, values map[string]any) (err error) {
+	defer translateImmutable(&err)
+
+	defer db.afterWrite(entryType)
+
+	defer db.lockWrite(false, entryType)()
+
+	if db.connection == nil {
+		return ErrNoDbConnection
+	}
+
+	registered := make(map[string]bool)
+	for _, column := range db.options.extraColumns {
+		registered[column.Name] = true
+	}
+	var assignments []string
+	var args []interface{}
+	for name, value := range values {
+		if !registered[name] {
+			return fmt.Errorf("%s is not an extra column", name)
+		}
+		assignments = append(assignments, `"`+name+`" = ?`)
+		args = append(args, value)
+	}
+	if len(assignments) == 0 {
+		return nil
+	}
+
+	ctx, done := db.writeContext()
+	defer done()
+
+	args = append(args, db.now(), entryType, key)
+	result, err := db.connection.ExecContext(ctx, "UPDATE entries SET "+strings.Join(assignments, ", ")+", version = version + 1, updatedAt = ? WHERE type = ? AND key = ? AND deletedAt IS NULL", args...)
+	if err != nil {
+		return err
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return ErrEntryNotFound
+	}
+	return nil
+}
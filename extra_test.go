@@ -0,0 +1,79 @@
+package sidb
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtraColumns(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_extra_columns"
+	splitTags := func(value any) (any, error) {
+		if value == nil {
+			return []string(nil), nil
+		}
+		return strings.Split(value.(string), ","), nil
+	}
+	db, err := Init(namespace, name, WithExtraColumns(
+		ExtraColumn{Name: "priority", Definition: "INTEGER NOT NULL DEFAULT 0"},
+		ExtraColumn{Name: "tags", Definition: "TEXT", Decode: splitTags},
+	))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	if err := db.Upsert(EntryInput{Type: "task", Key: "a", Value: []byte("a")}); err != nil {
+		t.Fatalf("Failed to upsert: %v", err)
+	}
+	entry, err := db.Get("task", "a")
+	if err != nil {
+		t.Fatalf("Failed to get: %v", err)
+	}
+	if !reflect.DeepEqual(entry.Extra, map[string]any{"priority": int64(0), "tags": []string(nil)}) {
+		t.Errorf("Unexpected defaults: %+v", entry.Extra)
+	}
+
+	if err := db.UpdateExtra("task", "a", map[string]any{"priority": 3, "tags": "home,urgent"}); err != nil {
+		t.Fatalf("Failed UpdateExtra: %v", err)
+	}
+	// Upserts leave the extra columns alone
+	if err := db.Upsert(EntryInput{Type: "task", Key: "a", Value: []byte("a2")}); err != nil {
+		t.Fatalf("Failed to upsert: %v", err)
+	}
+
+	taskType := "task"
+	entries, err := db.Query(QueryParams{Type: &taskType})
+	if err != nil {
+		t.Fatalf("Failed to query: %v", err)
+	}
+	expected := map[string]any{"priority": int64(3), "tags": []string{"home", "urgent"}}
+	if len(entries) != 1 || !reflect.DeepEqual(entries[0].Extra, expected) || string(entries[0].Value) != "a2" || entries[0].Version != 3 {
+		t.Errorf("Unexpected entries: %+v", entries)
+	}
+
+	if err := db.UpdateExtra("task", "a", map[string]any{"value": []byte("x")}); err == nil {
+		t.Errorf("Expected writing a column that is not extra to fail")
+	}
+	if err := db.UpdateExtra("task", "missing", map[string]any{"priority": 1}); err != ErrEntryNotFound {
+		t.Errorf("Expected ErrEntryNotFound, got %v", err)
+	}
+
+	// Without the option the columns are kept but not read
+	if err := db.Close(); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+	plain, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer plain.Close()
+	entry, err = plain.Get("task", "a")
+	if err != nil {
+		t.Fatalf("Failed to get: %v", err)
+	}
+	if entry.Extra != nil {
+		t.Errorf("Expected no extra columns, got %+v", entry.Extra)
+	}
+}
@@ -0,0 +1,66 @@
+package sidb
+
+import (
+	"runtime"
+	"sync"
+)
+
+type KeyedValue[T any] struct {
+	Key   string
+	Value T
+}
+
+// GetMany fetches keys in one BulkGet and decodes the values concurrently.
+// Found values are returned in the order of keys, followed by the keys that
+// do not exist.
+func (store *Store[T]) GetMany(keys []string) ([]KeyedValue[T], []string, error) {
+	normalized := store.keys(keys)
+	entries, err := store.db.BulkGet(store.entryType, normalized)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var found []KeyedValue[T]
+	var foundEntries []DbEntry
+	var missing []string
+	for i, key := range keys {
+		entry, ok := entries[normalized[i]]
+		if !ok {
+			missing = append(missing, key)
+			continue
+		}
+		found = append(found, KeyedValue[T]{Key: key})
+		foundEntries = append(foundEntries, entry)
+	}
+
+	var wg sync.WaitGroup
+	var errOnce sync.Once
+	var decodeErr error
+	next := make(chan int)
+
+	for worker := 0; worker < min(runtime.GOMAXPROCS(0), len(found)); worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				value, err := store.decode(found[i].Key, foundEntries[i].Value)
+				if err != nil {
+					errOnce.Do(func() { decodeErr = err })
+					continue
+				}
+				found[i].Value = value
+			}
+		}()
+	}
+
+	for i := range found {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	if decodeErr != nil {
+		return nil, nil, decodeErr
+	}
+	return found, missing, nil
+}
@@ -0,0 +1,51 @@
+package sidb
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestStoreGetMany(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_get_many"
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to init db: %v", err)
+	}
+	defer db.Drop()
+
+	store := MakeStore(db, "test_type", serializeTestItem, deserializeTestItem, nil)
+
+	var inputs []StoreEntryInput[testItem]
+	for i := 0; i < 50; i++ {
+		inputs = append(inputs, StoreEntryInput[testItem]{Key: fmt.Sprintf("key_%d", i), Value: testItem{Name: fmt.Sprintf("item_%d", i), Value: i}})
+	}
+	if err := store.BulkUpsert(inputs); err != nil {
+		t.Fatalf("Failed BulkUpsert: %v", err)
+	}
+
+	found, missing, err := store.GetMany([]string{"key_7", "nope", "key_3", "key_49", "gone"})
+	if err != nil {
+		t.Fatalf("Failed GetMany: %v", err)
+	}
+
+	if len(found) != 3 {
+		t.Fatalf("Expected 3 values, got %d", len(found))
+	}
+	for i, expected := range []int{7, 3, 49} {
+		key := fmt.Sprintf("key_%d", expected)
+		if found[i].Key != key || found[i].Value.Value != expected {
+			t.Errorf("At index %d, expected %s, got %+v", i, key, found[i])
+		}
+	}
+	if fmt.Sprint(missing) != "[nope gone]" {
+		t.Errorf("Expected [nope gone] missing, got %v", missing)
+	}
+
+	failing := MakeStore(db, "test_type", serializeTestItem, func([]byte) (testItem, error) {
+		return testItem{}, fmt.Errorf("decode failed intentionally")
+	}, nil)
+	if _, _, err := failing.GetMany([]string{"key_1", "key_2"}); err == nil {
+		t.Errorf("Expected decode error, got nil")
+	}
+}
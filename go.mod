@@ -2,4 +2,7 @@ module github.com/germtb/sidb
 
 go 1.23.4
 
-require github.com/mattn/go-sqlite3 v1.14.32
+require (
+	github.com/mattn/go-sqlite3 v1.14.32
+	golang.org/x/text v0.21.0
+)
@@ -0,0 +1,129 @@
+package sidb
+
+import (
+	"fmt"
+	"time"
+)
+
+type GroupingStats struct {
+	Grouping     string
+	Count        int64
+	MinTimestamp int64
+	MaxTimestamp int64
+}
+
+func (db *Database) GroupingStats(entryType string) ([]GroupingStats, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	if db.connection == nil {
+		return nil, ErrNoDbConnection
+	}
+
+	ctx, done := db.readContext()
+	defer done()
+
+	rows, err := db.connection.QueryContext(ctx, `SELECT COALESCE(grouping, ''), COUNT(*), MIN(timestamp), MAX(timestamp)
+		FROM entries WHERE type = ? AND deletedAt IS NULL GROUP BY COALESCE(grouping, '') ORDER BY 1`, entryType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []GroupingStats
+	for rows.Next() {
+		var stat GroupingStats
+		if err := rows.Scan(&stat.Grouping, &stat.Count, &stat.MinTimestamp, &stat.MaxTimestamp); err != nil {
+			return nil, err
+		}
+		stats = append(stats, stat)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// ListGroupings returns the distinct non-empty groupings of a type in order.
+// Use GroupingStats when counts are needed as well.
+func (db *Database) ListGroupings(entryType string) ([]string, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	if db.connection == nil {
+		return nil, ErrNoDbConnection
+	}
+
+	ctx, done := db.readContext()
+	defer done()
+
+	rows, err := db.connection.QueryContext(ctx, "SELECT DISTINCT grouping FROM entries WHERE type = ? AND deletedAt IS NULL AND grouping IS NOT NULL AND grouping != '' ORDER BY grouping", entryType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var groupings []string
+	for rows.Next() {
+		var grouping string
+		if err := rows.Scan(&grouping); err != nil {
+			return nil, err
+		}
+		groupings = append(groupings, grouping)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return groupings, nil
+}
+
+type ActivityBucket struct {
+	Start int64
+	Count int64
+}
+
+// GroupingActivity counts the entries of a grouping per bucket of time, by
+// timestamp. Buckets are aligned to multiples of bucket since the Unix epoch,
+// and empty buckets are omitted. Only the last write of each key is kept, so
+// overwritten entries count once, in the bucket of their latest timestamp.
+func (db *Database) GroupingActivity(entryType string, grouping string, bucket time.Duration) ([]ActivityBucket, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	if db.connection == nil {
+		return nil, ErrNoDbConnection
+	}
+
+	ctx, done := db.readContext()
+	defer done()
+
+	if bucket < time.Millisecond {
+		return nil, fmt.Errorf("bucket must be at least a millisecond, got %v", bucket)
+	}
+
+	condition, args := groupingCondition(grouping)
+	width := bucket.Milliseconds()
+	rows, err := db.connection.QueryContext(ctx, `SELECT timestamp - (timestamp % ?) AS start, COUNT(*)
+		FROM entries WHERE type = ? AND deletedAt IS NULL AND `+condition+` GROUP BY start ORDER BY start`,
+		append([]interface{}{width, entryType}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var buckets []ActivityBucket
+	for rows.Next() {
+		var activity ActivityBucket
+		if err := rows.Scan(&activity.Start, &activity.Count); err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, activity)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return buckets, nil
+}
@@ -0,0 +1,113 @@
+package sidb
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGroupingStats(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_grouping"
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	entryType := "test_type"
+	err = db.BulkUpsert([]EntryInput{
+		{Type: entryType, Value: []byte("data_1"), Key: "key_1", Grouping: "g1", Timestamp: ptr(int64(10))},
+		{Type: entryType, Value: []byte("data_2"), Key: "key_2", Grouping: "g1", Timestamp: ptr(int64(30))},
+		{Type: entryType, Value: []byte("data_3"), Key: "key_3", Grouping: "g2", Timestamp: ptr(int64(20))},
+		{Type: "other_type", Value: []byte("data_4"), Key: "key_4", Grouping: "g1", Timestamp: ptr(int64(5))},
+	})
+	if err != nil {
+		t.Fatalf("Failed to put entries: %v", err)
+	}
+
+	stats, err := db.GroupingStats(entryType)
+	if err != nil {
+		t.Fatalf("Failed to get grouping stats: %v", err)
+	}
+
+	expected := []GroupingStats{
+		{Grouping: "g1", Count: 2, MinTimestamp: 10, MaxTimestamp: 30},
+		{Grouping: "g2", Count: 1, MinTimestamp: 20, MaxTimestamp: 20},
+	}
+	if len(stats) != len(expected) {
+		t.Fatalf("Expected %d groupings, got %d", len(expected), len(stats))
+	}
+	for i, stat := range stats {
+		if stat != expected[i] {
+			t.Errorf("At index %d, expected %+v, got %+v", i, expected[i], stat)
+		}
+	}
+}
+
+func TestListGroupings(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_grouping"
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	entryType := "test_type"
+	err = db.BulkUpsert([]EntryInput{
+		{Type: entryType, Value: []byte("data_1"), Key: "key_1", Grouping: "b"},
+		{Type: entryType, Value: []byte("data_2"), Key: "key_2", Grouping: "a"},
+		{Type: entryType, Value: []byte("data_3"), Key: "key_3", Grouping: "b"},
+		{Type: entryType, Value: []byte("data_4"), Key: "key_4"},
+		{Type: "other_type", Value: []byte("data_5"), Key: "key_5", Grouping: "c"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to put entries: %v", err)
+	}
+
+	groupings, err := db.ListGroupings(entryType)
+	if err != nil {
+		t.Fatalf("Failed to list groupings: %v", err)
+	}
+
+	if len(groupings) != 2 || groupings[0] != "a" || groupings[1] != "b" {
+		t.Errorf("Expected [a b], got %v", groupings)
+	}
+}
+
+func TestGroupingActivity(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_grouping"
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	entryType := "test_type"
+	hour := time.Hour.Milliseconds()
+	err = db.BulkUpsert([]EntryInput{
+		{Type: entryType, Value: []byte("data_1"), Key: "key_1", Grouping: "g1", Timestamp: ptr(int64(10))},
+		{Type: entryType, Value: []byte("data_2"), Key: "key_2", Grouping: "g1", Timestamp: ptr(hour - 1)},
+		{Type: entryType, Value: []byte("data_3"), Key: "key_3", Grouping: "g1", Timestamp: ptr(3*hour + 5)},
+		{Type: entryType, Value: []byte("data_4"), Key: "key_4", Grouping: "g2", Timestamp: ptr(hour)},
+	})
+	if err != nil {
+		t.Fatalf("Failed to put entries: %v", err)
+	}
+
+	buckets, err := db.GroupingActivity(entryType, "g1", time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to get grouping activity: %v", err)
+	}
+
+	expected := []ActivityBucket{{Start: 0, Count: 2}, {Start: 3 * hour, Count: 1}}
+	if len(buckets) != len(expected) {
+		t.Fatalf("Expected %d buckets, got %d", len(expected), len(buckets))
+	}
+	for i, bucket := range buckets {
+		if bucket != expected[i] {
+			t.Errorf("At index %d, expected %+v, got %+v", i, expected[i], bucket)
+		}
+	}
+}
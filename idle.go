@@ -0,0 +1,15 @@
+package sidb
+
+import "time"
+
+// WithIdleCloseAfter closes the database's connections once they have been
+// unused for timeout, and opens new ones on the next call. Idle connections
+// are looked for about once a second. With no connection left, SQLite
+// checkpoints and removes the write-ahead log, so an idle database holds no
+// file handles. Prepared statements are prepared again on the new
+// connections.
+func WithIdleCloseAfter(timeout time.Duration) Option {
+	return func(options *Options) {
+		options.idleCloseAfter = timeout
+	}
+}
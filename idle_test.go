@@ -0,0 +1,47 @@
+package sidb
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestIdleCloseAfter(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_idle_close"
+	db, err := Init(namespace, name, WithIdleCloseAfter(20*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	if err := db.Upsert(EntryInput{Type: "note", Key: "a", Value: []byte("a")}); err != nil {
+		t.Fatalf("Failed to upsert: %v", err)
+	}
+	if _, err := os.Stat(db.Path + "-wal"); err != nil {
+		t.Fatalf("Expected a write-ahead log while open: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for db.connection.Stats().OpenConnections > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if open := db.connection.Stats().OpenConnections; open != 0 {
+		t.Fatalf("Expected idle connections to be closed, %d open", open)
+	}
+	if _, err := os.Stat(db.Path + "-wal"); !os.IsNotExist(err) {
+		t.Errorf("Expected the write-ahead log to be removed, got %v", err)
+	}
+
+	// The next call reopens a connection
+	entry, err := db.Get("note", "a")
+	if err != nil {
+		t.Fatalf("Failed to get after idling: %v", err)
+	}
+	if entry == nil || string(entry.Value) != "a" {
+		t.Errorf("Unexpected entry: %+v", entry)
+	}
+	if err := db.Upsert(EntryInput{Type: "note", Key: "b", Value: []byte("b")}); err != nil {
+		t.Fatalf("Failed to upsert after idling: %v", err)
+	}
+}
@@ -0,0 +1,29 @@
+package sidb
+
+import (
+	"errors"
+	"strings"
+)
+
+// Immutable entries are enforced by triggers, so no write path, including
+// raw SQL, can change or delete them. Write methods translate the trigger's
+// abort into ErrImmutable.
+
+var ErrImmutable = errors.New("entry is immutable")
+
+const immutableMessage = "sidb: immutable entry"
+
+const immutableTriggersSQL = `
+	CREATE TRIGGER IF NOT EXISTS entries_immutable_update BEFORE UPDATE ON entries WHEN OLD.immutable = 1
+	BEGIN SELECT RAISE(ABORT, '` + immutableMessage + `'); END;
+	CREATE TRIGGER IF NOT EXISTS entries_immutable_delete BEFORE DELETE ON entries WHEN OLD.immutable = 1
+	BEGIN SELECT RAISE(ABORT, '` + immutableMessage + `'); END;
+`
+
+// translateImmutable replaces *err with ErrImmutable when a trigger rejected
+// the write. It is deferred by the write methods.
+func translateImmutable(err *error) {
+	if *err != nil && strings.Contains((*err).Error(), immutableMessage) {
+		*err = ErrImmutable
+	}
+}
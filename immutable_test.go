@@ -0,0 +1,48 @@
+package sidb
+
+import "testing"
+
+func TestImmutableEntries(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_immutable"
+	db, err := Init(namespace, name, WithSoftDelete())
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	entryType := "audit"
+	err = db.BulkUpsert([]EntryInput{
+		{Type: entryType, Value: []byte("record"), Key: "key_1", Grouping: "g1", Immutable: true},
+		{Type: entryType, Value: []byte("mutable"), Key: "key_2", Grouping: "g2"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to put entries: %v", err)
+	}
+
+	if err := db.Upsert(EntryInput{Type: entryType, Value: []byte("changed"), Key: "key_1"}); err != ErrImmutable {
+		t.Errorf("Expected ErrImmutable on upsert, got %v", err)
+	}
+	if err := db.Update(EntryInput{Type: entryType, Value: []byte("changed"), Key: "key_1"}); err != ErrImmutable {
+		t.Errorf("Expected ErrImmutable on update, got %v", err)
+	}
+	if err := db.Delete(entryType, "key_1"); err != ErrImmutable {
+		t.Errorf("Expected ErrImmutable on delete, got %v", err)
+	}
+	if _, err := db.RawExec("DELETE FROM entries WHERE key = 'key_1'"); err == nil {
+		t.Errorf("Expected raw delete to be rejected")
+	}
+
+	entry, err := db.Get(entryType, "key_1")
+	if err != nil {
+		t.Fatalf("Failed to get entry: %v", err)
+	}
+	if entry == nil || string(entry.Value) != "record" || !entry.Immutable {
+		t.Errorf("Expected the immutable entry unchanged, got %+v", entry)
+	}
+
+	// Mutable entries next to it are unaffected
+	if err := db.Delete(entryType, "key_2"); err != nil {
+		t.Errorf("Failed to delete mutable entry: %v", err)
+	}
+}
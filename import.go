@@ -0,0 +1,183 @@
+package sidb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// ConflictStrategy decides what Import does with an entry whose type and key
+// already exist.
+type ConflictStrategy int
+
+const (
+	ConflictSkip       ConflictStrategy = iota // Keep the existing entry
+	ConflictOverwrite                          // Replace the existing entry
+	ConflictNewestWins                         // Keep whichever was updated last
+	ConflictRename                             // Import under the first free key of key~1, key~2, ...
+)
+
+type ImportFailure struct {
+	Type   string
+	Key    string
+	Reason string
+}
+
+type ImportReport struct {
+	Inserted   int64 // Entries written, including overwritten and renamed ones
+	Skipped    int64 // Conflicting entries left out by the strategy
+	Conflicted int64 // Entries whose type and key already existed
+	Failed     []ImportFailure
+}
+
+// Import copies the live entries of a database file, such as one written by
+// Export, into db, handling existing keys with strategy. Entries that cannot
+// be written, like replacements of immutable entries, are reported in Failed
+// while the rest are imported. Entries are written as they are read, in one
+// transaction that is rejected if it takes a type over its quota.
+func (db *Database) Import(srcPath string, strategy ConflictStrategy) (*ImportReport, error) {
+	// The imported types are only known once the source has been read
+	var importedTypes []string
+	defer func() {
+		if len(importedTypes) > 0 {
+			db.afterWrite(importedTypes...)
+		}
+	}()
+
+	defer db.lockWrite(true)()
+
+	if db.connection == nil {
+		return nil, ErrNoDbConnection
+	}
+
+	if _, err := os.Stat(srcPath); err != nil {
+		return nil, err
+	}
+
+	ctx, done := db.writeContext()
+	defer done()
+
+	conn, err := db.connection.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "ATTACH DATABASE ? AS source", srcPath); err != nil {
+		return nil, err
+	}
+	// Not ctx: a timed out import must still detach the source
+	defer conn.ExecContext(context.Background(), "DETACH DATABASE source")
+
+	columns, err := sharedColumns(ctx, conn, "source")
+	if err != nil {
+		return nil, err
+	}
+	column := make(map[string]int)
+	for i, name := range columns {
+		column[strings.Trim(name, `"`)] = i
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	insert := fmt.Sprintf("INSERT INTO main.entries (%s) VALUES (%s)", strings.Join(columns, ", "), placeholders(len(columns))) + replaceColumns(columns)
+	exists := func(entryType string, key string) (bool, int64, error) {
+		var updatedAt int64
+		err := tx.QueryRowContext(ctx, "SELECT COALESCE(updatedAt, timestamp) FROM main.entries WHERE type = ? AND key = ? AND deletedAt IS NULL", entryType, key).Scan(&updatedAt)
+		if err == sql.ErrNoRows {
+			return false, 0, nil
+		}
+		return err == nil, updatedAt, err
+	}
+
+	rows, err := tx.QueryContext(ctx, "SELECT "+strings.Join(columns, ", ")+" FROM source.entries WHERE deletedAt IS NULL ORDER BY type, key")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	report := &ImportReport{}
+	values := make([]any, len(columns))
+	targets := make([]any, len(columns))
+	for i := range values {
+		targets[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(targets...); err != nil {
+			return nil, err
+		}
+		entryType, key := asString(values[column["type"]]), asString(values[column["key"]])
+
+		found, existingUpdatedAt, err := exists(entryType, key)
+		if err != nil {
+			return nil, err
+		}
+
+		if found {
+			report.Conflicted++
+
+			write := false
+			switch strategy {
+			case ConflictOverwrite:
+				write = true
+			case ConflictNewestWins:
+				updatedAt, _ := values[column["updatedAt"]].(int64)
+				if updatedAt == 0 {
+					updatedAt, _ = values[column["timestamp"]].(int64)
+				}
+				write = updatedAt > existingUpdatedAt
+			case ConflictRename:
+				for suffix := 1; found; suffix++ {
+					renamed := fmt.Sprintf("%s~%d", key, suffix)
+					if found, _, err = exists(entryType, renamed); err != nil {
+						return nil, err
+					}
+					values[column["key"]] = renamed
+				}
+				write = true
+			}
+			if !write {
+				report.Skipped++
+				continue
+			}
+		}
+
+		if _, err := tx.ExecContext(ctx, insert, values...); err != nil {
+			translateImmutable(&err)
+			report.Failed = append(report.Failed, ImportFailure{Type: entryType, Key: key, Reason: err.Error()})
+			continue
+		}
+		report.Inserted++
+		if !slices.Contains(importedTypes, entryType) {
+			importedTypes = append(importedTypes, entryType)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := db.checkQuotas(ctx, tx, importedTypes...); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func asString(value any) string {
+	switch value := value.(type) {
+	case string:
+		return value
+	case []byte:
+		return string(value)
+	}
+	return fmt.Sprint(value)
+}
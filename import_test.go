@@ -0,0 +1,160 @@
+package sidb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestImport(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	src, err := Init(namespace, "test_import_src")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer src.Drop()
+
+	now := int64(1000)
+	db, err := Init(namespace, "test_import_dest", WithClock(func() int64 { return now }))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	err = src.BulkUpsert([]EntryInput{
+		{Type: "note", Key: "new", Value: []byte("imported")},
+		{Type: "note", Key: "shared", Value: []byte("imported")},
+		{Type: "note", Key: "pinned", Value: []byte("imported")},
+	})
+	if err != nil {
+		t.Fatalf("Failed to put entries: %v", err)
+	}
+	_, exportPath, _ := databasePath(namespace, "test_import_export")
+	os.Remove(exportPath)
+	defer os.Remove(exportPath)
+	if err := src.Export(exportPath); err != nil {
+		t.Fatalf("Failed to export: %v", err)
+	}
+
+	reset := func() {
+		if _, err := db.RawExec("DELETE FROM entries WHERE immutable = 0"); err != nil {
+			t.Fatalf("Failed to clear database: %v", err)
+		}
+		if err := db.Upsert(EntryInput{Type: "note", Key: "shared", Value: []byte("existing")}); err != nil {
+			t.Fatalf("Failed to put entry: %v", err)
+		}
+		// Already there after the first reset
+		err := db.Upsert(EntryInput{Type: "note", Key: "pinned", Value: []byte("existing"), Immutable: true})
+		if err != nil && err != ErrImmutable {
+			t.Fatalf("Failed to put entry: %v", err)
+		}
+	}
+	value := func(key string) string {
+		entry, err := db.Get("note", key)
+		if err != nil || entry == nil {
+			return ""
+		}
+		return string(entry.Value)
+	}
+
+	reset()
+	report, err := db.Import(exportPath, ConflictSkip)
+	if err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+	if report.Inserted != 1 || report.Skipped != 2 || report.Conflicted != 2 || len(report.Failed) != 0 {
+		t.Errorf("Unexpected skip report: %+v", report)
+	}
+	if value("new") != "imported" || value("shared") != "existing" {
+		t.Errorf("Expected only the new entry to be imported")
+	}
+
+	reset()
+	report, err = db.Import(exportPath, ConflictOverwrite)
+	if err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+	if report.Inserted != 2 || report.Conflicted != 2 || len(report.Failed) != 1 || report.Failed[0].Key != "pinned" {
+		t.Errorf("Unexpected overwrite report: %+v", report)
+	}
+	if value("shared") != "imported" || value("pinned") != "existing" {
+		t.Errorf("Expected the mutable entry to be overwritten")
+	}
+
+	// The source entries were written by the system clock, long after 1000
+	reset()
+	report, err = db.Import(exportPath, ConflictNewestWins)
+	if err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+	if value("shared") != "imported" {
+		t.Errorf("Expected the newer entry to win, got %+v", report)
+	}
+
+	reset()
+	report, err = db.Import(exportPath, ConflictRename)
+	if err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+	if report.Inserted != 3 || value("shared") != "existing" || value("shared~1") != "imported" || value("pinned~1") != "imported" {
+		t.Errorf("Expected conflicting entries to be renamed, got %+v", report)
+	}
+}
+
+func TestImportLimits(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	src, err := Init(namespace, "test_import_limits_src")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer src.Drop()
+
+	var inputs []EntryInput
+	for i := 0; i < 5; i++ {
+		inputs = append(inputs,
+			EntryInput{Type: "note", Key: fmt.Sprintf("note_%d", i), Value: make([]byte, 40), Timestamp: ptr(int64(i))},
+			EntryInput{Type: "log", Key: fmt.Sprintf("log_%d", i), Value: []byte("log"), Timestamp: ptr(int64(i))})
+	}
+	if err := src.BulkUpsert(inputs); err != nil {
+		t.Fatalf("Failed to put entries: %v", err)
+	}
+	_, exportPath, _ := databasePath(namespace, "test_import_limits_export")
+	os.Remove(exportPath)
+	defer os.Remove(exportPath)
+	if err := src.Export(exportPath); err != nil {
+		t.Fatalf("Failed to export: %v", err)
+	}
+
+	db, err := Init(namespace, "test_import_limits_dest", WithQuota("note", 100, QuotaReject), WithMaxEntries("log", 2))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	if _, err := db.Import(exportPath, ConflictSkip); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("Expected ErrQuotaExceeded, got %v", err)
+	}
+	if count, _ := db.CountWhere(QueryParams{}); count != 0 {
+		t.Errorf("Expected the rejected import to write nothing, got %d entries", count)
+	}
+
+	// Without the notes, the import fits and the logs are evicted down to the cap
+	if _, err := src.DeleteWhere(QueryParams{Type: ptr("note")}); err != nil {
+		t.Fatalf("Failed to delete: %v", err)
+	}
+	os.Remove(exportPath)
+	if err := src.Export(exportPath); err != nil {
+		t.Fatalf("Failed to export: %v", err)
+	}
+	report, err := db.Import(exportPath, ConflictSkip)
+	if err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+	if report.Inserted != 5 {
+		t.Errorf("Expected 5 entries imported, got %+v", report)
+	}
+	if count, _ := db.CountWhere(QueryParams{Type: ptr("log")}); count != 2 {
+		t.Errorf("Expected the logs to be evicted down to 2, got %d", count)
+	}
+}
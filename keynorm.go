@@ -0,0 +1,50 @@
+package sidb
+
+import (
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Key normalization makes a store treat keys that differ only in ways its
+// writers do not agree on, like case or surrounding spaces, as the same key.
+
+func TrimKey(key string) string {
+	return strings.TrimSpace(key)
+}
+
+func LowercaseKey(key string) string {
+	return strings.ToLower(key)
+}
+
+// NFCKey composes the key's characters, so "é" typed as one code point and as
+// "e" plus a combining accent are the same key.
+func NFCKey(key string) string {
+	return norm.NFC.String(key)
+}
+
+// WithKeyNormalization applies normalizers, in order, to every key the store
+// reads or writes, and returns the store. Keys already stored are not
+// rewritten.
+func (store *Store[T]) WithKeyNormalization(normalizers ...func(string) string) *Store[T] {
+	store.normalizers = normalizers
+	return store
+}
+
+func (store *Store[T]) key(key string) string {
+	for _, normalize := range store.normalizers {
+		key = normalize(key)
+	}
+	return key
+}
+
+func (store *Store[T]) keys(keys []string) []string {
+	if len(store.normalizers) == 0 {
+		return keys
+	}
+	normalized := make([]string, len(keys))
+	for i, key := range keys {
+		normalized[i] = store.key(key)
+	}
+	return normalized
+}
@@ -0,0 +1,59 @@
+package sidb
+
+import "testing"
+
+func TestKeyNormalization(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_key_normalization"
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	store := MakeStore(db, "test_type", serializeTestItem, deserializeTestItem, nil).WithKeyNormalization(TrimKey, LowercaseKey)
+
+	if err := store.Upsert(StoreEntryInput[testItem]{Key: " Alice ", Value: testItem{Name: "alice", Value: 1}}); err != nil {
+		t.Fatalf("Failed to upsert: %v", err)
+	}
+	if err := store.Upsert(StoreEntryInput[testItem]{Key: "ALICE", Value: testItem{Name: "alice", Value: 2}}); err != nil {
+		t.Fatalf("Failed to upsert: %v", err)
+	}
+
+	count, err := store.Count()
+	if err != nil {
+		t.Fatalf("Failed to count: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected both spellings to write one entry, got %d", count)
+	}
+	if exists, _ := db.Exists("test_type", "alice"); !exists {
+		t.Errorf("Expected the entry to be stored under the normalized key")
+	}
+
+	item, err := store.Get("Alice")
+	if err != nil || item.Value != 2 {
+		t.Errorf("Expected the latest value, got %+v (%v)", item, err)
+	}
+	items, err := store.BulkGet([]string{"aLiCe", "bob"})
+	if err != nil {
+		t.Fatalf("Failed BulkGet: %v", err)
+	}
+	if len(items) != 1 || items["aLiCe"].Value != 2 {
+		t.Errorf("Expected results under the given keys, got %+v", items)
+	}
+
+	if err := store.Delete(" alice"); err != nil {
+		t.Fatalf("Failed to delete: %v", err)
+	}
+	if exists, _ := store.Exists("alice"); exists {
+		t.Errorf("Expected the entry to be deleted")
+	}
+}
+
+func TestNFCKey(t *testing.T) {
+	composed, decomposed := "café", "café"
+	if NFCKey(decomposed) != composed || NFCKey(composed) != composed {
+		t.Errorf("Expected both spellings to normalize to %q, got %q and %q", composed, NFCKey(composed), NFCKey(decomposed))
+	}
+}
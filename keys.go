@@ -0,0 +1,114 @@
+package sidb
+
+type ListKeysOptions struct {
+	Prefix string
+	Limit  *int
+	After  string // Only return keys strictly after this one, usually the last key of the previous page
+}
+
+// prefixUpperBound returns the smallest string greater than every string
+// starting with prefix, or "" when there is none.
+func prefixUpperBound(prefix string) string {
+	bound := []byte(prefix)
+	for i := len(bound) - 1; i >= 0; i-- {
+		if bound[i] < 0xff {
+			bound[i]++
+			return string(bound[:i+1])
+		}
+	}
+	return ""
+}
+
+// keyRange returns the conditions selecting keys with prefix after the given
+// key, written as ranges so the primary key index can be used.
+func keyRange(prefix string, after string) (string, []interface{}) {
+	where := ""
+	var args []interface{}
+
+	if prefix != "" {
+		where += " AND key >= ?"
+		args = append(args, prefix)
+		if upper := prefixUpperBound(prefix); upper != "" {
+			where += " AND key < ?"
+			args = append(args, upper)
+		}
+	}
+
+	if after != "" {
+		where += " AND key > ?"
+		args = append(args, after)
+	}
+
+	return where, args
+}
+
+// ListKeys returns keys of a type in ascending order without loading values.
+func (db *Database) ListKeys(entryType string, opts ListKeysOptions) ([]string, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	if db.connection == nil {
+		return nil, ErrNoDbConnection
+	}
+
+	ctx, done := db.readContext()
+	defer done()
+
+	rangeWhere, rangeArgs := keyRange(opts.Prefix, opts.After)
+	query := "SELECT key FROM entries WHERE type = ? AND deletedAt IS NULL" + rangeWhere + " ORDER BY key ASC"
+	args := append([]interface{}{entryType}, rangeArgs...)
+
+	if opts.Limit != nil {
+		query += " LIMIT ?"
+		args = append(args, *opts.Limit)
+	}
+
+	rows, err := db.connection.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+const forEachKeyPageSize = 256
+
+// ForEachKey calls fn for every key of a type starting with prefix, in
+// ascending order, until fn returns false or an error. Keys are read in pages
+// and fn runs without holding the database lock, so it may use the database.
+func (db *Database) ForEachKey(entryType string, prefix string, fn func(key string) (bool, error)) error {
+	limit := forEachKeyPageSize
+	opts := ListKeysOptions{Prefix: prefix, Limit: &limit}
+
+	for {
+		keys, err := db.ListKeys(entryType, opts)
+		if err != nil {
+			return err
+		}
+
+		for _, key := range keys {
+			cont, err := fn(key)
+			if err != nil || !cont {
+				return err
+			}
+		}
+
+		if len(keys) < limit {
+			return nil
+		}
+		opts.After = keys[len(keys)-1]
+	}
+}
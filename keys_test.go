@@ -0,0 +1,110 @@
+package sidb
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestListKeys(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_keys"
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	entryType := "test_type"
+	err = db.BulkUpsert([]EntryInput{
+		{Type: entryType, Value: []byte("data"), Key: "user:3"},
+		{Type: entryType, Value: []byte("data"), Key: "user:1"},
+		{Type: entryType, Value: []byte("data"), Key: "user:2"},
+		{Type: entryType, Value: []byte("data"), Key: "users"},
+		{Type: entryType, Value: []byte("data"), Key: "team:1"},
+		{Type: "other_type", Value: []byte("data"), Key: "user:4"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to put entries: %v", err)
+	}
+
+	limit := 2
+	var pages [][]string
+	opts := ListKeysOptions{Prefix: "user:", Limit: &limit}
+	for {
+		keys, err := db.ListKeys(entryType, opts)
+		if err != nil {
+			t.Fatalf("Failed to list keys: %v", err)
+		}
+		if len(keys) == 0 {
+			break
+		}
+		pages = append(pages, keys)
+		opts.After = keys[len(keys)-1]
+	}
+
+	expected := "[[user:1 user:2] [user:3]]"
+	if fmt.Sprint(pages) != expected {
+		t.Errorf("Expected %s, got %v", expected, pages)
+	}
+}
+
+func TestPrefixUpperBound(t *testing.T) {
+	if bound := prefixUpperBound("ab"); bound != "ac" {
+		t.Errorf("Expected ac, got %q", bound)
+	}
+	if bound := prefixUpperBound("a\xff"); bound != "b" {
+		t.Errorf("Expected b, got %q", bound)
+	}
+	if bound := prefixUpperBound("\xff"); bound != "" {
+		t.Errorf("Expected no bound, got %q", bound)
+	}
+}
+
+func TestForEachKey(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_keys"
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	entryType := "test_type"
+	var entries []EntryInput
+	for i := 0; i < 600; i++ {
+		entries = append(entries, EntryInput{Type: entryType, Value: []byte("data"), Key: fmt.Sprintf("item:%04d", i)})
+	}
+	entries = append(entries, EntryInput{Type: entryType, Value: []byte("data"), Key: "other"})
+	if err := db.BulkUpsert(entries); err != nil {
+		t.Fatalf("Failed to put entries: %v", err)
+	}
+
+	count := 0
+	last := ""
+	err = db.ForEachKey(entryType, "item:", func(key string) (bool, error) {
+		if key <= last {
+			t.Errorf("Expected keys in ascending order, got %s after %s", key, last)
+		}
+		last = key
+		count++
+		return true, nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to iterate keys: %v", err)
+	}
+	if count != 600 {
+		t.Errorf("Expected 600 keys, got %d", count)
+	}
+
+	var first []string
+	err = db.ForEachKey(entryType, "item:", func(key string) (bool, error) {
+		first = append(first, key)
+		return len(first) < 3, nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to iterate keys: %v", err)
+	}
+	if fmt.Sprint(first) != "[item:0000 item:0001 item:0002]" {
+		t.Errorf("Expected to stop after 3 keys, got %v", first)
+	}
+}
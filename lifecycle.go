@@ -0,0 +1,84 @@
+package sidb
+
+import (
+	"errors"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+type LifecycleEventKind int
+
+const (
+	EventOpened LifecycleEventKind = iota
+	EventClosed
+	EventMigrated
+	EventVacuumed
+	EventBackupCompleted
+	EventCorruptionDetected
+)
+
+func (kind LifecycleEventKind) String() string {
+	switch kind {
+	case EventOpened:
+		return "opened"
+	case EventClosed:
+		return "closed"
+	case EventMigrated:
+		return "migrated"
+	case EventVacuumed:
+		return "vacuumed"
+	case EventBackupCompleted:
+		return "backup-completed"
+	case EventCorruptionDetected:
+		return "corruption-detected"
+	}
+	return "unknown"
+}
+
+type LifecycleEvent struct {
+	Kind     LifecycleEventKind
+	Path     string        // The database file
+	Duration time.Duration // How long a migration, vacuum or backup took
+	Detail   string        // The columns a migration added, or a backup's destination
+	Err      error         // The error that revealed corruption
+}
+
+// A LifecycleObserver is told about events in a database's life. It is called
+// synchronously after the database lock is released, so it may use the
+// database but should hand slow work off. Corruption can be found while the
+// database is locked and is reported from a goroutine of its own.
+type LifecycleObserver interface {
+	OnLifecycleEvent(event LifecycleEvent)
+}
+
+// WithLifecycleObserver adds an observer. Corruption is reported when opening
+// the file, a full read such as Vacuum or Export, or a signature check fails
+// because of it.
+func WithLifecycleObserver(observer LifecycleObserver) Option {
+	return func(options *Options) {
+		options.observers = append(options.observers, observer)
+	}
+}
+
+func (options *Options) emit(event LifecycleEvent) {
+	for _, observer := range options.observers {
+		observer.OnLifecycleEvent(event)
+	}
+}
+
+func (db *Database) emit(kind LifecycleEventKind, started time.Time, detail string) {
+	db.options.emit(LifecycleEvent{Kind: kind, Path: db.Path, Duration: time.Since(started), Detail: detail})
+}
+
+// reportCorruption emits EventCorruptionDetected if err shows the file or an
+// entry is damaged, and returns err unchanged.
+func (options *Options) reportCorruption(path string, err error) error {
+	var sqliteErr sqlite3.Error
+	corrupt := errors.Is(err, ErrTampered) ||
+		(errors.As(err, &sqliteErr) && (sqliteErr.Code == sqlite3.ErrCorrupt || sqliteErr.Code == sqlite3.ErrNotADB))
+	if corrupt && len(options.observers) > 0 {
+		go options.emit(LifecycleEvent{Kind: EventCorruptionDetected, Path: path, Err: err})
+	}
+	return err
+}
@@ -0,0 +1,149 @@
+package sidb
+
+import (
+	"database/sql"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingObserver struct {
+	mutex      sync.Mutex
+	events     []LifecycleEvent
+	corruption chan LifecycleEvent
+}
+
+func newRecordingObserver() *recordingObserver {
+	return &recordingObserver{corruption: make(chan LifecycleEvent, 1)}
+}
+
+func (observer *recordingObserver) OnLifecycleEvent(event LifecycleEvent) {
+	if event.Kind == EventCorruptionDetected {
+		observer.corruption <- event
+		return
+	}
+	observer.mutex.Lock()
+	defer observer.mutex.Unlock()
+	observer.events = append(observer.events, event)
+}
+
+func (observer *recordingObserver) kinds() []string {
+	observer.mutex.Lock()
+	defer observer.mutex.Unlock()
+	var kinds []string
+	for _, event := range observer.events {
+		kinds = append(kinds, event.Kind.String())
+	}
+	return kinds
+}
+
+func (observer *recordingObserver) waitForCorruption(t *testing.T) LifecycleEvent {
+	select {
+	case event := <-observer.corruption:
+		return event
+	case <-time.After(time.Second):
+		t.Fatalf("Expected a corruption event")
+		return LifecycleEvent{}
+	}
+}
+
+func TestLifecycleEvents(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_lifecycle_events"
+	dirPath, dbPath, _ := databasePath(namespace, name)
+	if err := os.MkdirAll(dirPath, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	os.Remove(dbPath)
+
+	// A table from before subgroupings makes Init migrate it
+	connection, err := sql.Open(driverName, dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	_, err = connection.Exec(`CREATE TABLE entries (
+		"key" TEXT NOT NULL,
+		"type" TEXT NOT NULL,
+		"timestamp" INTEGER NOT NULL,
+		"grouping" TEXT,
+		"sortingIndex" INTEGER,
+		"value" BLOB,
+		PRIMARY KEY ("key", "type")
+	) WITHOUT ROWID`)
+	connection.Close()
+	if err != nil {
+		t.Fatalf("Failed to create old schema: %v", err)
+	}
+
+	observer := newRecordingObserver()
+	db, err := Init(namespace, name, WithLifecycleObserver(observer))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	backupPath := path.Join(dirPath, "test_lifecycle_backup.db")
+	os.Remove(backupPath)
+	defer os.Remove(backupPath)
+	if err := db.Export(backupPath); err != nil {
+		t.Fatalf("Failed to export: %v", err)
+	}
+	if err := db.Vacuum(); err != nil {
+		t.Fatalf("Failed to vacuum: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+
+	expected := "migrated opened backup-completed vacuumed closed"
+	if kinds := strings.Join(observer.kinds(), " "); kinds != expected {
+		t.Errorf("Expected events %q, got %q", expected, kinds)
+	}
+	migration := observer.events[0]
+	if migration.Path != dbPath || !strings.Contains(migration.Detail, "subgrouping") {
+		t.Errorf("Unexpected migration event: %+v", migration)
+	}
+	if backup := observer.events[2]; backup.Detail != backupPath {
+		t.Errorf("Unexpected backup event: %+v", backup)
+	}
+}
+
+func TestCorruptionEvents(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	observer := newRecordingObserver()
+
+	db, err := Init(namespace, "test_corruption_events", WithLifecycleObserver(observer), WithSigningKey([]byte("secret")))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	if err := db.Upsert(EntryInput{Type: "note", Key: "a", Value: []byte("signed")}); err != nil {
+		t.Fatalf("Failed to upsert: %v", err)
+	}
+	if _, err := db.connection.Exec("UPDATE entries SET value = 'edited' WHERE key = 'a'"); err != nil {
+		t.Fatalf("Failed to edit entry: %v", err)
+	}
+	if _, err := db.Get("note", "a"); err != ErrTampered {
+		t.Fatalf("Expected ErrTampered, got %v", err)
+	}
+	if event := observer.waitForCorruption(t); event.Err != ErrTampered || event.Path != db.Path {
+		t.Errorf("Unexpected corruption event: %+v", event)
+	}
+
+	// A file that is not a database is reported before Init fails
+	dirPath, dbPath, _ := databasePath(namespace, "test_corruption_garbage")
+	if err := os.WriteFile(dbPath, []byte(strings.Repeat("not a database ", 100)), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	defer os.Remove(dbPath)
+	if _, err := Init(namespace, "test_corruption_garbage", WithLifecycleObserver(observer)); err == nil {
+		t.Fatalf("Expected Init to fail in %s", dirPath)
+	}
+	if event := observer.waitForCorruption(t); event.Path != dbPath {
+		t.Errorf("Unexpected corruption event: %+v", event)
+	}
+}
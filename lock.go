@@ -0,0 +1,170 @@
+package sidb
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Locks live in their own table so they never show up among the entries.
+// Acquiring is a single conditional upsert that only takes over a row whose
+// lease has expired, so processes sharing the file can race safely. Rows are
+// kept after release so fencing tokens never go back.
+const locksTableSQL = `CREATE TABLE IF NOT EXISTS locks (
+	"name" TEXT NOT NULL PRIMARY KEY,
+	"token" TEXT NOT NULL,
+	"expiresAt" INTEGER NOT NULL,
+	"fence" INTEGER NOT NULL DEFAULT 0
+) WITHOUT ROWID`
+
+// migrateLocks adds the fence column to locks tables created before fencing
+// tokens.
+func migrateLocks(connection *sql.DB) error {
+	var fenced bool
+	err := connection.QueryRow("SELECT COUNT(*) > 0 FROM pragma_table_info('locks') WHERE name = 'fence'").Scan(&fenced)
+	if err != nil || fenced {
+		return err
+	}
+	_, err = connection.Exec(`ALTER TABLE locks ADD COLUMN "fence" INTEGER NOT NULL DEFAULT 0`)
+	return err
+}
+
+var ErrLockHeld = errors.New("lock is held by another owner")
+var ErrLockLost = errors.New("lock expired or was taken over")
+
+type Lock struct {
+	Name      string
+	Token     string
+	ExpiresAt int64
+	// Fence is the lease's fencing token for UpsertWithFence. Each
+	// acquisition of any lock of the database gets a larger one, so it can be
+	// handed to a worker that does not hold the Lock itself.
+	Fence int64
+}
+
+// AcquireLock takes the named lock for ttl, or returns ErrLockHeld if another
+// owner holds an unexpired lease on it.
+func (db *Database) AcquireLock(name string, ttl time.Duration) (*Lock, error) {
+	defer db.lockWrite(false)()
+
+	if db.connection == nil {
+		return nil, ErrNoDbConnection
+	}
+
+	ctx, done := db.writeContext()
+	defer done()
+
+	now := db.now()
+	lock := &Lock{Name: name, Token: NewULID(now), ExpiresAt: now + ttl.Milliseconds()}
+	err := db.connection.QueryRowContext(ctx, `INSERT INTO locks (name, token, expiresAt, fence)
+		VALUES (?, ?, ?, (SELECT COALESCE(MAX(fence), 0) + 1 FROM locks))
+		ON CONFLICT (name) DO UPDATE SET token = excluded.token, expiresAt = excluded.expiresAt, fence = excluded.fence WHERE locks.expiresAt <= ?
+		RETURNING fence`,
+		lock.Name, lock.Token, lock.ExpiresAt, now).Scan(&lock.Fence)
+	if err == sql.ErrNoRows {
+		return nil, ErrLockHeld
+	}
+	if err != nil {
+		return nil, err
+	}
+	return lock, nil
+}
+
+// RenewLock extends a held lock to expire ttl from now. It returns ErrLockLost
+// if the lease already expired.
+func (db *Database) RenewLock(lock *Lock, ttl time.Duration) error {
+	defer db.lockWrite(false)()
+
+	if db.connection == nil {
+		return ErrNoDbConnection
+	}
+
+	ctx, done := db.writeContext()
+	defer done()
+
+	now := db.now()
+	result, err := db.connection.ExecContext(ctx, "UPDATE locks SET expiresAt = ? WHERE name = ? AND token = ? AND expiresAt > ?",
+		now+ttl.Milliseconds(), lock.Name, lock.Token, now)
+	if err != nil {
+		return err
+	}
+	renewed, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if renewed == 0 {
+		return ErrLockLost
+	}
+	lock.ExpiresAt = now + ttl.Milliseconds()
+	return nil
+}
+
+// ReleaseLock gives up a held lock. Releasing a lock that was taken over by
+// another owner leaves it alone and returns ErrLockLost.
+func (db *Database) ReleaseLock(lock *Lock) error {
+	defer db.lockWrite(false)()
+
+	if db.connection == nil {
+		return ErrNoDbConnection
+	}
+
+	ctx, done := db.writeContext()
+	defer done()
+
+	result, err := db.connection.ExecContext(ctx, "UPDATE locks SET expiresAt = 0 WHERE name = ? AND token = ? AND expiresAt > 0", lock.Name, lock.Token)
+	if err != nil {
+		return err
+	}
+	released, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if released == 0 {
+		return ErrLockLost
+	}
+	return nil
+}
+
+// UpsertWithFence writes the entry only while the lease whose Fence is fence
+// is still held, returning ErrLockLost otherwise. The lease is checked in the
+// same transaction as the write, so a worker whose lease expired mid-task
+// cannot overwrite the work of the one that took the lock over.
+func (db *Database) UpsertWithFence(entry EntryInput, fence int64) (err error) {
+	defer translateImmutable(&err)
+
+	defer db.afterWrite(entry.Type)
+
+	defer db.lockWrite(false, entry.Type)()
+
+	if db.connection == nil {
+		return ErrNoDbConnection
+	}
+
+	ctx, done := db.writeContext()
+	defer done()
+
+	tx, err := db.connection.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := db.now()
+	var held int
+	err = tx.QueryRowContext(ctx, "SELECT 1 FROM locks WHERE fence = ? AND fence > 0 AND expiresAt > ?", fence, now).Scan(&held)
+	if err == sql.ErrNoRows {
+		return ErrLockLost
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, upsertSQL(1), db.upsertArgs(entry, now)...); err != nil {
+		return err
+	}
+
+	if err := db.checkQuotas(ctx, tx, entry.Type); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,139 @@
+package sidb
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLocks(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_lock"
+	now := int64(1000)
+	db, err := Init(namespace, name, WithClock(func() int64 { return now }))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	lock, err := db.AcquireLock("syncer", time.Second)
+	if err != nil {
+		t.Fatalf("Failed to acquire lock: %v", err)
+	}
+	if _, err := db.AcquireLock("syncer", time.Second); err != ErrLockHeld {
+		t.Errorf("Expected ErrLockHeld, got %v", err)
+	}
+
+	now += 500
+	if err := db.RenewLock(lock, time.Second); err != nil {
+		t.Fatalf("Failed to renew lock: %v", err)
+	}
+	now += 900
+	if _, err := db.AcquireLock("syncer", time.Second); err != ErrLockHeld {
+		t.Errorf("Expected the renewed lock to be held, got %v", err)
+	}
+
+	// Once expired, another owner takes over and the first loses it
+	now += 200
+	other, err := db.AcquireLock("syncer", time.Second)
+	if err != nil {
+		t.Fatalf("Failed to take over expired lock: %v", err)
+	}
+	if err := db.RenewLock(lock, time.Second); err != ErrLockLost {
+		t.Errorf("Expected ErrLockLost renewing, got %v", err)
+	}
+	if err := db.ReleaseLock(lock); err != ErrLockLost {
+		t.Errorf("Expected ErrLockLost releasing, got %v", err)
+	}
+
+	if err := db.ReleaseLock(other); err != nil {
+		t.Fatalf("Failed to release lock: %v", err)
+	}
+	if _, err := db.AcquireLock("syncer", time.Second); err != nil {
+		t.Errorf("Expected to acquire released lock, got %v", err)
+	}
+}
+
+func TestUpsertWithFence(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_upsert_with_fence"
+	now := int64(1000)
+	db, err := Init(namespace, name, WithClock(func() int64 { return now }))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	lock, err := db.AcquireLock("syncer", time.Second)
+	if err != nil {
+		t.Fatalf("Failed to acquire lock: %v", err)
+	}
+	if err := db.UpsertWithFence(EntryInput{Type: "doc", Key: "a", Value: []byte("first")}, lock.Fence); err != nil {
+		t.Fatalf("Failed fenced upsert: %v", err)
+	}
+
+	// After the lease expires and another owner takes over, the old owner's
+	// writes are refused
+	now += 2000
+	other, err := db.AcquireLock("syncer", time.Second)
+	if err != nil {
+		t.Fatalf("Failed to take over expired lock: %v", err)
+	}
+	if err := db.UpsertWithFence(EntryInput{Type: "doc", Key: "a", Value: []byte("stale")}, lock.Fence); err != ErrLockLost {
+		t.Errorf("Expected ErrLockLost, got %v", err)
+	}
+	if err := db.UpsertWithFence(EntryInput{Type: "doc", Key: "a", Value: []byte("second")}, other.Fence); err != nil {
+		t.Fatalf("Failed fenced upsert: %v", err)
+	}
+
+	entry, err := db.Get("doc", "a")
+	if err != nil {
+		t.Fatalf("Failed to get entry: %v", err)
+	}
+	if string(entry.Value) != "second" {
+		t.Errorf("Expected the new owner's value, got %q", entry.Value)
+	}
+
+	// Fences keep growing across releases, and a released lease fences off
+	// its writes
+	if err := db.ReleaseLock(other); err != nil {
+		t.Fatalf("Failed to release lock: %v", err)
+	}
+	if err := db.UpsertWithFence(EntryInput{Type: "doc", Key: "a", Value: []byte("released")}, other.Fence); err != ErrLockLost {
+		t.Errorf("Expected ErrLockLost after release, got %v", err)
+	}
+	third, err := db.AcquireLock("syncer", time.Second)
+	if err != nil {
+		t.Fatalf("Failed to acquire released lock: %v", err)
+	}
+	if !(lock.Fence < other.Fence && other.Fence < third.Fence) {
+		t.Errorf("Expected increasing fences, got %d, %d and %d", lock.Fence, other.Fence, third.Fence)
+	}
+}
+
+func TestLocksMigrateFence(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_locks_migrate_fence"
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	// As created before fencing tokens
+	if _, err := db.RawExec("ALTER TABLE locks DROP COLUMN fence"); err != nil {
+		t.Fatalf("Failed to drop column: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("Failed to close database: %v", err)
+	}
+	if err := db.Reopen(); err != nil {
+		t.Fatalf("Failed to reopen database: %v", err)
+	}
+	lock, err := db.AcquireLock("syncer", time.Second)
+	if err != nil {
+		t.Fatalf("Failed to acquire lock: %v", err)
+	}
+	if lock.Fence != 1 {
+		t.Errorf("Expected the first fence to be 1, got %d", lock.Fence)
+	}
+}
@@ -1,25 +1,46 @@
 package sidb
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
-	"log"
 	"os"
 	"path"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
-
-	_ "github.com/mattn/go-sqlite3"
 )
 
 // This package is the Si(mple) DB library.
 
 type Database struct {
-	Path       string
-	connection *sql.DB
-	mutex      sync.RWMutex
+	Path            string
+	connection      *sql.DB
+	mutex           sync.RWMutex // Held for reading by every operation, see lockWriter
+	writeMutex      sync.Mutex
+	statementsMutex sync.Mutex
+	statements      map[string]*sql.Stmt
+	options         Options
+
+	thresholdMutex sync.Mutex
+	thresholds     []thresholdWatch
+
+	scheduler *writeScheduler
+
+	readsMutex    sync.Mutex
+	readsCtx      context.Context
+	cancelReads   context.CancelFunc
+	readTimeouts  atomic.Int64
+	writeTimeouts atomic.Int64
+	cancelled     atomic.Int64
+
+	stopRetention chan struct{}
+	writerLock    *os.File
+
+	countsMutex      sync.Mutex
+	refreshingCounts map[string]bool
 }
 
 type EntryInput struct {
@@ -27,8 +48,11 @@ type EntryInput struct {
 	Key          string
 	Value        []byte
 	Grouping     string
+	Subgrouping  string
 	SortingIndex *int64
 	Timestamp    *int64 // Optional: if provided, will be used instead of current time
+	Projection   []byte // Optional: a small summary of the value read by projections
+	Immutable    bool   // Rejects any later write or delete of the entry with ErrImmutable
 }
 
 type DbEntry struct {
@@ -36,66 +60,329 @@ type DbEntry struct {
 	Type         string
 	Key          string
 	Value        []byte
-	Grouping     string
+	Grouping     *string // Nil when stored as NULL, see GetGrouping
+	Subgrouping  string
 	SortingIndex *int64
+	Version      int64 // Starts at 1 and increases on every write to the entry
+	CreatedAt    int64 // When the key was first written, kept across replacements
+	UpdatedAt    int64 // When the entry was last written
+	Immutable    bool
+	Extra        map[string]any // The columns registered with WithExtraColumns
+
+	pooled *[]byte // The buffer Value was read into, see Release
 }
 
-func RootPath() string {
-	home, err := os.UserHomeDir()
+// GetGrouping returns the grouping, or an empty string for a NULL grouping.
+func (entry DbEntry) GetGrouping() string {
+	if entry.Grouping == nil {
+		return ""
+	}
+	return *entry.Grouping
+}
+
+const entryColumns = "timestamp, type, value, key, grouping, sortingIndex, subgrouping, signature, version, createdAt, updatedAt, immutable"
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanEntry reads a row selected with entryColumns, verifying its signature
+// when the type is signed.
+func (db *Database) scanEntry(row rowScanner) (DbEntry, error) {
+	if rows, ok := row.(*sql.Rows); ok && db.options.pooledValues {
+		return db.scanPooledEntry(rows)
+	}
+
+	var entry DbEntry
+	signature, err := db.scanEntryInto(row, &entry, &entry.Value)
+	if err != nil {
+		return entry, err
+	}
+
+	if err := db.verify(entry.Type, entry.Key, entry.Value, signature); err != nil {
+		return entry, err
+	}
+	return entry, nil
+}
+
+// scanEntryInto scans a row of entryColumns into entry, and its value into
+// value, and returns the signature for the caller to verify.
+func (db *Database) scanEntryInto(row rowScanner, entry *DbEntry, value any) ([]byte, error) {
+	var subgrouping sql.NullString
+	var signature []byte
+	var createdAt, updatedAt sql.NullInt64
+	targets := []any{&entry.Timestamp, &entry.Type, value, &entry.Key, &entry.Grouping, &entry.SortingIndex, &subgrouping, &signature, &entry.Version, &createdAt, &updatedAt, &entry.Immutable}
+	extra := db.extraTargets()
+	err := row.Scan(append(targets, extra...)...)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.decodeExtra(entry, extra); err != nil {
+		return nil, err
+	}
+	entry.Subgrouping = subgrouping.String
+	// Rows inserted behind the package's back fall back to their timestamp
+	entry.CreatedAt = createdAt.Int64
+	if !createdAt.Valid {
+		entry.CreatedAt = entry.Timestamp
+	}
+	entry.UpdatedAt = updatedAt.Int64
+	if !updatedAt.Valid {
+		entry.UpdatedAt = entry.Timestamp
+	}
+	return signature, nil
+}
 
+var (
+	rootPathMutex sync.RWMutex
+	rootPath      string
+)
+
+// SetRootPath makes every database opened afterwards live under dir instead
+// of $SIDB_ROOT or ~/.sidb. An empty dir restores the default.
+func SetRootPath(dir string) {
+	rootPathMutex.Lock()
+	defer rootPathMutex.Unlock()
+	rootPath = dir
+}
+
+// RootPath is the directory databases are stored under: the one given to
+// SetRootPath, then $SIDB_ROOT, then ~/.sidb.
+func RootPath() (string, error) {
+	rootPathMutex.RLock()
+	dir := rootPath
+	rootPathMutex.RUnlock()
+	if dir != "" {
+		return dir, nil
+	}
+
+	if dir := os.Getenv("SIDB_ROOT"); dir != "" {
+		return dir, nil
+	}
+
+	home, err := os.UserHomeDir()
 	if err != nil {
-		log.Fatal(err)
+		return "", err
 	}
 
-	return path.Join(home, ".sidb")
+	return path.Join(home, ".sidb"), nil
 }
 
 var ErrNoDbConnection = errors.New("no database connection")
+var ErrConflict = errors.New("entry was modified concurrently")
+
+// An empty grouping means the entry is ungrouped, whether it is stored as
+// NULL or as an empty string.
+func groupingCondition(grouping string) (string, []interface{}) {
+	if grouping == "" {
+		return "(grouping IS NULL OR grouping = '')", nil
+	}
+	return "grouping = ?", []interface{}{grouping}
+}
 
-func Init(namespace []string, name string) (*Database, error) {
-	dirPath := path.Join(append([]string{RootPath()}, namespace...)...)
-	dbPath := path.Join(dirPath, name+".db")
+func placeholders(count int) string {
+	placeholders := strings.Repeat("?,", count)
+	return placeholders[:len(placeholders)-1] // Remove trailing comma
+}
+
+// chunks splits keys so that statements binding one variable per key stay
+// under SQLite's variable limit.
+func chunks(keys []string, size int) [][]string {
+	size = max(size, 1)
+	var result [][]string
+	for start := 0; start < len(keys); start += size {
+		result = append(result, keys[start:min(start+size, len(keys))])
+	}
+	return result
+}
+
+func (db *Database) chunkSize() int {
+	if db.options.chunkSize > 0 {
+		return db.options.chunkSize
+	}
+	// Leaves room for the type and any other fixed variables
+	return maxSQLVariables - 9
+}
+
+func databasePath(namespace []string, name string) (string, string, error) {
+	root, err := RootPath()
+	if err != nil {
+		return "", "", err
+	}
+	dirPath, dbPath := databasePathIn(root, namespace, name)
+	return dirPath, dbPath, nil
+}
+
+func databasePathIn(baseDir string, namespace []string, name string) (string, string) {
+	dirPath := path.Join(append([]string{baseDir}, namespace...)...)
+	return dirPath, path.Join(dirPath, name+".db")
+}
+
+func Init(namespace []string, name string, opts ...Option) (*Database, error) {
+	var options Options
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	dirPath, dbPath, err := options.databasePath(namespace, name)
+	if err != nil {
+		return nil, err
+	}
 
 	// Ensure parent directory exists
-	if err := os.MkdirAll(dirPath, 0755); err != nil {
+	if err := os.MkdirAll(dirPath, options.dirMode()); err != nil {
 		return nil, err
 	}
 
-	connection, err := sql.Open("sqlite3", dbPath)
+	database := &Database{Path: dbPath, mutex: sync.RWMutex{}, options: options}
+	if options.writeConcurrency > 0 {
+		database.scheduler = newWriteScheduler(options.writeConcurrency)
+	}
+
+	database.thresholds = append(database.thresholds, options.thresholds...)
 
+	events, err := database.open()
 	if err != nil {
 		return nil, err
 	}
+	for _, event := range events {
+		options.emit(event)
+	}
+
+	return database, nil
+}
+
+// open connects to the file and starts the work that runs while the database
+// is open. It returns the lifecycle events to emit once the database is
+// unlocked.
+func (db *Database) open() ([]LifecycleEvent, error) {
+	options := &db.options
+	dbPath := db.Path
+
+	var writerLock *os.File
+	if options.singleWriter {
+		lock, err := acquireWriterLock(dbPath, options.fileMode)
+		if err != nil {
+			return nil, err
+		}
+		writerLock = lock
+	}
+	opened := false
+	defer func() {
+		if !opened {
+			releaseWriterLock(writerLock)
+		}
+	}()
+
+	if err := options.applyFileMode(dbPath); err != nil {
+		return nil, err
+	}
+
+	var params []string
+	if options.syncOnEveryWrite {
+		params = append(params, "_sync=FULL")
+	}
+	perfParams, pragmas := options.perf.params()
+	params = append(params, perfParams...)
+	pragmas = append(pragmas, options.pragmas...)
+	params = append(params, options.busyParams()...)
+	if options.autoVacuum != nil {
+		params = append(params, autoVacuumParam(*options.autoVacuum))
+	}
+
+	dsn := dbPath
+	if len(params) > 0 {
+		dsn += "?" + strings.Join(params, "&")
+	}
+
+	connection := sql.OpenDB(pragmaConnector{dsn: dsn, pragmas: pragmas, retry: options.busyRetry()})
+
+	// WAL lets readers, including exports, proceed while a write is in progress
+	if _, err := connection.Exec("PRAGMA journal_mode=WAL"); err != nil {
+		connection.Close()
+		return nil, options.reportCorruption(dbPath, err)
+	}
 
-	createTableSQL := `CREATE TABLE IF NOT EXISTS entries (
-		"key" TEXT NOT NULL,
-		"type" TEXT NOT NULL,
-    	"timestamp" INTEGER NOT NULL,
-		"grouping" TEXT,
-		"sortingIndex" INTEGER,
-		"value" BLOB,
-		PRIMARY KEY ("key", "type")
-	) WITHOUT ROWID;
+	_, err := connection.Exec(fmt.Sprintf(entriesTableSQL, "entries"))
 
-		CREATE INDEX IF NOT EXISTS idx_entries_key ON entries(type);
-		CREATE INDEX IF NOT EXISTS idx_entries_grouping ON entries(type, grouping);
-		CREATE INDEX IF NOT EXISTS idx_entries_sorting_index ON entries(type, sortingIndex);
-		CREATE INDEX IF NOT EXISTS idx_entries_timestamp ON entries(type, timestamp);
-	`
+	if err != nil {
+		connection.Close()
+		return nil, options.reportCorruption(dbPath, err)
+	}
 
-	_, err = connection.Exec(createTableSQL)
+	migrationStarted := time.Now()
+	added, err := migrate(connection)
+	if err != nil {
+		connection.Close()
+		return nil, options.reportCorruption(dbPath, err)
+	}
+	addedExtra, err := addExtraColumns(connection, options.extraColumns)
+	if err != nil {
+		connection.Close()
+		return nil, err
+	}
+	added = append(added, addedExtra...)
 
+	var events []LifecycleEvent
+	if len(added) > 0 {
+		events = append(events, LifecycleEvent{Kind: EventMigrated, Path: dbPath, Duration: time.Since(migrationStarted), Detail: "added columns " + strings.Join(added, ", ")})
+	}
+
+	if options.idleCloseAfter > 0 {
+		connection.SetConnMaxIdleTime(options.idleCloseAfter)
+	}
+
+	db.connection = connection
+	vacuumed, err := db.applyAutoVacuum()
 	if err != nil {
+		db.connection = nil
 		connection.Close()
 		return nil, err
 	}
+	events = append(events, vacuumed...)
+	db.writerLock = writerLock
 
-	database := &Database{Path: dbPath, connection: connection, mutex: sync.RWMutex{}}
+	if options.retentionInterval > 0 && len(options.retention) > 0 {
+		db.stopRetention = make(chan struct{})
+		go db.runRetention(options.retentionInterval, db.stopRetention)
+	}
 
-	return database, nil
+	opened = true
+	return append(events, LifecycleEvent{Kind: EventOpened, Path: dbPath}), nil
+}
+
+// Reopen opens the database again after Close, with the options it was
+// initialized with, so an application can let go of the file while it is idle
+// and carry on later with the same handle. It does nothing while the database
+// is open.
+func (db *Database) Reopen() error {
+	var events []LifecycleEvent
+	defer func() {
+		for _, event := range events {
+			db.options.emit(event)
+		}
+	}()
+
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if db.connection != nil {
+		return nil
+	}
+
+	var err error
+	events, err = db.open()
+	return err
 }
 
 func (db *Database) Close() error {
+	closed := false
+	defer func() {
+		if closed {
+			db.emit(EventClosed, time.Now(), "")
+		}
+	}()
+
 	db.mutex.Lock()
 	defer db.mutex.Unlock()
 
@@ -103,12 +390,22 @@ func (db *Database) Close() error {
 		return nil
 	}
 
+	db.closeStatements()
+
 	err := db.connection.Close()
 	if err != nil {
 		return err
 	}
 	db.connection = nil
-	return nil
+
+	if db.stopRetention != nil {
+		close(db.stopRetention)
+		db.stopRetention = nil
+	}
+	closed = true
+	err = releaseWriterLock(db.writerLock)
+	db.writerLock = nil
+	return err
 }
 
 func (db *Database) Get(entryType string, key string) (*DbEntry, error) {
@@ -119,10 +416,15 @@ func (db *Database) Get(entryType string, key string) (*DbEntry, error) {
 		return nil, ErrNoDbConnection
 	}
 
-	row := db.connection.QueryRow("SELECT timestamp, type, value, key, grouping, sortingIndex FROM entries WHERE type = ? AND key = ?", entryType, key)
+	ctx, done := db.readContext()
+	defer done()
 
-	var entry DbEntry
-	err := row.Scan(&entry.Timestamp, &entry.Type, &entry.Value, &entry.Key, &entry.Grouping, &entry.SortingIndex)
+	stmt, err := db.prepared(ctx, db.getSQL())
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := db.scanEntry(stmt.QueryRowContext(ctx, entryType, key))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // No entry found
@@ -134,6 +436,33 @@ func (db *Database) Get(entryType string, key string) (*DbEntry, error) {
 	return &entry, nil
 }
 
+func (db *Database) Exists(entryType string, key string) (bool, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	if db.connection == nil {
+		return false, ErrNoDbConnection
+	}
+
+	ctx, done := db.readContext()
+	defer done()
+
+	stmt, err := db.prepared(ctx, existsSQL)
+	if err != nil {
+		return false, err
+	}
+
+	var found int
+	err = stmt.QueryRowContext(ctx, entryType, key).Scan(&found)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 func (db *Database) BulkGet(entryType string, keys []string) (map[string]DbEntry, error) {
 	db.mutex.RLock()
 	defer db.mutex.RUnlock()
@@ -142,192 +471,621 @@ func (db *Database) BulkGet(entryType string, keys []string) (map[string]DbEntry
 		return nil, ErrNoDbConnection
 	}
 
-	if len(keys) == 0 {
-		return make(map[string]DbEntry), nil
+	ctx, done := db.readContext()
+	defer done()
+
+	entries := make(map[string]DbEntry)
+	if len(keys) == 0 {
+		return entries, nil
+	}
+
+	// One read transaction keeps every chunk on the same snapshot
+	tx, err := db.connection.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	for _, chunk := range chunks(keys, db.chunkSize()) {
+		query := fmt.Sprintf("SELECT %s FROM entries WHERE key IN (%s) AND type = ? AND deletedAt IS NULL", db.entryColumns(), placeholders(len(chunk)))
+
+		args := make([]interface{}, len(chunk)+1)
+		for i, key := range chunk {
+			args[i] = key
+		}
+		args[len(chunk)] = entryType
+
+		if err := db.scanEntriesInto(ctx, tx, query, args, func(entry DbEntry) { entries[entry.Key] = entry }); err != nil {
+			return nil, err
+		}
+	}
+
+	return entries, nil
+}
+
+func (db *Database) scanEntriesInto(ctx context.Context, tx *sql.Tx, query string, args []interface{}, fn func(DbEntry)) error {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		entry, err := db.scanEntry(rows)
+		if err != nil {
+			return err
+		}
+		fn(entry)
+	}
+
+	return rows.Err()
+}
+
+// ChangedSince returns the entries among the given keys whose stored timestamp
+// is newer than the timestamp the caller already has for them.
+func (db *Database) ChangedSince(entryType string, known map[string]int64) ([]DbEntry, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	if db.connection == nil {
+		return nil, ErrNoDbConnection
+	}
+
+	ctx, done := db.readContext()
+	defer done()
+
+	if len(known) == 0 {
+		return nil, nil
+	}
+
+	tx, err := db.connection.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	keys := make([]string, 0, len(known))
+	for key := range known {
+		keys = append(keys, key)
+	}
+
+	var entries []DbEntry
+	for _, chunk := range chunks(keys, max(1, db.chunkSize()/2)) {
+		values := strings.Repeat("(?, ?),", len(chunk))
+		values = values[:len(values)-1] // Remove trailing comma
+
+		// The known columns are named apart from the entry columns, which are
+		// selected unqualified
+		query := fmt.Sprintf(`WITH known(knownKey, knownTimestamp) AS (VALUES %s)
+			SELECT %s FROM entries JOIN known ON entries.key = known.knownKey
+			WHERE type = ? AND deletedAt IS NULL AND timestamp > known.knownTimestamp`, values, db.entryColumns())
+
+		args := make([]interface{}, 0, 2*len(chunk)+1)
+		for _, key := range chunk {
+			args = append(args, key, known[key])
+		}
+		args = append(args, entryType)
+
+		if err := db.scanEntriesInto(ctx, tx, query, args, func(entry DbEntry) { entries = append(entries, entry) }); err != nil {
+			return nil, err
+		}
+	}
+
+	return entries, nil
+}
+
+// Builds on SQLite versions before 3.32 reject statements with more variables.
+const maxSQLVariables = 999
+
+const upsertColumnCount = 12
+
+const upsertColumns = "type, value, timestamp, key, grouping, sortingIndex, subgrouping, signature, createdAt, updatedAt, projection, immutable"
+
+// upsertValues returns the VALUES placeholders for rows of upsertColumns.
+func upsertValues(rows int) string {
+	values := strings.Repeat("(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?),", rows)
+	return values[:len(values)-1] // Remove trailing comma
+}
+
+// upsertSQL returns an insert for the given number of rows that replaces
+// existing entries and bumps their version.
+func upsertSQL(rows int) string {
+	return upsertSQLWhere(rows, "")
+}
+
+// upsertSQLWhere is upsertSQL, but existing entries are only replaced when
+// condition holds. The condition can refer to the incoming row as excluded.
+func upsertSQLWhere(rows int, condition string) string {
+	query := "INSERT INTO entries(" + upsertColumns + ") VALUES " + upsertValues(rows) + `
+		ON CONFLICT (key, type) DO UPDATE SET
+			value = excluded.value,
+			timestamp = excluded.timestamp,
+			grouping = excluded.grouping,
+			sortingIndex = excluded.sortingIndex,
+			subgrouping = excluded.subgrouping,
+			signature = excluded.signature,
+			projection = excluded.projection,
+			immutable = excluded.immutable,
+			version = entries.version + 1,
+			updatedAt = excluded.updatedAt,
+			createdAt = CASE WHEN entries.deletedAt IS NULL THEN entries.createdAt ELSE excluded.createdAt END,
+			deletedAt = NULL`
+	if condition != "" {
+		query += " WHERE " + condition
+	}
+	return query
+}
+
+// upsertArgs returns the arguments for one row of upsertSQL.
+func (db *Database) upsertArgs(entry EntryInput, now int64) []interface{} {
+	timestamp := now
+	if entry.Timestamp != nil {
+		timestamp = *entry.Timestamp
+	}
+	var grouping interface{} = entry.Grouping
+	if entry.Grouping == "" && db.options.nullEmptyGrouping {
+		grouping = nil
+	}
+	signature := db.sign(entry.Type, entry.Key, entry.Value)
+	return []interface{}{entry.Type, entry.Value, timestamp, entry.Key, grouping, entry.SortingIndex, entry.Subgrouping, signature, now, now, entry.Projection, entry.Immutable}
+}
+
+func (db *Database) Upsert(entry EntryInput) (err error) {
+	defer translateImmutable(&err)
+
+	defer db.afterWrite(entry.Type)
+
+	defer db.lockWrite(false, entry.Type)()
+
+	if db.connection == nil {
+		return ErrNoDbConnection
+	}
+
+	ctx, done := db.writeContext()
+	defer done()
+
+	return db.execWrite(ctx, entry.Type, upsertSQL(1), db.upsertArgs(entry, db.now())...)
+}
+
+// UpsertIfUnchanged writes the entry only if the stored entry still has the
+// expected timestamp, returning ErrConflict otherwise (including when it is gone).
+func (db *Database) UpsertIfUnchanged(entry EntryInput, expectedTimestamp int64) error {
+	return db.conditionalUpsert(entry, func(found bool, timestamp int64, version int64) error {
+		if !found || timestamp != expectedTimestamp {
+			return ErrConflict
+		}
+		return nil
+	})
+}
+
+// conditionalUpsert writes the entry if check, given the stored timestamp and
+// version, returns nil. The check and the write happen in one transaction.
+func (db *Database) conditionalUpsert(entry EntryInput, check func(found bool, timestamp int64, version int64) error) (err error) {
+	defer translateImmutable(&err)
+
+	defer db.afterWrite(entry.Type)
+
+	defer db.lockWrite(false, entry.Type)()
+
+	if db.connection == nil {
+		return ErrNoDbConnection
+	}
+
+	ctx, done := db.writeContext()
+	defer done()
+
+	tx, err := db.connection.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var timestamp, version int64
+	err = tx.QueryRowContext(ctx, "SELECT timestamp, version FROM entries WHERE type = ? AND key = ? AND deletedAt IS NULL", entry.Type, entry.Key).Scan(&timestamp, &version)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if err := check(err == nil, timestamp, version); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, upsertSQL(1), db.upsertArgs(entry, db.now())...); err != nil {
+		return err
+	}
+
+	if err := db.checkQuotas(ctx, tx, entry.Type); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (db *Database) UpsertReturning(entry EntryInput) (*DbEntry, error) {
+	err := db.Upsert(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	return db.Get(entry.Type, entry.Key)
+}
+
+// GetOrSet returns the stored entry, inserting the given one first if there is
+// none. The returned bool reports whether it was inserted.
+func (db *Database) GetOrSet(entry EntryInput) (*DbEntry, bool, error) {
+	defer db.afterWrite(entry.Type)
+
+	defer db.lockWrite(false, entry.Type)()
+
+	if db.connection == nil {
+		return nil, false, ErrNoDbConnection
+	}
+
+	ctx, done := db.writeContext()
+	defer done()
+
+	tx, err := db.connection.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback()
+
+	// Only a deleted entry is replaced, so inserted is also true when it is revived
+	result, err := tx.ExecContext(ctx, upsertSQLWhere(1, "entries.deletedAt IS NOT NULL"), db.upsertArgs(entry, db.now())...)
+	if err != nil {
+		return nil, false, err
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return nil, false, err
+	}
+
+	row := tx.QueryRowContext(ctx, "SELECT "+db.entryColumns()+" FROM entries WHERE type = ? AND key = ?", entry.Type, entry.Key)
+	stored, err := db.scanEntry(row)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := db.checkQuotas(ctx, tx, entry.Type); err != nil {
+		return nil, false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, false, err
+	}
+	return &stored, inserted == 1, nil
+}
+
+func (db *Database) Update(entry EntryInput) (err error) {
+	defer translateImmutable(&err)
+
+	defer db.afterWrite(entry.Type)
+
+	defer db.lockWrite(false, entry.Type)()
+
+	if db.connection == nil {
+		return ErrNoDbConnection
+	}
+
+	ctx, done := db.writeContext()
+	defer done()
+
+	return db.execWrite(ctx, entry.Type, updateSQL,
+		entry.Value, db.sign(entry.Type, entry.Key, entry.Value), db.now(), entry.Key, entry.Type)
+}
+
+func (db *Database) Delete(entryType string, key string) (err error) {
+	defer translateImmutable(&err)
+
+	defer db.lockWrite(false, entryType)()
+
+	if db.connection == nil {
+		return ErrNoDbConnection
+	}
+
+	ctx, done := db.writeContext()
+	defer done()
+
+	query, args := db.deleteStatement("key = ? AND type = ?", []interface{}{key, entryType})
+	stmt, err := db.prepared(ctx, query)
+	if err != nil {
+		return err
+	}
+	_, err = stmt.ExecContext(ctx, args...)
+	return err
+}
+
+func (db *Database) BulkDelete(entryType string, keys []string) (err error) {
+	defer translateImmutable(&err)
+
+	defer db.lockWrite(true, entryType)()
+
+	if db.connection == nil {
+		return ErrNoDbConnection
+	}
+
+	ctx, done := db.writeContext()
+	defer done()
+
+	if len(keys) == 0 {
+		return nil
+	}
+
+	tx, err := db.connection.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, chunk := range chunks(keys, db.chunkSize()) {
+		args := make([]interface{}, len(chunk)+1)
+		for i, key := range chunk {
+			args[i] = key
+		}
+		args[len(chunk)] = entryType
+
+		query, args := db.deleteStatement(fmt.Sprintf("key IN (%s) AND type = ?", placeholders(len(chunk))), args)
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Touch sets the timestamp of the given entries to now without rewriting
+// their values, and returns how many were found.
+func (db *Database) Touch(entryType string, keys []string) (touched int64, err error) {
+	defer translateImmutable(&err)
+
+	defer db.afterWrite(entryType)
+
+	defer db.lockWrite(true, entryType)()
+
+	if db.connection == nil {
+		return 0, ErrNoDbConnection
 	}
-	placeholders := strings.Repeat("?,", len(keys))
-	placeholders = placeholders[:len(placeholders)-1] // Remove trailing comma
 
-	query := fmt.Sprintf("SELECT timestamp, type, value, key, grouping, sortingIndex FROM entries WHERE key IN (%s) AND type = ?", placeholders)
+	ctx, done := db.writeContext()
+	defer done()
 
-	args := make([]interface{}, len(keys)+1)
-	for i, key := range keys {
-		args[i] = key
+	if len(keys) == 0 {
+		return 0, nil
 	}
-	args[len(keys)] = entryType
 
-	rows, err := db.connection.Query(query, args...)
+	tx, err := db.connection.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
-	defer rows.Close()
+	defer tx.Rollback()
 
-	entries := make(map[string]DbEntry)
+	now := db.now()
+	for _, chunk := range chunks(keys, db.chunkSize()) {
+		query := fmt.Sprintf("UPDATE entries SET timestamp = ?, updatedAt = ?, version = version + 1 WHERE key IN (%s) AND type = ? AND deletedAt IS NULL", placeholders(len(chunk)))
 
-	for rows.Next() {
-		var entry DbEntry
-		if err := rows.Scan(&entry.Timestamp, &entry.Type, &entry.Value, &entry.Key, &entry.Grouping, &entry.SortingIndex); err != nil {
-			return nil, err
+		args := make([]interface{}, 0, len(chunk)+3)
+		args = append(args, now, now)
+		for _, key := range chunk {
+			args = append(args, key)
+		}
+		args = append(args, entryType)
+
+		result, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return 0, err
+		}
+		count, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
 		}
-		entries[entry.Key] = entry
+		touched += count
 	}
 
-	if err := rows.Err(); err != nil {
-		return nil, err
+	if err := tx.Commit(); err != nil {
+		return 0, err
 	}
-	return entries, nil
+	return touched, nil
 }
 
-func (db *Database) Upsert(entry EntryInput) error {
-	db.mutex.Lock()
-	defer db.mutex.Unlock()
+func (db *Database) DeleteByGrouping(entryType string, grouping string) (err error) {
+	defer translateImmutable(&err)
+
+	defer db.lockWrite(true, entryType)()
 
 	if db.connection == nil {
 		return ErrNoDbConnection
 	}
 
-	stmt, err := db.connection.Prepare("INSERT OR REPLACE INTO entries(type, value, timestamp, key, grouping, sortingIndex) VALUES(?, ?, ?, ?, ?, ?)")
-	if err != nil {
-		return err
-	}
-	defer stmt.Close()
-
-	timestamp := time.Now().UnixMilli()
-	if entry.Timestamp != nil {
-		timestamp = *entry.Timestamp
-	}
-
-	_, err = stmt.Exec(entry.Type, entry.Value, timestamp, entry.Key, entry.Grouping, entry.SortingIndex)
+	ctx, done := db.writeContext()
+	defer done()
 
+	condition, args := groupingCondition(grouping)
+	query, args := db.deleteStatement("type = ? AND "+condition, append([]interface{}{entryType}, args...))
+	_, err = db.connection.ExecContext(ctx, query, args...)
 	return err
 }
 
-func (db *Database) UpsertReturning(entry EntryInput) (*DbEntry, error) {
-	err := db.Upsert(entry)
-	if err != nil {
-		return nil, err
-	}
-
-	return db.Get(entry.Type, entry.Key)
-}
+// DeleteWhere deletes every entry Query would return for params, in a single
+// statement, and returns how many were deleted.
+func (db *Database) DeleteWhere(params QueryParams) (deleted int64, err error) {
+	defer translateImmutable(&err)
 
-func (db *Database) Update(entry EntryInput) error {
-	db.mutex.Lock()
-	defer db.mutex.Unlock()
+	defer db.lockWrite(true, params.matchedTypes()...)()
 
 	if db.connection == nil {
-		return ErrNoDbConnection
+		return 0, ErrNoDbConnection
 	}
 
-	stmt, err := db.connection.Prepare("UPDATE entries SET value = ? WHERE key = ? AND type = ?")
+	ctx, done := db.writeContext()
+	defer done()
+
+	query, args, err := db.selectQuery(params, "key, type")
 	if err != nil {
-		return err
+		return 0, err
 	}
-	defer stmt.Close()
 
-	_, err = stmt.Exec(entry.Value, entry.Key, entry.Type)
+	query, args = db.deleteStatement("(key, type) IN (SELECT key, type FROM ("+query+"))", args)
+	result, err := db.connection.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
 
-	return err
+// EntryChanges lists the metadata UpdateWhere sets. Nil fields are left as is.
+type EntryChanges struct {
+	Grouping     *string
+	Subgrouping  *string
+	SortingIndex *int64
 }
 
-func (db *Database) Delete(entryType string, key string) error {
-	db.mutex.Lock()
-	defer db.mutex.Unlock()
+// UpdateWhere applies changes to every entry Query would return for params,
+// in a single statement, and returns how many were updated.
+func (db *Database) UpdateWhere(params QueryParams, changes EntryChanges) (updated int64, err error) {
+	defer translateImmutable(&err)
+
+	defer db.afterWrite(params.matchedTypes()...)
+
+	defer db.lockWrite(true, params.matchedTypes()...)()
 
 	if db.connection == nil {
-		return ErrNoDbConnection
+		return 0, ErrNoDbConnection
+	}
+
+	ctx, done := db.writeContext()
+	defer done()
+
+	if changes.Grouping == nil && changes.Subgrouping == nil && changes.SortingIndex == nil {
+		return 0, nil
+	}
+
+	set := "version = version + 1, updatedAt = ?"
+	args := []interface{}{db.now()}
+	if changes.Grouping != nil {
+		set += ", grouping = ?"
+		if *changes.Grouping == "" && db.options.nullEmptyGrouping {
+			args = append(args, nil)
+		} else {
+			args = append(args, *changes.Grouping)
+		}
+	}
+	if changes.Subgrouping != nil {
+		set += ", subgrouping = ?"
+		args = append(args, *changes.Subgrouping)
+	}
+	if changes.SortingIndex != nil {
+		set += ", sortingIndex = ?"
+		args = append(args, *changes.SortingIndex)
 	}
 
-	stmt, err := db.connection.Prepare("DELETE FROM entries WHERE key = ? AND type = ?")
+	query, queryArgs, err := db.selectQuery(params, "key, type")
 	if err != nil {
-		return err
+		return 0, err
 	}
-	defer stmt.Close()
 
-	_, err = stmt.Exec(key, entryType)
+	result, err := db.connection.ExecContext(ctx, "UPDATE entries SET "+set+" WHERE (key, type) IN (SELECT key, type FROM ("+query+"))", append(args, queryArgs...)...)
 	if err != nil {
-		return err
+		return 0, err
 	}
-	return nil
+	return result.RowsAffected()
 }
 
-func (db *Database) BulkDelete(entryType string, keys []string) error {
-	db.mutex.Lock()
-	defer db.mutex.Unlock()
+func (db *Database) DeleteBySubgrouping(entryType string, grouping string, subgrouping string) (err error) {
+	defer translateImmutable(&err)
+
+	defer db.lockWrite(true, entryType)()
 
 	if db.connection == nil {
 		return ErrNoDbConnection
 	}
 
-	if len(keys) == 0 {
-		return nil
-	}
-
-	placeholders := strings.Repeat("?,", len(keys))
-	placeholders = placeholders[:len(placeholders)-1] // Remove trailing comma
+	ctx, done := db.writeContext()
+	defer done()
 
-	query := fmt.Sprintf("DELETE FROM entries WHERE key IN (%s) AND type = ?", placeholders)
+	condition, args := groupingCondition(grouping)
+	args = append([]interface{}{entryType}, args...)
+	query, args := db.deleteStatement("type = ? AND "+condition+" AND subgrouping = ?", append(args, subgrouping))
+	_, err = db.connection.ExecContext(ctx, query, args...)
+	return err
+}
 
-	args := make([]interface{}, len(keys)+1)
-	for i, key := range keys {
-		args[i] = key
-	}
-	args[len(keys)] = entryType
+func (db *Database) BulkUpsert(entries []EntryInput) error {
+	return db.bulkUpsert(entries, "")
+}
 
-	_, err := db.connection.Exec(query, args...)
+const newerCondition = "excluded.timestamp > entries.timestamp"
 
-	return err
-}
+// UpsertIfNewer writes the entry unless the stored one has the same or a newer
+// timestamp, so replaying or merging changes is idempotent. It reports whether
+// the entry was written.
+func (db *Database) UpsertIfNewer(entry EntryInput) (bool, error) {
+	defer db.afterWrite(entry.Type)
 
-func (db *Database) DeleteByGrouping(entryType string, grouping string) error {
-	db.mutex.Lock()
-	defer db.mutex.Unlock()
+	defer db.lockWrite(false, entry.Type)()
 
 	if db.connection == nil {
-		return ErrNoDbConnection
+		return false, ErrNoDbConnection
 	}
 
-	stmt, err := db.connection.Prepare("DELETE FROM entries WHERE type = ? AND grouping = ?")
+	ctx, done := db.writeContext()
+	defer done()
+
+	result, err := db.connection.ExecContext(ctx, upsertSQLWhere(1, newerCondition), db.upsertArgs(entry, db.now())...)
 	if err != nil {
-		return err
+		return false, err
 	}
-	defer stmt.Close()
 
-	_, err = stmt.Exec(entryType, grouping)
-	return err
+	written, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return written == 1, nil
 }
 
-func (db *Database) BulkUpsert(entries []EntryInput) error {
-	db.mutex.Lock()
-	defer db.mutex.Unlock()
+// BulkUpsertIfNewer is the BulkUpsert equivalent of UpsertIfNewer.
+func (db *Database) BulkUpsertIfNewer(entries []EntryInput) error {
+	return db.bulkUpsert(entries, newerCondition)
+}
+
+func (db *Database) bulkUpsert(entries []EntryInput, condition string) (err error) {
+	defer translateImmutable(&err)
+
+	defer db.afterWrite(entryTypes(entries)...)
+
+	defer db.lockWrite(true, entryTypes(entries)...)()
 
 	if db.connection == nil {
 		return ErrNoDbConnection
 	}
 
-	tx, err := db.connection.Begin()
-	if err != nil {
-		return err
-	}
+	ctx, done := db.writeContext()
+	defer done()
 
-	stmt, err := tx.Prepare("INSERT OR REPLACE INTO entries(type, value, timestamp, key, grouping, sortingIndex) VALUES(?, ?, ?, ?, ?, ?)")
+	tx, err := db.connection.BeginTx(ctx, nil)
 	if err != nil {
-		tx.Rollback()
 		return err
 	}
-	defer stmt.Close()
 
-	for _, e := range entries {
-		timestamp := time.Now().UnixMilli()
-		if e.Timestamp != nil {
-			timestamp = *e.Timestamp
+	now := db.now()
+	rowsPerBatch := maxSQLVariables / upsertColumnCount
+
+	for start := 0; start < len(entries); start += rowsPerBatch {
+		batch := entries[start:min(start+rowsPerBatch, len(entries))]
+
+		args := make([]interface{}, 0, len(batch)*upsertColumnCount)
+		for _, e := range batch {
+			args = append(args, db.upsertArgs(e, now)...)
 		}
-		if _, err := stmt.Exec(e.Type, e.Value, timestamp, e.Key, e.Grouping, e.SortingIndex); err != nil {
+
+		if _, err := tx.ExecContext(ctx, upsertSQLWhere(len(batch), condition), args...); err != nil {
 			tx.Rollback()
 			return err
 		}
 	}
 
+	if err := db.checkQuotas(ctx, tx, entryTypes(entries)...); err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	return tx.Commit()
 }
 
@@ -339,7 +1097,10 @@ func (db *Database) Count() (int64, error) {
 		return 0, ErrNoDbConnection
 	}
 
-	row := db.connection.QueryRow("SELECT COUNT(*) FROM entries")
+	ctx, done := db.readContext()
+	defer done()
+
+	row := db.connection.QueryRowContext(ctx, "SELECT COUNT(*) FROM entries WHERE deletedAt IS NULL")
 
 	var count int64
 	err := row.Scan(&count)
@@ -355,6 +1116,7 @@ type SortField int
 const (
 	SortByTimestamp SortField = iota
 	SortBySortingIndex
+	SortByKey
 )
 
 type SortOrder int
@@ -364,61 +1126,269 @@ const (
 	Descending
 )
 
-type QueryParams struct {
-	From      *int64
-	To        *int64
-	Type      *string
-	Limit     *int
-	Offset    *int
-	Grouping  *string
-	SortField SortField
-	SortOrder SortOrder
+// DecayRank orders entries by a recency score that halves every HalfLife,
+// optionally multiplied by the numeric JSON value at WeightPath (e.g. "$.visits").
+type DecayRank struct {
+	HalfLife   time.Duration
+	WeightPath string
 }
 
-func (db *Database) Query(
-	params QueryParams,
-) ([]DbEntry, error) {
-	db.mutex.RLock()
-	defer db.mutex.RUnlock()
+type SortSpec struct {
+	Field SortField
+	Order SortOrder
+}
 
-	if db.connection == nil {
-		return nil, ErrNoDbConnection
+func orderBy(specs []SortSpec) string {
+	columns := map[SortField]string{
+		SortByTimestamp:    "timestamp",
+		SortBySortingIndex: "sortingIndex",
+		SortByKey:          "key",
+	}
+
+	var terms []string
+	hasKey := false
+	order := "ASC"
+	for _, spec := range specs {
+		order = "ASC"
+		if spec.Order == Descending {
+			order = "DESC"
+		}
+		hasKey = hasKey || spec.Field == SortByKey
+		terms = append(terms, columns[spec.Field]+" "+order)
+	}
+
+	// Ties are broken by key and type so that results and pages are stable
+	if !hasKey {
+		terms = append(terms, "key "+order)
 	}
+	terms = append(terms, "type "+order)
 
-	query := "SELECT timestamp, type, value, key, grouping, sortingIndex FROM entries WHERE 1=1"
+	return " ORDER BY " + strings.Join(terms, ", ")
+}
+
+type QueryParams struct {
+	From              *int64
+	To                *int64
+	Type              *string
+	Types             []string // Matches any of the given types, combined with Type if both are set
+	TypeNot           *string
+	Limit             *int
+	Offset            *int
+	Grouping          *string  // An empty grouping matches ungrouped entries
+	Groupings         []string // Matches any of the given groupings
+	ExcludeGroupings  []string // An empty grouping excludes ungrouped entries
+	ExcludeKeys       []string
+	UngroupedOnly     bool // Entries with a NULL or empty grouping
+	GroupingIsNull    bool // Entries with a NULL grouping only
+	Subgrouping       *string
+	SortField         SortField
+	SortOrder         SortOrder
+	Sort              []SortSpec // Overrides SortField and SortOrder when set
+	RankBy            *DecayRank // Overrides any sorting, highest score first
+	LatestPerGrouping bool       // Only return the newest entry of each grouping
+	After             string     // Cursor from CursorFor or QueryPage, requires SortByTimestamp
+	CreatedFrom       *int64
+	CreatedTo         *int64
+	UpdatedFrom       *int64
+	UpdatedTo         *int64
+
+	// Calendar filters, resolved in Location (or the time's own location)
+	Day      *time.Time
+	Week     *time.Time
+	Month    *time.Time
+	Location *time.Location
+}
+
+func buildWhere(params QueryParams) (string, []interface{}) {
+	where := "WHERE deletedAt IS NULL"
 
 	var args []interface{}
 
 	if params.Type != nil {
-		query += " AND type = ?"
+		where += " AND type = ?"
 		args = append(args, *params.Type)
 	}
 
+	if len(params.Types) > 0 {
+		where += fmt.Sprintf(" AND type IN (%s)", placeholders(len(params.Types)))
+		for _, entryType := range params.Types {
+			args = append(args, entryType)
+		}
+	}
+
+	if params.TypeNot != nil {
+		where += " AND type != ?"
+		args = append(args, *params.TypeNot)
+	}
+
 	if params.From != nil {
-		query += " AND timestamp >= ?"
+		where += " AND timestamp >= ?"
 		args = append(args, *params.From)
 	}
 
 	if params.To != nil {
-		query += " AND timestamp <= ?"
+		where += " AND timestamp <= ?"
 		args = append(args, *params.To)
 	}
 
 	if params.Grouping != nil {
-		query += " AND grouping = ?"
-		args = append(args, *params.Grouping)
+		condition, conditionArgs := groupingCondition(*params.Grouping)
+		where += " AND " + condition
+		args = append(args, conditionArgs...)
 	}
 
-	order := "DESC"
-	if params.SortOrder == Ascending {
-		order = "ASC"
+	if params.UngroupedOnly {
+		where += " AND (grouping IS NULL OR grouping = '')"
+	}
+
+	if params.GroupingIsNull {
+		where += " AND grouping IS NULL"
+	}
+
+	if params.Subgrouping != nil {
+		where += " AND subgrouping = ?"
+		args = append(args, *params.Subgrouping)
+	}
+
+	bounds := []struct {
+		condition string
+		value     *int64
+	}{
+		{"createdAt >= ?", params.CreatedFrom},
+		{"createdAt <= ?", params.CreatedTo},
+		{"updatedAt >= ?", params.UpdatedFrom},
+		{"updatedAt <= ?", params.UpdatedTo},
+	}
+	for _, bound := range bounds {
+		if bound.value != nil {
+			where += " AND " + bound.condition
+			args = append(args, *bound.value)
+		}
+	}
+
+	if len(params.Groupings) > 0 {
+		condition := fmt.Sprintf("grouping IN (%s)", placeholders(len(params.Groupings)))
+		for _, grouping := range params.Groupings {
+			args = append(args, grouping)
+			if grouping == "" {
+				condition = "(" + condition + " OR grouping IS NULL)"
+			}
+		}
+		where += " AND " + condition
+	}
+
+	if len(params.ExcludeGroupings) > 0 {
+		where += fmt.Sprintf(" AND COALESCE(grouping, '') NOT IN (%s)", placeholders(len(params.ExcludeGroupings)))
+		for _, grouping := range params.ExcludeGroupings {
+			args = append(args, grouping)
+		}
+	}
+
+	if len(params.ExcludeKeys) > 0 {
+		where += fmt.Sprintf(" AND key NOT IN (%s)", placeholders(len(params.ExcludeKeys)))
+		for _, key := range params.ExcludeKeys {
+			args = append(args, key)
+		}
+	}
+
+	calendarRanges := []struct {
+		t         *time.Time
+		rangeFunc func(time.Time, *time.Location) (int64, int64)
+	}{
+		{params.Day, DayRange},
+		{params.Week, WeekRange},
+		{params.Month, MonthRange},
+	}
+	for _, calendarRange := range calendarRanges {
+		if calendarRange.t == nil {
+			continue
+		}
+		from, to := calendarRange.rangeFunc(*calendarRange.t, params.Location)
+		where += " AND timestamp >= ? AND timestamp <= ?"
+		args = append(args, from, to)
+	}
+
+	return where, args
+}
+
+// querySource returns the filtered rows as a FROM clause ending in a WHERE, so
+// callers can append further conditions with AND.
+func querySource(params QueryParams) (string, []interface{}) {
+	where, args := buildWhere(params)
+
+	if params.LatestPerGrouping {
+		return `(SELECT *, ROW_NUMBER() OVER (PARTITION BY type, COALESCE(grouping, '') ORDER BY timestamp DESC, key ASC) AS rowNumber
+			FROM entries ` + where + `) WHERE rowNumber = 1`, args
+	}
+
+	return "entries " + where, args
+}
+
+func (db *Database) CountWhere(params QueryParams) (int64, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	if db.connection == nil {
+		return 0, ErrNoDbConnection
+	}
+
+	ctx, done := db.readContext()
+	defer done()
+
+	return countWhere(ctx, db.connection, params)
+}
+
+func countWhere(ctx context.Context, q querier, params QueryParams) (int64, error) {
+	source, args := querySource(params)
+
+	var count int64
+	err := q.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+source, args...).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// selectQuery builds the full SELECT for params, including ordering, cursor
+// and limits.
+func (db *Database) selectQuery(params QueryParams, columns string) (string, []interface{}, error) {
+	source, args := querySource(params)
+
+	query := "SELECT " + columns + " FROM " + source
+
+	if params.After != "" {
+		if params.SortField != SortByTimestamp || len(params.Sort) > 0 || params.RankBy != nil {
+			return "", nil, ErrCursorUnsupported
+		}
+		cursor, err := decodeCursor(params.After)
+		if err != nil {
+			return "", nil, err
+		}
+		comparison := "<"
+		if params.SortOrder == Ascending {
+			comparison = ">"
+		}
+		query += " AND (timestamp, key, type) " + comparison + " (?, ?, ?)"
+		args = append(args, cursor.Timestamp, cursor.Key, cursor.Type)
 	}
 
-	switch params.SortField {
-	case SortByTimestamp:
-		query += " ORDER BY timestamp " + order
-	case SortBySortingIndex:
-		query += " ORDER BY sortingIndex " + order
+	if params.RankBy != nil {
+		weight := "1.0"
+		if params.RankBy.WeightPath != "" {
+			weight = "CAST(COALESCE(json_extract(CAST(value AS TEXT), ?), 0) AS REAL)"
+		}
+		query += " ORDER BY sidb_decay(timestamp, ?, ?, " + weight + ") DESC, key ASC, type ASC"
+		args = append(args, db.now(), params.RankBy.HalfLife.Milliseconds())
+		if params.RankBy.WeightPath != "" {
+			args = append(args, params.RankBy.WeightPath)
+		}
+	} else {
+		sort := params.Sort
+		if len(sort) == 0 {
+			sort = []SortSpec{{Field: params.SortField, Order: params.SortOrder}}
+		}
+		query += orderBy(sort)
 	}
 
 	if params.Limit != nil {
@@ -430,7 +1400,39 @@ func (db *Database) Query(
 		query += " OFFSET ?"
 		args = append(args, *params.Offset)
 	}
-	rows, err := db.connection.Query(query, args...)
+	return query, args, nil
+}
+
+func (db *Database) Query(
+	params QueryParams,
+) ([]DbEntry, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	if db.connection == nil {
+		return nil, ErrNoDbConnection
+	}
+
+	ctx, done := db.readContext()
+	defer done()
+
+	return db.queryEntries(ctx, db.connection, params)
+}
+
+// querier is what *sql.DB and *sql.Tx have in common, for reads that run
+// either on their own or inside a transaction.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func (db *Database) queryEntries(ctx context.Context, q querier, params QueryParams) ([]DbEntry, error) {
+	query, args, err := db.selectQuery(params, db.entryColumns())
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -438,8 +1440,8 @@ func (db *Database) Query(
 
 	var entries []DbEntry
 	for rows.Next() {
-		var entry DbEntry
-		if err := rows.Scan(&entry.Timestamp, &entry.Type, &entry.Value, &entry.Key, &entry.Grouping, &entry.SortingIndex); err != nil {
+		entry, err := db.scanEntry(rows)
+		if err != nil {
 			return nil, err
 		}
 		entries = append(entries, entry)
@@ -461,6 +1463,12 @@ func (db *Database) Drop() error {
 
 	db.mutex.Lock()
 	defer db.mutex.Unlock()
+
+	for _, suffix := range []string{"-wal", "-shm", ".lock"} {
+		if err := os.Remove(db.Path + suffix); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
 	return os.Remove(db.Path)
 }
 
@@ -472,25 +1480,37 @@ type Store[T any] struct {
 	serialize          func(T) ([]byte, error)
 	deserialize        func([]byte) (T, error)
 	deriveSortingIndex func(T) *int64
+	project            func(T) ([]byte, error)
+	normalizers        []func(string) string
 }
 
 func (store *Store[T]) Get(key string) (T, error) {
-	entry, err := store.db.Get(store.entryType, key)
+	entry, err := store.db.Get(store.entryType, store.key(key))
 	if err != nil || entry == nil {
 		var zero T
 		return zero, err
 	}
-	return store.deserialize(entry.Value)
+	return store.decode(key, entry.Value)
+}
+
+func (store *Store[T]) Exists(key string) (bool, error) {
+	return store.db.Exists(store.entryType, store.key(key))
 }
 
 func (store *Store[T]) BulkGet(keys []string) (map[string]T, error) {
-	entries, err := store.db.BulkGet(store.entryType, keys)
+	normalized := store.keys(keys)
+	entries, err := store.db.BulkGet(store.entryType, normalized)
 	if err != nil {
 		return nil, err
 	}
+	// Results are keyed by the keys as given
 	result := make(map[string]T)
-	for key, entry := range entries {
-		value, err := store.deserialize(entry.Value)
+	for i, key := range keys {
+		entry, ok := entries[normalized[i]]
+		if !ok {
+			continue
+		}
+		value, err := store.decode(key, entry.Value)
 		if err != nil {
 			return nil, err
 		}
@@ -500,16 +1520,18 @@ func (store *Store[T]) BulkGet(keys []string) (map[string]T, error) {
 }
 
 type StoreEntryInput[T any] struct {
-	Key       string
-	Value     T
-	Grouping  string
-	Timestamp *int64 // Optional: if provided, will be used instead of current time
+	Key         string
+	Value       T
+	Grouping    string
+	Subgrouping string
+	Timestamp   *int64 // Optional: if provided, will be used instead of current time
+	Immutable   bool
 }
 
-func (store *Store[T]) Upsert(entry StoreEntryInput[T]) error {
+func (store *Store[T]) entryInput(entry StoreEntryInput[T]) (EntryInput, error) {
 	serialized, err := store.serialize(entry.Value)
 	if err != nil {
-		return err
+		return EntryInput{}, &SerializationError{Type: store.entryType, Key: entry.Key, Op: "serialize", Cause: err}
 	}
 
 	var sortingIndex *int64
@@ -517,47 +1539,70 @@ func (store *Store[T]) Upsert(entry StoreEntryInput[T]) error {
 		sortingIndex = store.deriveSortingIndex(entry.Value)
 	}
 
-	return store.db.Upsert(EntryInput{
+	var projection []byte
+	if store.project != nil {
+		if projection, err = store.project(entry.Value); err != nil {
+			return EntryInput{}, &SerializationError{Type: store.entryType, Key: entry.Key, Op: "project", Cause: err}
+		}
+	}
+
+	return EntryInput{
 		Type:         store.entryType,
-		Key:          entry.Key,
+		Key:          store.key(entry.Key),
 		Value:        serialized,
 		Grouping:     entry.Grouping,
+		Subgrouping:  entry.Subgrouping,
 		SortingIndex: sortingIndex,
 		Timestamp:    entry.Timestamp,
-	})
+		Projection:   projection,
+		Immutable:    entry.Immutable,
+	}, nil
+}
+
+func (store *Store[T]) Upsert(entry StoreEntryInput[T]) error {
+	input, err := store.entryInput(entry)
+	if err != nil {
+		return err
+	}
+	return store.db.Upsert(input)
 }
 
 func (store *Store[T]) Delete(key string) error {
-	return store.db.Delete(store.entryType, key)
+	return store.db.Delete(store.entryType, store.key(key))
 }
 
 func (store *Store[T]) BulkDelete(keys []string) error {
-	return store.db.BulkDelete(store.entryType, keys)
+	return store.db.BulkDelete(store.entryType, store.keys(keys))
+}
+
+func (store *Store[T]) Touch(keys []string) (int64, error) {
+	return store.db.Touch(store.entryType, store.keys(keys))
 }
 
 func (store *Store[T]) DeleteByGrouping(grouping string) error {
 	return store.db.DeleteByGrouping(store.entryType, grouping)
 }
 
+func (store *Store[T]) DeleteWhere(params StoreQueryParams) (int64, error) {
+	return store.db.DeleteWhere(store.queryParams(params))
+}
+
+func (store *Store[T]) UpdateWhere(params StoreQueryParams, changes EntryChanges) (int64, error) {
+	return store.db.UpdateWhere(store.queryParams(params), changes)
+}
+
+func (store *Store[T]) DeleteBySubgrouping(grouping string, subgrouping string) error {
+	return store.db.DeleteBySubgrouping(store.entryType, grouping, subgrouping)
+}
+
 func (store *Store[T]) BulkUpsert(entries []StoreEntryInput[T]) error {
 	var dbEntries []EntryInput
 	for _, entry := range entries {
-		serialized, err := store.serialize(entry.Value)
+		input, err := store.entryInput(entry)
 		if err != nil {
 			return err
 		}
-		var sortingIndex *int64
-		if store.deriveSortingIndex != nil {
-			sortingIndex = store.deriveSortingIndex(entry.Value)
-		}
-		dbEntries = append(dbEntries, EntryInput{
-			Type:         store.entryType,
-			Key:          entry.Key,
-			Value:        serialized,
-			Grouping:     entry.Grouping,
-			SortingIndex: sortingIndex,
-			Timestamp:    entry.Timestamp,
-		})
+		dbEntries = append(dbEntries, input)
 	}
 	return store.db.BulkUpsert(dbEntries)
 }
@@ -570,7 +1615,10 @@ func (store *Store[T]) Count() (int64, error) {
 		return 0, ErrNoDbConnection
 	}
 
-	row := store.db.connection.QueryRow("SELECT COUNT(*) FROM entries WHERE type = ?", store.entryType)
+	ctx, done := store.db.readContext()
+	defer done()
+
+	row := store.db.connection.QueryRowContext(ctx, "SELECT COUNT(*) FROM entries WHERE type = ? AND deletedAt IS NULL", store.entryType)
 
 	var count int64
 	err := row.Scan(&count)
@@ -582,32 +1630,72 @@ func (store *Store[T]) Count() (int64, error) {
 }
 
 type StoreQueryParams struct {
-	From      *int64
-	To        *int64
-	Limit     *int
-	Offset    *int
-	Grouping  *string
-	SortField SortField
-	SortOrder SortOrder
+	From              *int64
+	To                *int64
+	Limit             *int
+	Offset            *int
+	Grouping          *string
+	Groupings         []string
+	ExcludeGroupings  []string
+	ExcludeKeys       []string
+	UngroupedOnly     bool
+	GroupingIsNull    bool
+	Subgrouping       *string
+	SortField         SortField
+	SortOrder         SortOrder
+	Sort              []SortSpec
+	RankBy            *DecayRank
+	LatestPerGrouping bool
+	After             string
+	CreatedFrom       *int64
+	CreatedTo         *int64
+	UpdatedFrom       *int64
+	UpdatedTo         *int64
+	Day               *time.Time
+	Week              *time.Time
+	Month             *time.Time
+	Location          *time.Location
+}
+
+func (store *Store[T]) queryParams(params StoreQueryParams) QueryParams {
+	return QueryParams{
+		From:              params.From,
+		To:                params.To,
+		Type:              &store.entryType,
+		Limit:             params.Limit,
+		Offset:            params.Offset,
+		Grouping:          params.Grouping,
+		Groupings:         params.Groupings,
+		ExcludeGroupings:  params.ExcludeGroupings,
+		ExcludeKeys:       store.keys(params.ExcludeKeys),
+		UngroupedOnly:     params.UngroupedOnly,
+		GroupingIsNull:    params.GroupingIsNull,
+		Subgrouping:       params.Subgrouping,
+		SortField:         params.SortField,
+		SortOrder:         params.SortOrder,
+		Sort:              params.Sort,
+		RankBy:            params.RankBy,
+		LatestPerGrouping: params.LatestPerGrouping,
+		After:             params.After,
+		CreatedFrom:       params.CreatedFrom,
+		CreatedTo:         params.CreatedTo,
+		UpdatedFrom:       params.UpdatedFrom,
+		UpdatedTo:         params.UpdatedTo,
+		Day:               params.Day,
+		Week:              params.Week,
+		Month:             params.Month,
+		Location:          params.Location,
+	}
 }
 
 func (store *Store[T]) Query(params StoreQueryParams) ([]T, error) {
-	entries, err := store.db.Query(QueryParams{
-		From:      params.From,
-		To:        params.To,
-		Type:      &store.entryType,
-		Limit:     params.Limit,
-		Offset:    params.Offset,
-		Grouping:  params.Grouping,
-		SortField: params.SortField,
-		SortOrder: params.SortOrder,
-	})
+	entries, err := store.db.Query(store.queryParams(params))
 	if err != nil {
 		return nil, err
 	}
 	var results []T
 	for _, entry := range entries {
-		value, err := store.deserialize(entry.Value)
+		value, err := store.decode(entry.Key, entry.Value)
 		if err != nil {
 			return nil, err
 		}
@@ -617,16 +1705,11 @@ func (store *Store[T]) Query(params StoreQueryParams) ([]T, error) {
 }
 
 func (store *Store[T]) QueryEntries(params StoreQueryParams) ([]DbEntry, error) {
-	return store.db.Query(QueryParams{
-		From:      params.From,
-		To:        params.To,
-		Type:      &store.entryType,
-		Limit:     params.Limit,
-		Offset:    params.Offset,
-		Grouping:  params.Grouping,
-		SortField: params.SortField,
-		SortOrder: params.SortOrder,
-	})
+	return store.db.Query(store.queryParams(params))
+}
+
+func (store *Store[T]) CountWhere(params StoreQueryParams) (int64, error) {
+	return store.db.CountWhere(store.queryParams(params))
 }
 
 func (store *Store[T]) DropParentDb() error {
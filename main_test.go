@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"path"
 	"testing"
+	"time"
 )
 
 func TestInit(t *testing.T) {
@@ -15,7 +16,11 @@ func TestInit(t *testing.T) {
 		t.Fatalf("Failed to initialize database: %v", err)
 	}
 	defer db.Drop()
-	expectedDir := path.Join(append([]string{RootPath()}, namespace...)...)
+	root, err := RootPath()
+	if err != nil {
+		t.Fatalf("Failed to resolve root path: %v", err)
+	}
+	expectedDir := path.Join(append([]string{root}, namespace...)...)
 	expectedPath := path.Join(expectedDir, name+".db")
 	if db.Path != expectedPath {
 		t.Errorf("Expected database path %s, got %s", expectedPath, db.Path)
@@ -702,3 +707,899 @@ func TestStoreWithSortingIndex(t *testing.T) {
 		}
 	}
 }
+
+func TestQueryLatestPerGrouping(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_db"
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	entryType := "test_type"
+	err = db.BulkUpsert([]EntryInput{
+		{Type: entryType, Value: []byte("data_1"), Key: "key_1", Grouping: "group_a", Timestamp: ptr(int64(1))},
+		{Type: entryType, Value: []byte("data_2"), Key: "key_2", Grouping: "group_a", Timestamp: ptr(int64(3))},
+		{Type: entryType, Value: []byte("data_3"), Key: "key_3", Grouping: "group_b", Timestamp: ptr(int64(2))},
+		{Type: entryType, Value: []byte("data_4"), Key: "key_4", Grouping: "group_b", Timestamp: ptr(int64(1))},
+	})
+	if err != nil {
+		t.Fatalf("Failed to put entries: %v", err)
+	}
+
+	entries, err := db.Query(QueryParams{
+		Type:              &entryType,
+		LatestPerGrouping: true,
+		SortOrder:         Descending,
+	})
+	if err != nil {
+		t.Fatalf("Failed to query entries: %v", err)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+
+	if entries[0].Key != "key_2" || entries[0].GetGrouping() != "group_a" {
+		t.Errorf("Unexpected first entry: %+v", entries[0])
+	}
+	if entries[1].Key != "key_3" || entries[1].GetGrouping() != "group_b" {
+		t.Errorf("Unexpected second entry: %+v", entries[1])
+	}
+}
+
+func TestQueryMultipleTypes(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_db"
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	err = db.BulkUpsert([]EntryInput{
+		{Type: "note", Value: []byte("data_1"), Key: "key_1", Timestamp: ptr(int64(1))},
+		{Type: "event", Value: []byte("data_2"), Key: "key_2", Timestamp: ptr(int64(2))},
+		{Type: "task", Value: []byte("data_3"), Key: "key_3", Timestamp: ptr(int64(3))},
+		{Type: "note", Value: []byte("data_4"), Key: "key_4", Timestamp: ptr(int64(4))},
+	})
+	if err != nil {
+		t.Fatalf("Failed to put entries: %v", err)
+	}
+
+	entries, err := db.Query(QueryParams{
+		Types:     []string{"note", "event"},
+		SortField: SortByTimestamp,
+		SortOrder: Ascending,
+	})
+	if err != nil {
+		t.Fatalf("Failed to query entries: %v", err)
+	}
+
+	expectedKeys := []string{"key_1", "key_2", "key_4"}
+	if len(entries) != len(expectedKeys) {
+		t.Fatalf("Expected %d entries, got %d", len(expectedKeys), len(entries))
+	}
+	for i, entry := range entries {
+		if entry.Key != expectedKeys[i] {
+			t.Errorf("At index %d, expected %s, got %s", i, expectedKeys[i], entry.Key)
+		}
+	}
+}
+
+func TestStoreQueryMultipleGroupings(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_store_groupings"
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to init db: %v", err)
+	}
+	defer db.Drop()
+
+	store := MakeStore(db, "test_type", serializeTestItem, deserializeTestItem, nil)
+
+	err = store.BulkUpsert([]StoreEntryInput[testItem]{
+		{Key: "key_a", Value: testItem{Name: "A", Value: 1}, Grouping: "g1", Timestamp: ptr(int64(1))},
+		{Key: "key_b", Value: testItem{Name: "B", Value: 2}, Grouping: "g2", Timestamp: ptr(int64(2))},
+		{Key: "key_c", Value: testItem{Name: "C", Value: 3}, Grouping: "g3", Timestamp: ptr(int64(3))},
+	})
+	if err != nil {
+		t.Fatalf("Failed BulkUpsert: %v", err)
+	}
+
+	results, err := store.Query(StoreQueryParams{Groupings: []string{"g1", "g3"}, SortOrder: Ascending})
+	if err != nil {
+		t.Fatalf("Failed Query(): %v", err)
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 items, got %d", len(results))
+	}
+	if results[0].Name != "A" || results[1].Name != "C" {
+		t.Errorf("Unexpected results: %+v", results)
+	}
+}
+
+func TestQueryWithMultipleSortFields(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_db"
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	entryType := "test_type"
+	err = db.BulkUpsert([]EntryInput{
+		{Type: entryType, Value: []byte("data_1"), Key: "key_1", SortingIndex: ptr(int64(1)), Timestamp: ptr(int64(10))},
+		{Type: entryType, Value: []byte("data_2"), Key: "key_2", SortingIndex: ptr(int64(1)), Timestamp: ptr(int64(20))},
+		{Type: entryType, Value: []byte("data_3"), Key: "key_3", SortingIndex: ptr(int64(0)), Timestamp: ptr(int64(5))},
+		{Type: entryType, Value: []byte("data_4"), Key: "key_4", SortingIndex: ptr(int64(1)), Timestamp: ptr(int64(20))},
+	})
+	if err != nil {
+		t.Fatalf("Failed to put entries: %v", err)
+	}
+
+	entries, err := db.Query(QueryParams{
+		Type: &entryType,
+		Sort: []SortSpec{
+			{Field: SortBySortingIndex, Order: Ascending},
+			{Field: SortByTimestamp, Order: Descending},
+			{Field: SortByKey, Order: Ascending},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to query entries: %v", err)
+	}
+
+	expectedKeys := []string{"key_3", "key_2", "key_4", "key_1"}
+	if len(entries) != len(expectedKeys) {
+		t.Fatalf("Expected %d entries, got %d", len(expectedKeys), len(entries))
+	}
+	for i, entry := range entries {
+		if entry.Key != expectedKeys[i] {
+			t.Errorf("At index %d, expected %s, got %s", i, expectedKeys[i], entry.Key)
+		}
+	}
+}
+
+func TestCountWhere(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_db"
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	entryType := "test_type"
+	err = db.BulkUpsert([]EntryInput{
+		{Type: entryType, Value: []byte("data_1"), Key: "key_1", Grouping: "g1", Timestamp: ptr(int64(10))},
+		{Type: entryType, Value: []byte("data_2"), Key: "key_2", Grouping: "g1", Timestamp: ptr(int64(20))},
+		{Type: entryType, Value: []byte("data_3"), Key: "key_3", Grouping: "g1", Timestamp: ptr(int64(30))},
+		{Type: entryType, Value: []byte("data_4"), Key: "key_4", Grouping: "g2", Timestamp: ptr(int64(30))},
+		{Type: "other_type", Value: []byte("data_5"), Key: "key_5", Grouping: "g1", Timestamp: ptr(int64(30))},
+	})
+	if err != nil {
+		t.Fatalf("Failed to put entries: %v", err)
+	}
+
+	count, err := db.CountWhere(QueryParams{Type: &entryType, Grouping: ptr("g1"), From: ptr(int64(20))})
+	if err != nil {
+		t.Fatalf("Failed to count entries: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected count 2, got %d", count)
+	}
+
+	store := MakeStore(db, entryType, serializeTestItem, deserializeTestItem, nil)
+	count, err = store.CountWhere(StoreQueryParams{LatestPerGrouping: true})
+	if err != nil {
+		t.Fatalf("Failed to count store entries: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected store count 2, got %d", count)
+	}
+}
+
+func TestQueryRankByDecay(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_db"
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	hour := time.Hour.Milliseconds()
+	now := time.Now().UnixMilli()
+	entryType := "launcher_item"
+	err = db.BulkUpsert([]EntryInput{
+		// Used a lot, but two half-lives ago: 40 * 0.25 = 10
+		{Type: entryType, Value: []byte(`{"visits": 40}`), Key: "old_favourite", Timestamp: ptr(now - 2*hour)},
+		// Used a few times just now: 5 * 1 = 5
+		{Type: entryType, Value: []byte(`{"visits": 5}`), Key: "recent", Timestamp: ptr(now)},
+		// Used a lot, one half-life ago: 30 * 0.5 = 15
+		{Type: entryType, Value: []byte(`{"visits": 30}`), Key: "regular", Timestamp: ptr(now - hour)},
+	})
+	if err != nil {
+		t.Fatalf("Failed to put entries: %v", err)
+	}
+
+	entries, err := db.Query(QueryParams{
+		Type:   &entryType,
+		RankBy: &DecayRank{HalfLife: time.Hour, WeightPath: "$.visits"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to query entries: %v", err)
+	}
+
+	expectedKeys := []string{"regular", "old_favourite", "recent"}
+	if len(entries) != len(expectedKeys) {
+		t.Fatalf("Expected %d entries, got %d", len(expectedKeys), len(entries))
+	}
+	for i, entry := range entries {
+		if entry.Key != expectedKeys[i] {
+			t.Errorf("At index %d, expected %s, got %s", i, expectedKeys[i], entry.Key)
+		}
+	}
+}
+
+func TestChangedSince(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_db"
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	entryType := "test_type"
+	err = db.BulkUpsert([]EntryInput{
+		{Type: entryType, Value: []byte("data_1"), Key: "key_1", Timestamp: ptr(int64(10))},
+		{Type: entryType, Value: []byte("data_2"), Key: "key_2", Timestamp: ptr(int64(20))},
+		{Type: entryType, Value: []byte("data_3"), Key: "key_3", Timestamp: ptr(int64(30))},
+	})
+	if err != nil {
+		t.Fatalf("Failed to put entries: %v", err)
+	}
+
+	entries, err := db.ChangedSince(entryType, map[string]int64{
+		"key_1":   10, // Up to date
+		"key_2":   15, // Stale
+		"key_4":   0,  // Unknown key
+		"key_3":   30, // Up to date
+		"missing": 5,
+	})
+	if err != nil {
+		t.Fatalf("Failed to get changed entries: %v", err)
+	}
+
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(entries))
+	}
+	if entries[0].Key != "key_2" || string(entries[0].Value) != "data_2" {
+		t.Errorf("Unexpected entry: %+v", entries[0])
+	}
+}
+
+func TestChangedSinceSmallChunkSize(t *testing.T) {
+	db, err := Init([]string{"test_namespace"}, "test_db", WithChunkSize(1))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	entryType := "test_type"
+	err = db.BulkUpsert([]EntryInput{
+		{Type: entryType, Value: []byte("data_1"), Key: "key_1", Timestamp: ptr(int64(10))},
+		{Type: entryType, Value: []byte("data_2"), Key: "key_2", Timestamp: ptr(int64(20))},
+		{Type: entryType, Value: []byte("data_3"), Key: "key_3", Timestamp: ptr(int64(30))},
+	})
+	if err != nil {
+		t.Fatalf("Failed to put entries: %v", err)
+	}
+
+	entries, err := db.ChangedSince(entryType, map[string]int64{"key_1": 5, "key_2": 20, "key_3": 25})
+	if err != nil {
+		t.Fatalf("Failed to get changed entries: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("Expected 2 entries, got %d", len(entries))
+	}
+
+	found, err := db.BulkGet(entryType, []string{"key_1", "key_2", "key_3"})
+	if err != nil || len(found) != 3 {
+		t.Errorf("Expected 3 entries, got %d, %v", len(found), err)
+	}
+}
+
+func TestChunks(t *testing.T) {
+	if got := chunks([]string{"a", "b", "c"}, 0); len(got) != 3 {
+		t.Errorf("Expected a size of 0 to chunk one key at a time, got %v", got)
+	}
+}
+
+func TestSubgrouping(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_db"
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	entryType := "test_type"
+	err = db.BulkUpsert([]EntryInput{
+		{Type: entryType, Value: []byte("data_1"), Key: "key_1", Grouping: "project", Subgrouping: "todo"},
+		{Type: entryType, Value: []byte("data_2"), Key: "key_2", Grouping: "project", Subgrouping: "done"},
+		{Type: entryType, Value: []byte("data_3"), Key: "key_3", Grouping: "project", Subgrouping: "todo"},
+		{Type: entryType, Value: []byte("data_4"), Key: "key_4", Grouping: "other", Subgrouping: "todo"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to put entries: %v", err)
+	}
+
+	entries, err := db.Query(QueryParams{Type: &entryType, Grouping: ptr("project"), Subgrouping: ptr("todo")})
+	if err != nil {
+		t.Fatalf("Failed to query entries: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].Subgrouping != "todo" {
+		t.Errorf("Expected subgrouping todo, got %s", entries[0].Subgrouping)
+	}
+
+	err = db.DeleteBySubgrouping(entryType, "project", "todo")
+	if err != nil {
+		t.Fatalf("Failed to delete entries by subgrouping: %v", err)
+	}
+
+	entries, err = db.Query(QueryParams{Type: &entryType, SortField: SortByKey})
+	if err != nil {
+		t.Fatalf("Failed to query entries: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Key != "key_2" || entries[1].Key != "key_4" {
+		t.Errorf("Unexpected entries after delete: %+v", entries)
+	}
+}
+
+func TestBulkUpsertAcrossBatches(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_db"
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	entryType := "test_type"
+	var entries []EntryInput
+	for i := 0; i < 1000; i++ {
+		entries = append(entries, EntryInput{Type: entryType, Value: []byte(fmt.Sprintf("data_%d", i)), Key: fmt.Sprintf("key_%d", i%600)})
+	}
+
+	if err := db.BulkUpsert(entries); err != nil {
+		t.Fatalf("Failed to put entries: %v", err)
+	}
+
+	count, err := db.Count()
+	if err != nil {
+		t.Fatalf("Failed to count entries: %v", err)
+	}
+	if count != 600 {
+		t.Errorf("Expected count 600, got %d", count)
+	}
+
+	// Later entries for the same key replace earlier ones, even across batches
+	entry, err := db.Get(entryType, "key_10")
+	if err != nil {
+		t.Fatalf("Failed to get entry: %v", err)
+	}
+	if string(entry.Value) != "data_610" {
+		t.Errorf("Expected data_610, got %s", string(entry.Value))
+	}
+}
+
+func TestExists(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_db"
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	store := MakeStore(db, "test_type", serializeTestItem, deserializeTestItem, nil)
+	if err := store.Upsert(StoreEntryInput[testItem]{Key: "key_1", Value: testItem{Name: "one"}}); err != nil {
+		t.Fatalf("Failed to upsert: %v", err)
+	}
+
+	exists, err := store.Exists("key_1")
+	if err != nil || !exists {
+		t.Errorf("Expected key_1 to exist, got %v, %v", exists, err)
+	}
+
+	exists, err = store.Exists("key_2")
+	if err != nil || exists {
+		t.Errorf("Expected key_2 to not exist, got %v, %v", exists, err)
+	}
+
+	exists, err = db.Exists("other_type", "key_1")
+	if err != nil || exists {
+		t.Errorf("Expected key_1 to not exist for other_type, got %v, %v", exists, err)
+	}
+}
+
+func TestGetOrSet(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_db"
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	entry, created, err := db.GetOrSet(EntryInput{Type: "config", Key: "settings", Value: []byte("default")})
+	if err != nil {
+		t.Fatalf("Failed to get or set entry: %v", err)
+	}
+	if !created || string(entry.Value) != "default" {
+		t.Errorf("Expected created default entry, got %+v, created=%v", entry, created)
+	}
+
+	entry, created, err = db.GetOrSet(EntryInput{Type: "config", Key: "settings", Value: []byte("other")})
+	if err != nil {
+		t.Fatalf("Failed to get or set entry: %v", err)
+	}
+	if created || string(entry.Value) != "default" {
+		t.Errorf("Expected existing default entry, got %+v, created=%v", entry, created)
+	}
+}
+
+func TestUpsertIfUnchanged(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_db"
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	entryType := "test_type"
+	err = db.Upsert(EntryInput{Type: entryType, Value: []byte("v1"), Key: "doc", Timestamp: ptr(int64(100))})
+	if err != nil {
+		t.Fatalf("Failed to put entry: %v", err)
+	}
+
+	err = db.UpsertIfUnchanged(EntryInput{Type: entryType, Value: []byte("v2"), Key: "doc", Timestamp: ptr(int64(200))}, 100)
+	if err != nil {
+		t.Fatalf("Expected write with matching timestamp to succeed, got %v", err)
+	}
+
+	// A second writer that read v1 must not clobber v2
+	err = db.UpsertIfUnchanged(EntryInput{Type: entryType, Value: []byte("v2_other"), Key: "doc"}, 100)
+	if err != ErrConflict {
+		t.Errorf("Expected ErrConflict, got %v", err)
+	}
+
+	entry, err := db.Get(entryType, "doc")
+	if err != nil {
+		t.Fatalf("Failed to get entry: %v", err)
+	}
+	if string(entry.Value) != "v2" || entry.Timestamp != 200 {
+		t.Errorf("Unexpected entry: %+v", entry)
+	}
+
+	err = db.UpsertIfUnchanged(EntryInput{Type: entryType, Value: []byte("v1"), Key: "missing"}, 100)
+	if err != ErrConflict {
+		t.Errorf("Expected ErrConflict for a missing entry, got %v", err)
+	}
+}
+
+func TestUngroupedEntries(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_db"
+	db, err := Init(namespace, name, WithNullEmptyGrouping())
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	entryType := "test_type"
+	err = db.BulkUpsert([]EntryInput{
+		{Type: entryType, Value: []byte("data_1"), Key: "key_1"},
+		{Type: entryType, Value: []byte("data_2"), Key: "key_2", Grouping: "g1"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to put entries: %v", err)
+	}
+	// An entry written by an older version, with an empty string grouping
+	if _, err := db.RawExec("INSERT INTO entries (key, type, timestamp, grouping, value) VALUES ('key_3', ?, 1, '', 'data_3')", entryType); err != nil {
+		t.Fatalf("Failed to insert legacy entry: %v", err)
+	}
+
+	entry, err := db.Get(entryType, "key_1")
+	if err != nil {
+		t.Fatalf("Failed to get entry with NULL grouping: %v", err)
+	}
+	if entry.Grouping != nil {
+		t.Errorf("Expected NULL grouping, got %q", *entry.Grouping)
+	}
+
+	legacy, err := db.Get(entryType, "key_3")
+	if err != nil {
+		t.Fatalf("Failed to get entry with empty grouping: %v", err)
+	}
+	if legacy.Grouping == nil || *legacy.Grouping != "" {
+		t.Errorf("Expected empty string grouping, got %v", legacy.Grouping)
+	}
+
+	ungrouped, err := db.Query(QueryParams{Type: &entryType, UngroupedOnly: true, SortField: SortByKey})
+	if err != nil {
+		t.Fatalf("Failed to query ungrouped entries: %v", err)
+	}
+	if len(ungrouped) != 2 || ungrouped[0].Key != "key_1" || ungrouped[1].Key != "key_3" {
+		t.Errorf("Unexpected ungrouped entries: %+v", ungrouped)
+	}
+
+	null, err := db.Query(QueryParams{Type: &entryType, GroupingIsNull: true})
+	if err != nil {
+		t.Fatalf("Failed to query NULL grouping entries: %v", err)
+	}
+	if len(null) != 1 || null[0].Key != "key_1" {
+		t.Errorf("Unexpected NULL grouping entries: %+v", null)
+	}
+
+	if err := db.DeleteByGrouping(entryType, ""); err != nil {
+		t.Fatalf("Failed to delete ungrouped entries: %v", err)
+	}
+	count, err := db.CountWhere(QueryParams{Type: &entryType})
+	if err != nil {
+		t.Fatalf("Failed to count entries: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected only the grouped entry to remain, got %d entries", count)
+	}
+}
+
+func TestUpsertIfNewer(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_db"
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	entryType := "test_type"
+	written, err := db.UpsertIfNewer(EntryInput{Type: entryType, Value: []byte("v2"), Key: "doc", Timestamp: ptr(int64(200))})
+	if err != nil || !written {
+		t.Fatalf("Expected new entry to be written, got %v, %v", written, err)
+	}
+
+	written, err = db.UpsertIfNewer(EntryInput{Type: entryType, Value: []byte("v1"), Key: "doc", Timestamp: ptr(int64(100))})
+	if err != nil || written {
+		t.Errorf("Expected older entry to be ignored, got %v, %v", written, err)
+	}
+
+	// Replaying a batch containing older, equal and newer changes
+	err = db.BulkUpsertIfNewer([]EntryInput{
+		{Type: entryType, Value: []byte("v1"), Key: "doc", Timestamp: ptr(int64(100))},
+		{Type: entryType, Value: []byte("v2_replayed"), Key: "doc", Timestamp: ptr(int64(200))},
+		{Type: entryType, Value: []byte("other"), Key: "other", Timestamp: ptr(int64(50))},
+	})
+	if err != nil {
+		t.Fatalf("Failed to bulk upsert: %v", err)
+	}
+
+	entry, err := db.Get(entryType, "doc")
+	if err != nil {
+		t.Fatalf("Failed to get entry: %v", err)
+	}
+	if string(entry.Value) != "v2" {
+		t.Errorf("Expected v2 to survive replays, got %s", string(entry.Value))
+	}
+
+	written, err = db.UpsertIfNewer(EntryInput{Type: entryType, Value: []byte("v3"), Key: "doc", Timestamp: ptr(int64(300))})
+	if err != nil || !written {
+		t.Errorf("Expected newer entry to be written, got %v, %v", written, err)
+	}
+
+	exists, err := db.Exists(entryType, "other")
+	if err != nil || !exists {
+		t.Errorf("Expected new entries in a bulk replay to be inserted, got %v, %v", exists, err)
+	}
+}
+
+func TestBulkGetAndDeleteManyKeys(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_db"
+	for _, opts := range [][]Option{nil, {WithChunkSize(7)}} {
+		db, err := Init(namespace, name, opts...)
+		if err != nil {
+			t.Fatalf("Failed to initialize database: %v", err)
+		}
+
+		entryType := "test_type"
+		var entries []EntryInput
+		var keys []string
+		for i := 0; i < 2500; i++ {
+			key := fmt.Sprintf("key_%d", i)
+			keys = append(keys, key)
+			entries = append(entries, EntryInput{Type: entryType, Value: []byte("data"), Key: key})
+		}
+		if err := db.BulkUpsert(entries); err != nil {
+			t.Fatalf("Failed to put entries: %v", err)
+		}
+
+		found, err := db.BulkGet(entryType, append(keys, "missing"))
+		if err != nil {
+			t.Fatalf("Failed to bulk get: %v", err)
+		}
+		if len(found) != 2500 {
+			t.Errorf("Expected 2500 entries, got %d", len(found))
+		}
+
+		if err := db.BulkDelete(entryType, keys[:2400]); err != nil {
+			t.Fatalf("Failed to bulk delete: %v", err)
+		}
+		count, err := db.Count()
+		if err != nil {
+			t.Fatalf("Failed to count entries: %v", err)
+		}
+		if count != 100 {
+			t.Errorf("Expected 100 entries after bulk delete, got %d", count)
+		}
+
+		db.Drop()
+	}
+}
+
+func TestDeleteWhere(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_db"
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	entryType := "test_type"
+	err = db.BulkUpsert([]EntryInput{
+		{Type: entryType, Value: []byte("data_1"), Key: "key_1", Grouping: "g1", Timestamp: ptr(int64(10))},
+		{Type: entryType, Value: []byte("data_2"), Key: "key_2", Grouping: "g1", Timestamp: ptr(int64(20))},
+		{Type: entryType, Value: []byte("data_3"), Key: "key_3", Grouping: "g1", Timestamp: ptr(int64(30))},
+		{Type: entryType, Value: []byte("data_4"), Key: "key_4", Grouping: "g2", Timestamp: ptr(int64(30))},
+		{Type: "other_type", Value: []byte("data_5"), Key: "key_5", Grouping: "g1", Timestamp: ptr(int64(30))},
+	})
+	if err != nil {
+		t.Fatalf("Failed to put entries: %v", err)
+	}
+
+	deleted, err := db.DeleteWhere(QueryParams{Type: &entryType, Grouping: ptr("g1"), From: ptr(int64(20))})
+	if err != nil {
+		t.Fatalf("Failed to delete entries: %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("Expected 2 deleted, got %d", deleted)
+	}
+
+	// Limits apply in the query's sort order
+	store := MakeStore(db, entryType, serializeTestItem, deserializeTestItem, nil)
+	deleted, err = store.DeleteWhere(StoreQueryParams{SortOrder: Ascending, Limit: ptr(1)})
+	if err != nil {
+		t.Fatalf("Failed to delete store entries: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("Expected 1 deleted, got %d", deleted)
+	}
+
+	exists, err := db.Exists(entryType, "key_1")
+	if err != nil {
+		t.Fatalf("Failed to check entry: %v", err)
+	}
+	if exists {
+		t.Errorf("Expected oldest entry key_1 to be deleted")
+	}
+
+	count, err := db.Count()
+	if err != nil {
+		t.Fatalf("Failed to count entries: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 remaining entries, got %d", count)
+	}
+}
+
+func TestUpdateWhere(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_db"
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	entryType := "test_type"
+	err = db.BulkUpsert([]EntryInput{
+		{Type: entryType, Value: []byte("data_1"), Key: "key_1", Grouping: "g1", Timestamp: ptr(int64(10))},
+		{Type: entryType, Value: []byte("data_2"), Key: "key_2", Grouping: "g1", Timestamp: ptr(int64(20))},
+		{Type: entryType, Value: []byte("data_3"), Key: "key_3", Grouping: "g2", Timestamp: ptr(int64(30))},
+	})
+	if err != nil {
+		t.Fatalf("Failed to put entries: %v", err)
+	}
+
+	store := MakeStore(db, entryType, serializeTestItem, deserializeTestItem, nil)
+	updated, err := store.UpdateWhere(StoreQueryParams{Grouping: ptr("g1")}, EntryChanges{Grouping: ptr("g3"), SortingIndex: ptr(int64(5))})
+	if err != nil {
+		t.Fatalf("Failed to update entries: %v", err)
+	}
+	if updated != 2 {
+		t.Errorf("Expected 2 updated, got %d", updated)
+	}
+
+	entries, err := db.Query(QueryParams{Type: &entryType, Grouping: ptr("g3")})
+	if err != nil {
+		t.Fatalf("Failed to query entries: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries in g3, got %d", len(entries))
+	}
+	for _, entry := range entries {
+		if entry.SortingIndex == nil || *entry.SortingIndex != 5 {
+			t.Errorf("Expected sorting index 5 for %s, got %v", entry.Key, entry.SortingIndex)
+		}
+		if entry.Version != 2 {
+			t.Errorf("Expected version 2 for %s, got %d", entry.Key, entry.Version)
+		}
+	}
+
+	entry, err := db.Get(entryType, "key_3")
+	if err != nil {
+		t.Fatalf("Failed to get entry: %v", err)
+	}
+	if entry.GetGrouping() != "g2" {
+		t.Errorf("Expected key_3 to stay in g2, got %s", entry.GetGrouping())
+	}
+}
+
+func TestCreatedAtAndUpdatedAt(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_db"
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	entryType := "test_type"
+	if err := db.Upsert(EntryInput{Type: entryType, Value: []byte("data_1"), Key: "key_1", Timestamp: ptr(int64(100))}); err != nil {
+		t.Fatalf("Failed to put entry: %v", err)
+	}
+	created, err := db.Get(entryType, "key_1")
+	if err != nil {
+		t.Fatalf("Failed to get entry: %v", err)
+	}
+	if created.CreatedAt != created.UpdatedAt || created.CreatedAt < time.Now().Add(-time.Minute).UnixMilli() {
+		t.Errorf("Expected creation and update at write time, got %+v", created)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	if err := db.Upsert(EntryInput{Type: entryType, Value: []byte("data_2"), Key: "key_1", Timestamp: ptr(int64(50))}); err != nil {
+		t.Fatalf("Failed to replace entry: %v", err)
+	}
+	replaced, err := db.Get(entryType, "key_1")
+	if err != nil {
+		t.Fatalf("Failed to get entry: %v", err)
+	}
+	if replaced.CreatedAt != created.CreatedAt {
+		t.Errorf("Expected CreatedAt %d to be kept, got %d", created.CreatedAt, replaced.CreatedAt)
+	}
+	if replaced.UpdatedAt <= created.UpdatedAt {
+		t.Errorf("Expected UpdatedAt to advance past %d, got %d", created.UpdatedAt, replaced.UpdatedAt)
+	}
+
+	entries, err := db.Query(QueryParams{Type: &entryType, CreatedTo: &created.CreatedAt, UpdatedFrom: &replaced.UpdatedAt})
+	if err != nil {
+		t.Fatalf("Failed to query entries: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected 1 entry, got %d", len(entries))
+	}
+
+	entries, err = db.Query(QueryParams{Type: &entryType, UpdatedTo: &created.UpdatedAt})
+	if err != nil {
+		t.Fatalf("Failed to query entries: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected no entries updated before the replacement, got %d", len(entries))
+	}
+}
+
+func TestTouch(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_db"
+	now := int64(1000)
+	db, err := Init(namespace, name, WithClock(func() int64 { return now }))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	entryType := "test_type"
+	err = db.BulkUpsert([]EntryInput{
+		{Type: entryType, Value: []byte("data_1"), Key: "key_1", Timestamp: ptr(int64(10))},
+		{Type: entryType, Value: []byte("data_2"), Key: "key_2", Timestamp: ptr(int64(20))},
+	})
+	if err != nil {
+		t.Fatalf("Failed to put entries: %v", err)
+	}
+
+	now = 2000
+	touched, err := db.Touch(entryType, []string{"key_1", "missing"})
+	if err != nil {
+		t.Fatalf("Failed to touch entries: %v", err)
+	}
+	if touched != 1 {
+		t.Errorf("Expected 1 touched entry, got %d", touched)
+	}
+
+	entry, err := db.Get(entryType, "key_1")
+	if err != nil {
+		t.Fatalf("Failed to get entry: %v", err)
+	}
+	if entry.Timestamp != 2000 || string(entry.Value) != "data_1" {
+		t.Errorf("Expected timestamp 2000 with the value kept, got %+v", entry)
+	}
+
+	entry, err = db.Get(entryType, "key_2")
+	if err != nil {
+		t.Fatalf("Failed to get entry: %v", err)
+	}
+	if entry.Timestamp != 20 {
+		t.Errorf("Expected untouched timestamp 20, got %d", entry.Timestamp)
+	}
+}
+
+func TestQueryExclusions(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_db"
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	entryType := "test_type"
+	err = db.BulkUpsert([]EntryInput{
+		{Type: entryType, Value: []byte("data_1"), Key: "key_1", Grouping: "archived"},
+		{Type: entryType, Value: []byte("data_2"), Key: "key_2", Grouping: "active"},
+		{Type: entryType, Value: []byte("data_3"), Key: "key_3"},
+		{Type: entryType, Value: []byte("data_4"), Key: "key_4", Grouping: "active"},
+		{Type: "other_type", Value: []byte("data_5"), Key: "key_5", Grouping: "active"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to put entries: %v", err)
+	}
+
+	entries, err := db.Query(QueryParams{TypeNot: ptr("other_type"), ExcludeGroupings: []string{"archived", ""}, ExcludeKeys: []string{"key_4"}})
+	if err != nil {
+		t.Fatalf("Failed to query entries: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Key != "key_2" {
+		t.Errorf("Expected only key_2, got %+v", entries)
+	}
+
+	store := MakeStore(db, entryType, serializeTestItem, deserializeTestItem, nil)
+	count, err := store.CountWhere(StoreQueryParams{ExcludeGroupings: []string{"archived"}})
+	if err != nil {
+		t.Fatalf("Failed to count store entries: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("Expected 3 entries outside archived, got %d", count)
+	}
+}
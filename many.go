@@ -0,0 +1,139 @@
+package sidb
+
+import (
+	"cmp"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+)
+
+var ErrMergeUnsupported = errors.New("ranked queries need MergeSpec.Compare to be merged")
+
+// MergeSpec controls how QueryMany combines the results of each database.
+type MergeSpec struct {
+	Sort    []SortSpec             // Defaults to the query's sorting
+	Compare func(a, b DbEntry) int // Overrides Sort, needed for RankBy queries
+	Limit   *int                   // Applied to the merged results
+	Offset  *int                   // Applied to the merged results
+}
+
+type MergedEntry struct {
+	DbEntry
+	Source int // Index of the database in the dbs passed to QueryMany
+}
+
+// QueryMany runs the query on every database concurrently and merges the
+// results. Each database returns at most Offset+Limit entries, params.Limit
+// and params.Offset are ignored in favour of the merge's.
+func QueryMany(dbs []*Database, params QueryParams, merge MergeSpec) ([]MergedEntry, error) {
+	compare := merge.Compare
+	if compare == nil {
+		if params.RankBy != nil {
+			return nil, ErrMergeUnsupported
+		}
+		sort := merge.Sort
+		if len(sort) == 0 {
+			sort = params.Sort
+		}
+		if len(sort) == 0 {
+			sort = []SortSpec{{Field: params.SortField, Order: params.SortOrder}}
+		}
+		compare = compareEntries(sort)
+	}
+
+	params.Offset = nil
+	params.Limit = nil
+	if merge.Limit != nil {
+		limit := *merge.Limit
+		if merge.Offset != nil {
+			limit += *merge.Offset
+		}
+		params.Limit = &limit
+	}
+
+	results := make([][]DbEntry, len(dbs))
+	errs := make([]error, len(dbs))
+	var wg sync.WaitGroup
+	for i, db := range dbs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = db.Query(params)
+		}()
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	var merged []MergedEntry
+	for source, entries := range results {
+		for _, entry := range entries {
+			merged = append(merged, MergedEntry{DbEntry: entry, Source: source})
+		}
+	}
+	slices.SortStableFunc(merged, func(a, b MergedEntry) int {
+		return compare(a.DbEntry, b.DbEntry)
+	})
+
+	if merge.Offset != nil {
+		merged = merged[min(*merge.Offset, len(merged)):]
+	}
+	if merge.Limit != nil {
+		merged = merged[:min(*merge.Limit, len(merged))]
+	}
+	return merged, nil
+}
+
+// compareEntries orders entries the way orderBy does in SQL, NULL sorting
+// indexes first.
+func compareEntries(specs []SortSpec) func(a, b DbEntry) int {
+	return func(a, b DbEntry) int {
+		hasKey := false
+		order := Ascending
+		for _, spec := range specs {
+			order = spec.Order
+			var c int
+			switch spec.Field {
+			case SortByTimestamp:
+				c = cmp.Compare(a.Timestamp, b.Timestamp)
+			case SortBySortingIndex:
+				c = compareNullable(a.SortingIndex, b.SortingIndex)
+			case SortByKey:
+				hasKey = true
+				c = strings.Compare(a.Key, b.Key)
+			}
+			if c != 0 {
+				return directed(c, order)
+			}
+		}
+
+		if !hasKey {
+			if c := strings.Compare(a.Key, b.Key); c != 0 {
+				return directed(c, order)
+			}
+		}
+		return directed(strings.Compare(a.Type, b.Type), order)
+	}
+}
+
+func compareNullable(a, b *int64) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	return cmp.Compare(*a, *b)
+}
+
+func directed(c int, order SortOrder) int {
+	if order == Descending {
+		return -c
+	}
+	return c
+}
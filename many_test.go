@@ -0,0 +1,49 @@
+package sidb
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestQueryMany(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	var dbs []*Database
+	for i := 0; i < 3; i++ {
+		db, err := Init(namespace, fmt.Sprintf("test_query_many_%d", i))
+		if err != nil {
+			t.Fatalf("Failed to initialize database: %v", err)
+		}
+		defer db.Drop()
+		dbs = append(dbs, db)
+
+		for j := 0; j < 4; j++ {
+			// Database i holds timestamps i, i+3, i+6, i+9
+			timestamp := int64(i + j*3)
+			err := db.Upsert(EntryInput{Type: "task", Key: fmt.Sprintf("task_%d", timestamp), Value: []byte("data"), Timestamp: &timestamp})
+			if err != nil {
+				t.Fatalf("Failed to put entry: %v", err)
+			}
+		}
+	}
+
+	taskType := "task"
+	limit, offset := 4, 2
+	entries, err := QueryMany(dbs, QueryParams{Type: &taskType, SortOrder: Descending}, MergeSpec{Limit: &limit, Offset: &offset})
+	if err != nil {
+		t.Fatalf("Failed to query databases: %v", err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("Expected 4 entries, got %d", len(entries))
+	}
+	for i, entry := range entries {
+		expected := int64(9 - i)
+		if entry.Timestamp != expected || entry.Source != int(expected%3) {
+			t.Errorf("Expected timestamp %d from database %d, got %d from %d", expected, expected%3, entry.Timestamp, entry.Source)
+		}
+	}
+
+	_, err = QueryMany(dbs, QueryParams{RankBy: &DecayRank{HalfLife: 1}}, MergeSpec{})
+	if err != ErrMergeUnsupported {
+		t.Errorf("Expected ErrMergeUnsupported, got %v", err)
+	}
+}
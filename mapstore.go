@@ -0,0 +1,176 @@
+package sidb
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+)
+
+// A MapStore holds JSON objects, and can read and write single fields inside
+// SQLite with the JSON functions instead of decoding and re-encoding the
+// whole object in Go. The embedded Store reads and writes whole objects.
+type MapStore[V any] struct {
+	*Store[map[string]V]
+}
+
+func MakeMapStore[V any](db *Database, entryType string) *MapStore[V] {
+	serialize := func(value map[string]V) ([]byte, error) {
+		return json.Marshal(value)
+	}
+	deserialize := func(data []byte) (map[string]V, error) {
+		var value map[string]V
+		err := json.Unmarshal(data, &value)
+		return value, err
+	}
+	return &MapStore[V]{MakeStore(db, entryType, serialize, deserialize, nil)}
+}
+
+// jsonPath returns the JSON path of a top level field, quoted so fields may
+// contain dots and brackets.
+func jsonPath(field string) string {
+	return `$."` + strings.ReplaceAll(field, `"`, `\"`) + `"`
+}
+
+// SetField sets one field of the object at key, creating the object if the
+// key does not exist.
+func (store *MapStore[V]) SetField(key string, field string, value V) error {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return store.db.patchJSON(store.entryType, store.key(key), true, "json_set(COALESCE(CAST(value AS TEXT), '{}'), ?, json(?))", jsonPath(field), string(encoded))
+}
+
+// DeleteField removes one field of the object at key. Missing keys and fields
+// are ignored.
+func (store *MapStore[V]) DeleteField(key string, field string) error {
+	return store.db.patchJSON(store.entryType, store.key(key), false, "json_remove(CAST(value AS TEXT), ?)", jsonPath(field))
+}
+
+// GetField returns one field of the object at key, and whether it was found.
+func (store *MapStore[V]) GetField(key string, field string) (V, bool, error) {
+	var value V
+	db := store.db
+
+	// A signed value has to be read whole to be verified
+	if db.signs(store.entryType) {
+		object, err := store.Get(key)
+		if err != nil {
+			return value, false, err
+		}
+		value, found := object[field]
+		return value, found, nil
+	}
+
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	if db.connection == nil {
+		return value, false, ErrNoDbConnection
+	}
+
+	ctx, done := db.readContext()
+	defer done()
+
+	var encoded sql.NullString
+	err := db.connection.QueryRowContext(ctx, "SELECT CAST(value AS TEXT) -> ? FROM entries WHERE type = ? AND key = ? AND deletedAt IS NULL",
+		jsonPath(field), store.entryType, store.key(key)).Scan(&encoded)
+	if err == sql.ErrNoRows || (err == nil && !encoded.Valid) {
+		return value, false, nil
+	}
+	if err != nil {
+		return value, false, err
+	}
+
+	if err := json.Unmarshal([]byte(encoded.String), &value); err != nil {
+		return value, false, err
+	}
+	return value, true, nil
+}
+
+// patchJSON replaces the value of an entry with the result of a JSON function
+// over it. With create, a missing entry is first created as an empty object.
+func (db *Database) patchJSON(entryType string, key string, create bool, expression string, args ...interface{}) (err error) {
+	defer translateImmutable(&err)
+
+	defer db.afterWrite(entryType)
+
+	defer db.lockWrite(false, entryType)()
+
+	if db.connection == nil {
+		return ErrNoDbConnection
+	}
+
+	ctx, done := db.writeContext()
+	defer done()
+
+	tx, err := db.connection.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if create {
+		entry := EntryInput{Type: entryType, Key: key, Value: []byte("{}")}
+		if _, err := tx.ExecContext(ctx, upsertSQLWhere(1, "entries.deletedAt IS NOT NULL"), db.upsertArgs(entry, db.now())...); err != nil {
+			return err
+		}
+	}
+
+	if err := db.verifyStored(ctx, tx, entryType, key); err != nil {
+		return err
+	}
+
+	args = append(args, db.now(), entryType, key)
+	_, err = tx.ExecContext(ctx, "UPDATE entries SET value = CAST("+expression+" AS BLOB), projection = NULL, version = version + 1, updatedAt = ? WHERE type = ? AND key = ? AND deletedAt IS NULL", args...)
+	if err != nil {
+		return err
+	}
+
+	if err := db.checkQuotas(ctx, tx, entryType); err != nil {
+		return err
+	}
+
+	if err := db.resign(ctx, tx, entryType, key); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// verifyStored checks the signature of an entry about to be changed in SQL,
+// so that resign cannot turn a tampered value into a validly signed one.
+func (db *Database) verifyStored(ctx context.Context, tx *sql.Tx, entryType string, key string) error {
+	if !db.signs(entryType) {
+		return nil
+	}
+
+	var value, signature []byte
+	err := tx.QueryRowContext(ctx, "SELECT value, signature FROM entries WHERE type = ? AND key = ? AND deletedAt IS NULL", entryType, key).Scan(&value, &signature)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return db.verify(entryType, key, value, signature)
+}
+
+// resign updates the signature of an entry whose value was changed in SQL.
+func (db *Database) resign(ctx context.Context, tx *sql.Tx, entryType string, key string) error {
+	if !db.signs(entryType) {
+		return nil
+	}
+
+	var value []byte
+	err := tx.QueryRowContext(ctx, "SELECT value FROM entries WHERE type = ? AND key = ? AND deletedAt IS NULL", entryType, key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE entries SET signature = ? WHERE type = ? AND key = ?", db.sign(entryType, key, value), entryType, key)
+	return err
+}
@@ -0,0 +1,88 @@
+package sidb
+
+import "testing"
+
+func TestMapStore(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_map_store"
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	store := MakeMapStore[int](db, "counts")
+	if err := store.SetField("key", "a", 1); err != nil {
+		t.Fatalf("Failed to set field: %v", err)
+	}
+	if err := store.SetField("key", `b."odd".field`, 2); err != nil {
+		t.Fatalf("Failed to set field: %v", err)
+	}
+	if err := store.SetField("key", "a", 3); err != nil {
+		t.Fatalf("Failed to overwrite field: %v", err)
+	}
+
+	value, found, err := store.GetField("key", `b."odd".field`)
+	if err != nil {
+		t.Fatalf("Failed to get field: %v", err)
+	}
+	if !found || value != 2 {
+		t.Errorf("Expected field value 2, got %d (found %v)", value, found)
+	}
+
+	if err := store.DeleteField("key", "a"); err != nil {
+		t.Fatalf("Failed to delete field: %v", err)
+	}
+	if _, found, err := store.GetField("key", "a"); err != nil || found {
+		t.Errorf("Expected deleted field to be missing, got found=%v err=%v", found, err)
+	}
+	if _, found, err := store.GetField("missing", "a"); err != nil || found {
+		t.Errorf("Expected missing key to have no fields, got found=%v err=%v", found, err)
+	}
+
+	object, err := store.Get("key")
+	if err != nil {
+		t.Fatalf("Failed to get object: %v", err)
+	}
+	if len(object) != 1 || object[`b."odd".field`] != 2 {
+		t.Errorf("Unexpected object: %v", object)
+	}
+}
+
+func TestMapStoreSigned(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_map_store"
+	db, err := Init(namespace, name, WithSigningKey([]byte("secret")))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	store := MakeMapStore[string](db, "names")
+	if err := store.SetField("key", "first", "ada"); err != nil {
+		t.Fatalf("Failed to set field: %v", err)
+	}
+
+	// The signature is recomputed for the patched value
+	value, found, err := store.GetField("key", "first")
+	if err != nil {
+		t.Fatalf("Failed to get field: %v", err)
+	}
+	if !found || value != "ada" {
+		t.Errorf("Expected ada, got %q (found %v)", value, found)
+	}
+
+	// A value forged behind the package's back must not be re-signed
+	if _, err := db.RawExec(`UPDATE entries SET value = '{"first":"mallory"}' WHERE type = 'names'`); err != nil {
+		t.Fatalf("Failed to tamper with entry: %v", err)
+	}
+	if err := store.SetField("key", "last", "lovelace"); err != ErrTampered {
+		t.Errorf("Expected ErrTampered from SetField, got %v", err)
+	}
+	if err := store.DeleteField("key", "first"); err != ErrTampered {
+		t.Errorf("Expected ErrTampered from DeleteField, got %v", err)
+	}
+	if _, _, err := store.GetField("key", "first"); err != ErrTampered {
+		t.Errorf("Expected the entry to still be reported as tampered, got %v", err)
+	}
+}
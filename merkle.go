@@ -0,0 +1,375 @@
+package sidb
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+)
+
+// Merkle hashes summarize the contents of a type, or of one grouping of it, so
+// two databases can locate divergent entries without comparing every value.
+// Entries are bucketed by the hex digits of sha256(key), forming a 16-ary tree
+// where the node for a prefix covers every entry whose key hash starts with
+// it. Compare roots, then descend with MerkleChildren into the prefixes that
+// differ, and finally compare MerkleLeaves once a bucket is small.
+//
+// The hashes are kept in derived tables. The first Merkle call of a database
+// hashes every entry and installs triggers that record the keys each write
+// changes, including raw SQL; every later call folds those keys into the
+// stored hashes, so it costs as much as the writes since the previous call
+// rather than a scan of the type. A node hash is the XOR of the leaf hashes
+// under it, which lets a change be applied without rehashing its siblings.
+
+// Nodes down to this prefix length are stored, deeper ones are computed from
+// the leaves under them.
+const merkleDepth = 3
+
+const merkleTablesSQL = `CREATE TABLE IF NOT EXISTS merkle_leaves (
+	"type" TEXT NOT NULL,
+	"key" TEXT NOT NULL,
+	"grouping" TEXT NOT NULL,
+	"keyHash" TEXT NOT NULL,
+	"leafHash" BLOB NOT NULL,
+	PRIMARY KEY (type, key)
+) WITHOUT ROWID;
+CREATE INDEX IF NOT EXISTS merkle_leaves_key_hash ON merkle_leaves (type, keyHash);
+CREATE INDEX IF NOT EXISTS merkle_leaves_grouping_key_hash ON merkle_leaves (type, grouping, keyHash);
+CREATE TABLE IF NOT EXISTS merkle_nodes (
+	"type" TEXT NOT NULL,
+	"grouped" INTEGER NOT NULL,
+	"grouping" TEXT NOT NULL,
+	"prefix" TEXT NOT NULL,
+	"hash" BLOB NOT NULL,
+	PRIMARY KEY (type, grouped, grouping, prefix)
+) WITHOUT ROWID;
+CREATE TABLE IF NOT EXISTS merkle_dirty (
+	"type" TEXT NOT NULL,
+	"key" TEXT NOT NULL,
+	PRIMARY KEY (type, key)
+) WITHOUT ROWID`
+
+// The triggers use NOT EXISTS rather than OR IGNORE, which the conflict clause
+// of the statement firing them would override.
+const merkleTriggersSQL = `
+	CREATE TRIGGER IF NOT EXISTS entries_merkle_insert AFTER INSERT ON entries
+	BEGIN ` + markMerkleNew + ` END;
+	CREATE TRIGGER IF NOT EXISTS entries_merkle_update AFTER UPDATE OF type, key, value, grouping, deletedAt ON entries
+	BEGIN ` + markMerkleOld + markMerkleNew + ` END;
+	CREATE TRIGGER IF NOT EXISTS entries_merkle_delete AFTER DELETE ON entries
+	BEGIN ` + markMerkleOld + ` END;
+`
+
+const markMerkleOld = `INSERT INTO merkle_dirty (type, key) SELECT OLD.type, OLD.key
+	WHERE NOT EXISTS (SELECT 1 FROM merkle_dirty WHERE type = OLD.type AND key = OLD.key);`
+
+const markMerkleNew = `INSERT INTO merkle_dirty (type, key) SELECT NEW.type, NEW.key
+	WHERE NOT EXISTS (SELECT 1 FROM merkle_dirty WHERE type = NEW.type AND key = NEW.key);`
+
+type merkleNode struct {
+	grouped  bool
+	grouping string
+	prefix   string
+}
+
+// merkleTx folds the pending changes of entryType into the stored hashes and
+// runs fn in the same transaction.
+func (db *Database) merkleTx(entryType string, fn func(ctx context.Context, tx *sql.Tx) error) error {
+	defer db.lockWrite(false, entryType)()
+
+	if db.connection == nil {
+		return ErrNoDbConnection
+	}
+
+	ctx, done := db.writeContext()
+	defer done()
+
+	tx, err := db.connection.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := trackMerkle(ctx, tx); err != nil {
+		return err
+	}
+	if err := foldMerkle(ctx, tx, entryType); err != nil {
+		return err
+	}
+	if err := fn(ctx, tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// trackMerkle installs the triggers recording changed keys, and marks every
+// entry as changed so the hashes are rebuilt. It does nothing once they are
+// installed, but NormalizeSchema drops them along with the old table.
+func trackMerkle(ctx context.Context, tx *sql.Tx) error {
+	var triggers int
+	err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND tbl_name = 'entries' AND name LIKE 'entries_merkle_%'").Scan(&triggers)
+	if err != nil || triggers == 3 {
+		return err
+	}
+
+	for _, statement := range []string{
+		"DELETE FROM merkle_leaves",
+		"DELETE FROM merkle_nodes",
+		"DELETE FROM merkle_dirty",
+		"INSERT INTO merkle_dirty (type, key) SELECT type, key FROM entries WHERE deletedAt IS NULL",
+		merkleTriggersSQL,
+	} {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func foldMerkle(ctx context.Context, tx *sql.Tx, entryType string) error {
+	rows, err := tx.QueryContext(ctx, `SELECT merkle_dirty.key, entries.key IS NOT NULL, entries.value, COALESCE(entries.grouping, ''), merkle_leaves.grouping, merkle_leaves.leafHash
+		FROM merkle_dirty
+		LEFT JOIN entries ON entries.type = merkle_dirty.type AND entries.key = merkle_dirty.key AND entries.deletedAt IS NULL
+		LEFT JOIN merkle_leaves ON merkle_leaves.type = merkle_dirty.type AND merkle_leaves.key = merkle_dirty.key
+		WHERE merkle_dirty.type = ?`, entryType)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	type leaf struct {
+		key      string
+		grouping string
+		keyHash  string
+		leafHash []byte // nil once the entry is gone
+	}
+	var leaves []leaf
+	deltas := make(map[merkleNode][]byte)
+	apply := func(keyHash string, grouping string, leafHash []byte) {
+		for depth := 0; depth <= merkleDepth; depth++ {
+			for _, node := range []merkleNode{{prefix: keyHash[:depth]}, {grouped: true, grouping: grouping, prefix: keyHash[:depth]}} {
+				deltas[node] = xorHash(deltas[node], leafHash)
+			}
+		}
+	}
+
+	for rows.Next() {
+		var key string
+		var exists bool
+		var value []byte
+		var grouping string
+		var oldGrouping sql.NullString
+		var oldHash []byte
+		if err := rows.Scan(&key, &exists, &value, &grouping, &oldGrouping, &oldHash); err != nil {
+			return err
+		}
+
+		keyHash := merkleKeyHash(key)
+		if oldHash != nil {
+			apply(keyHash, oldGrouping.String, oldHash)
+		}
+		changed := leaf{key: key, grouping: grouping, keyHash: keyHash}
+		if exists {
+			changed.leafHash = merkleLeafHash(key, value)
+			apply(keyHash, grouping, changed.leafHash)
+		}
+		leaves = append(leaves, changed)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	for _, leaf := range leaves {
+		if leaf.leafHash == nil {
+			_, err = tx.ExecContext(ctx, "DELETE FROM merkle_leaves WHERE type = ? AND key = ?", entryType, leaf.key)
+		} else {
+			_, err = tx.ExecContext(ctx, `INSERT INTO merkle_leaves (type, key, grouping, keyHash, leafHash) VALUES (?, ?, ?, ?, ?)
+				ON CONFLICT(type, key) DO UPDATE SET grouping = excluded.grouping, leafHash = excluded.leafHash`,
+				entryType, leaf.key, leaf.grouping, leaf.keyHash, leaf.leafHash)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for node, delta := range deltas {
+		if delta == nil {
+			continue
+		}
+		hash, err := merkleNodeHash(ctx, tx, entryType, node)
+		if err != nil {
+			return err
+		}
+		hash = xorHash(hash, delta)
+		if hash == nil {
+			_, err = tx.ExecContext(ctx, "DELETE FROM merkle_nodes WHERE type = ? AND grouped = ? AND grouping = ? AND prefix = ?", entryType, node.grouped, node.grouping, node.prefix)
+		} else {
+			_, err = tx.ExecContext(ctx, `INSERT INTO merkle_nodes (type, grouped, grouping, prefix, hash) VALUES (?, ?, ?, ?, ?)
+				ON CONFLICT(type, grouped, grouping, prefix) DO UPDATE SET hash = excluded.hash`,
+				entryType, node.grouped, node.grouping, node.prefix, hash)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM merkle_dirty WHERE type = ?", entryType)
+	return err
+}
+
+func merkleKeyHash(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
+func merkleLeafHash(key string, value []byte) []byte {
+	hash := sha256.New()
+	hash.Write([]byte(key))
+	hash.Write([]byte{0})
+	hash.Write(value)
+	return hash.Sum(nil)
+}
+
+// xorHash returns nil for an all zero result so absent and empty nodes compare
+// equal.
+func xorHash(a []byte, b []byte) []byte {
+	result := make([]byte, sha256.Size)
+	copy(result, a)
+	empty := true
+	for i := range b {
+		result[i] ^= b[i]
+	}
+	for _, b := range result {
+		if b != 0 {
+			empty = false
+		}
+	}
+	if empty {
+		return nil
+	}
+	return result
+}
+
+func merkleNodeHash(ctx context.Context, tx *sql.Tx, entryType string, node merkleNode) ([]byte, error) {
+	var hash []byte
+	err := tx.QueryRowContext(ctx, "SELECT hash FROM merkle_nodes WHERE type = ? AND grouped = ? AND grouping = ? AND prefix = ?", entryType, node.grouped, node.grouping, node.prefix).Scan(&hash)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return hash, err
+}
+
+func merkleScope(grouping *string) merkleNode {
+	if grouping == nil {
+		return merkleNode{}
+	}
+	return merkleNode{grouped: true, grouping: *grouping}
+}
+
+// forMerkleLeaves calls fn for every leaf whose key hash starts with prefix.
+func forMerkleLeaves(ctx context.Context, tx *sql.Tx, entryType string, grouping *string, prefix string, fn func(key string, keyHash string, leafHash []byte)) error {
+	// Key hashes starting with prefix sort after it and before prefix + "g"
+	query := "SELECT key, keyHash, leafHash FROM merkle_leaves WHERE type = ? AND keyHash > ? AND keyHash < ?"
+	args := []interface{}{entryType, prefix, prefix + "g"}
+	if grouping != nil {
+		query += " AND grouping = ?"
+		args = append(args, *grouping)
+	}
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key, keyHash string
+		var leafHash []byte
+		if err := rows.Scan(&key, &keyHash, &leafHash); err != nil {
+			return err
+		}
+		fn(key, keyHash, leafHash)
+	}
+	return rows.Err()
+}
+
+func (db *Database) merkleRoot(entryType string, grouping *string) (root []byte, err error) {
+	err = db.merkleTx(entryType, func(ctx context.Context, tx *sql.Tx) error {
+		root, err = merkleNodeHash(ctx, tx, entryType, merkleScope(grouping))
+		return err
+	})
+	return root, err
+}
+
+func (db *Database) merkleChildren(entryType string, grouping *string, prefix string) (map[string][]byte, error) {
+	children := make(map[string][]byte)
+	err := db.merkleTx(entryType, func(ctx context.Context, tx *sql.Tx) error {
+		if len(prefix) >= merkleDepth {
+			return forMerkleLeaves(ctx, tx, entryType, grouping, prefix, func(key string, keyHash string, leafHash []byte) {
+				childPrefix := keyHash[:len(prefix)+1]
+				children[childPrefix] = xorHash(children[childPrefix], leafHash)
+			})
+		}
+
+		node := merkleScope(grouping)
+		for _, digit := range "0123456789abcdef" {
+			node.prefix = prefix + string(digit)
+			hash, err := merkleNodeHash(ctx, tx, entryType, node)
+			if err != nil {
+				return err
+			}
+			if hash != nil {
+				children[node.prefix] = hash
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return children, nil
+}
+
+func (db *Database) merkleLeaves(entryType string, grouping *string, prefix string) (map[string][]byte, error) {
+	leaves := make(map[string][]byte)
+	err := db.merkleTx(entryType, func(ctx context.Context, tx *sql.Tx) error {
+		return forMerkleLeaves(ctx, tx, entryType, grouping, prefix, func(key string, keyHash string, leafHash []byte) {
+			leaves[key] = leafHash
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return leaves, nil
+}
+
+func (db *Database) MerkleRoot(entryType string) ([]byte, error) {
+	return db.merkleRoot(entryType, nil)
+}
+
+// GroupingMerkleRoot covers the entries of one grouping of a type, where ""
+// is the ungrouped entries.
+func (db *Database) GroupingMerkleRoot(entryType string, grouping string) ([]byte, error) {
+	return db.merkleRoot(entryType, &grouping)
+}
+
+// MerkleChildren returns the hashes of the non-empty children of prefix, keyed
+// by the child prefix.
+func (db *Database) MerkleChildren(entryType string, prefix string) (map[string][]byte, error) {
+	return db.merkleChildren(entryType, nil, prefix)
+}
+
+func (db *Database) GroupingMerkleChildren(entryType string, grouping string, prefix string) (map[string][]byte, error) {
+	return db.merkleChildren(entryType, &grouping, prefix)
+}
+
+// MerkleLeaves returns the leaf hash of every entry under prefix, keyed by the
+// entry key.
+func (db *Database) MerkleLeaves(entryType string, prefix string) (map[string][]byte, error) {
+	return db.merkleLeaves(entryType, nil, prefix)
+}
+
+func (db *Database) GroupingMerkleLeaves(entryType string, grouping string, prefix string) (map[string][]byte, error) {
+	return db.merkleLeaves(entryType, &grouping, prefix)
+}
@@ -0,0 +1,250 @@
+package sidb
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestMerkleRootDetectsDivergence(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	a, err := Init(namespace, "test_merkle_a")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer a.Drop()
+	b, err := Init(namespace, "test_merkle_b")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer b.Drop()
+
+	entryType := "test_type"
+	entries := []EntryInput{
+		{Type: entryType, Value: []byte("data_1"), Key: "key_1", Grouping: "g1"},
+		{Type: entryType, Value: []byte("data_2"), Key: "key_2", Grouping: "g1"},
+		{Type: entryType, Value: []byte("data_3"), Key: "key_3", Grouping: "g2"},
+	}
+	if err := a.BulkUpsert(entries); err != nil {
+		t.Fatalf("Failed to put entries: %v", err)
+	}
+	if err := b.BulkUpsert(entries); err != nil {
+		t.Fatalf("Failed to put entries: %v", err)
+	}
+
+	rootA, err := a.MerkleRoot(entryType)
+	if err != nil {
+		t.Fatalf("Failed to compute root: %v", err)
+	}
+	rootB, err := b.MerkleRoot(entryType)
+	if err != nil {
+		t.Fatalf("Failed to compute root: %v", err)
+	}
+	if rootA == nil || !bytes.Equal(rootA, rootB) {
+		t.Fatalf("Expected equal non-empty roots, got %x and %x", rootA, rootB)
+	}
+
+	if err := b.Upsert(EntryInput{Type: entryType, Value: []byte("changed"), Key: "key_3", Grouping: "g2"}); err != nil {
+		t.Fatalf("Failed to put entry: %v", err)
+	}
+
+	rootB, err = b.MerkleRoot(entryType)
+	if err != nil {
+		t.Fatalf("Failed to compute root: %v", err)
+	}
+	if bytes.Equal(rootA, rootB) {
+		t.Fatalf("Expected roots to differ after a change")
+	}
+
+	groupA, _ := a.GroupingMerkleRoot(entryType, "g1")
+	groupB, _ := b.GroupingMerkleRoot(entryType, "g1")
+	if !bytes.Equal(groupA, groupB) {
+		t.Errorf("Expected untouched grouping roots to match")
+	}
+
+	// Descend until the differing key is found
+	prefix := ""
+	for {
+		childrenA, err := a.MerkleChildren(entryType, prefix)
+		if err != nil {
+			t.Fatalf("Failed to compute children: %v", err)
+		}
+		childrenB, err := b.MerkleChildren(entryType, prefix)
+		if err != nil {
+			t.Fatalf("Failed to compute children: %v", err)
+		}
+		next := ""
+		for childPrefix, hash := range childrenA {
+			if !bytes.Equal(hash, childrenB[childPrefix]) {
+				next = childPrefix
+			}
+		}
+		if next == "" {
+			t.Fatalf("Expected a differing child under %q", prefix)
+		}
+		prefix = next
+
+		leavesA, _ := a.MerkleLeaves(entryType, prefix)
+		if len(leavesA) == 1 {
+			if _, ok := leavesA["key_3"]; !ok {
+				t.Errorf("Expected to find key_3, got %v", leavesA)
+			}
+			break
+		}
+	}
+}
+
+func TestMerkleIncrementalMatchesRebuild(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	a, err := Init(namespace, "test_merkle_incremental")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer a.Drop()
+	b, err := Init(namespace, "test_merkle_rebuilt")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer b.Drop()
+
+	entryType := "test_type"
+	var entries []EntryInput
+	for i := 0; i < 200; i++ {
+		entries = append(entries, EntryInput{Type: entryType, Key: fmt.Sprintf("key_%d", i), Value: []byte("data"), Grouping: fmt.Sprintf("g%d", i%3)})
+	}
+
+	// a hashes its entries before the writes and folds them in afterwards
+	if _, err := a.MerkleRoot(entryType); err != nil {
+		t.Fatalf("Failed to compute root: %v", err)
+	}
+	if err := a.BulkUpsert(entries); err != nil {
+		t.Fatalf("Failed to put entries: %v", err)
+	}
+	if _, err := a.MerkleRoot(entryType); err != nil {
+		t.Fatalf("Failed to compute root: %v", err)
+	}
+	if err := a.Upsert(EntryInput{Type: entryType, Key: "key_1", Value: []byte("changed"), Grouping: "g2"}); err != nil {
+		t.Fatalf("Failed to put entry: %v", err)
+	}
+	if err := a.Delete(entryType, "key_2"); err != nil {
+		t.Fatalf("Failed to delete entry: %v", err)
+	}
+	if _, err := a.RawExec("UPDATE entries SET value = 'raw' WHERE key = 'key_3'"); err != nil {
+		t.Fatalf("Failed to run raw exec: %v", err)
+	}
+
+	entries[1] = EntryInput{Type: entryType, Key: "key_1", Value: []byte("changed"), Grouping: "g2"}
+	entries[3].Value = []byte("raw")
+	entries = append(entries[:2], entries[3:]...)
+	if err := b.BulkUpsert(entries); err != nil {
+		t.Fatalf("Failed to put entries: %v", err)
+	}
+
+	rootA, err := a.MerkleRoot(entryType)
+	if err != nil {
+		t.Fatalf("Failed to compute root: %v", err)
+	}
+	rootB, err := b.MerkleRoot(entryType)
+	if err != nil {
+		t.Fatalf("Failed to compute root: %v", err)
+	}
+	if rootA == nil || !bytes.Equal(rootA, rootB) {
+		t.Errorf("Expected the incremental root to match the rebuilt one, got %x and %x", rootA, rootB)
+	}
+	for _, grouping := range []string{"g0", "g1", "g2"} {
+		groupA, _ := a.GroupingMerkleRoot(entryType, grouping)
+		groupB, _ := b.GroupingMerkleRoot(entryType, grouping)
+		if groupA == nil || !bytes.Equal(groupA, groupB) {
+			t.Errorf("Expected grouping %s roots to match, got %x and %x", grouping, groupA, groupB)
+		}
+	}
+
+	// Missing triggers, as after NormalizeSchema rebuilds the table, make the
+	// next call rebuild the hashes
+	if _, err := a.RawExec("DROP TRIGGER entries_merkle_update"); err != nil {
+		t.Fatalf("Failed to drop trigger: %v", err)
+	}
+	if _, err := a.RawExec("UPDATE entries SET value = 'untracked' WHERE key = 'key_4'"); err != nil {
+		t.Fatalf("Failed to run raw exec: %v", err)
+	}
+	rootA, _ = a.MerkleRoot(entryType)
+	if bytes.Equal(rootA, rootB) {
+		t.Errorf("Expected the root to be rebuilt after the triggers went missing")
+	}
+
+	if root, err := a.MerkleRoot("missing"); err != nil || root != nil {
+		t.Errorf("Expected an empty root for an empty type, got %x, %v", root, err)
+	}
+}
+
+func TestGroupingMerkleDescent(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	a, err := Init(namespace, "test_merkle_grouping_a")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer a.Drop()
+	b, err := Init(namespace, "test_merkle_grouping_b")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer b.Drop()
+
+	entryType := "test_type"
+	var entries []EntryInput
+	for i := 0; i < 100; i++ {
+		entries = append(entries, EntryInput{Type: entryType, Key: fmt.Sprintf("key_%d", i), Value: []byte("data"), Grouping: fmt.Sprintf("g%d", i%2)})
+	}
+	if err := a.BulkUpsert(entries); err != nil {
+		t.Fatalf("Failed to put entries: %v", err)
+	}
+	entries[7].Value = []byte("changed")
+	if err := b.BulkUpsert(entries); err != nil {
+		t.Fatalf("Failed to put entries: %v", err)
+	}
+
+	// key_7 is in g1, so g0 agrees
+	if childrenA, _ := a.GroupingMerkleChildren(entryType, "g0", ""); len(childrenA) == 0 {
+		t.Fatalf("Expected children for g0")
+	}
+	leavesA, err := a.GroupingMerkleLeaves(entryType, "g0", "")
+	if err != nil || len(leavesA) != 50 {
+		t.Fatalf("Expected 50 leaves in g0, got %d, %v", len(leavesA), err)
+	}
+
+	prefix := ""
+	for depth := 0; ; depth++ {
+		if depth > 64 {
+			t.Fatalf("Failed to find the differing key")
+		}
+		childrenA, err := a.GroupingMerkleChildren(entryType, "g1", prefix)
+		if err != nil {
+			t.Fatalf("Failed to compute children: %v", err)
+		}
+		childrenB, err := b.GroupingMerkleChildren(entryType, "g1", prefix)
+		if err != nil {
+			t.Fatalf("Failed to compute children: %v", err)
+		}
+		next := ""
+		for childPrefix, hash := range childrenA {
+			if !bytes.Equal(hash, childrenB[childPrefix]) {
+				next = childPrefix
+			}
+		}
+		if next == "" {
+			t.Fatalf("Expected a differing child under %q", prefix)
+		}
+		prefix = next
+
+		leaves, err := a.GroupingMerkleLeaves(entryType, "g1", prefix)
+		if err != nil {
+			t.Fatalf("Failed to get leaves: %v", err)
+		}
+		if len(leaves) == 1 {
+			if _, ok := leaves["key_7"]; !ok {
+				t.Errorf("Expected to find key_7, got %v", leaves)
+			}
+			break
+		}
+	}
+}
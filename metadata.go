@@ -0,0 +1,82 @@
+package sidb
+
+// MetadataUpdate changes the grouping and sorting index of one entry without
+// rewriting its value. Nil fields are left as is.
+type MetadataUpdate struct {
+	Type         string
+	Key          string
+	Grouping     *string
+	SortingIndex *int64
+}
+
+// UpdateMetadata applies the updates in one transaction and returns how many
+// entries were updated. Missing keys are skipped.
+func (db *Database) UpdateMetadata(updates []MetadataUpdate) (updated int64, err error) {
+	defer translateImmutable(&err)
+
+	entryType := ""
+	if len(updates) > 0 {
+		entryType = updates[0].Type
+	}
+	defer db.lockWrite(true, entryType)()
+
+	if db.connection == nil {
+		return 0, ErrNoDbConnection
+	}
+
+	ctx, done := db.writeContext()
+	defer done()
+
+	tx, err := db.connection.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `UPDATE entries SET
+		grouping = CASE WHEN ? THEN ? ELSE grouping END,
+		sortingIndex = CASE WHEN ? THEN ? ELSE sortingIndex END,
+		version = version + 1, updatedAt = ?
+		WHERE type = ? AND key = ? AND deletedAt IS NULL`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	now := db.now()
+	for _, update := range updates {
+		var grouping interface{}
+		if update.Grouping != nil && (*update.Grouping != "" || !db.options.nullEmptyGrouping) {
+			grouping = *update.Grouping
+		}
+		var sortingIndex interface{}
+		if update.SortingIndex != nil {
+			sortingIndex = *update.SortingIndex
+		}
+
+		result, err := stmt.ExecContext(ctx, update.Grouping != nil, grouping, update.SortingIndex != nil, sortingIndex, now, update.Type, update.Key)
+		if err != nil {
+			return 0, err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		updated += affected
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return updated, nil
+}
+
+func (store *Store[T]) UpdateMetadata(updates []MetadataUpdate) (int64, error) {
+	typed := make([]MetadataUpdate, len(updates))
+	for i, update := range updates {
+		update.Type = store.entryType
+		update.Key = store.key(update.Key)
+		typed[i] = update
+	}
+	return store.db.UpdateMetadata(typed)
+}
@@ -0,0 +1,43 @@
+package sidb
+
+import "testing"
+
+func TestUpdateMetadata(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_update_metadata"
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	err = db.BulkUpsert([]EntryInput{
+		{Type: "task", Key: "a", Value: []byte("large value a"), Grouping: "inbox", SortingIndex: ptr(int64(1))},
+		{Type: "task", Key: "b", Value: []byte("large value b"), Grouping: "inbox", SortingIndex: ptr(int64(2))},
+	})
+	if err != nil {
+		t.Fatalf("Failed to put entries: %v", err)
+	}
+
+	done := "done"
+	updated, err := db.UpdateMetadata([]MetadataUpdate{
+		{Type: "task", Key: "a", SortingIndex: ptr(int64(5))},
+		{Type: "task", Key: "b", Grouping: &done},
+		{Type: "task", Key: "missing", Grouping: &done},
+	})
+	if err != nil {
+		t.Fatalf("Failed to update metadata: %v", err)
+	}
+	if updated != 2 {
+		t.Errorf("Expected 2 updated entries, got %d", updated)
+	}
+
+	a, _ := db.Get("task", "a")
+	if a.GetGrouping() != "inbox" || *a.SortingIndex != 5 || string(a.Value) != "large value a" || a.Version != 2 {
+		t.Errorf("Unexpected entry a: %+v", a)
+	}
+	b, _ := db.Get("task", "b")
+	if b.GetGrouping() != "done" || *b.SortingIndex != 2 || string(b.Value) != "large value b" {
+		t.Errorf("Unexpected entry b: %+v", b)
+	}
+}
@@ -0,0 +1,31 @@
+package sidb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+var ErrDatabaseNotFound = errors.New("database not found")
+
+// Open is Init for databases that must already exist: instead of creating an
+// empty database it fails with an error matching ErrDatabaseNotFound.
+func Open(namespace []string, name string, opts ...Option) (*Database, error) {
+	var options Options
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	_, dbPath, err := options.databasePath(namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(dbPath); err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%s: %w", dbPath, ErrDatabaseNotFound)
+		}
+		return nil, err
+	}
+
+	return Init(namespace, name, opts...)
+}
@@ -0,0 +1,44 @@
+package sidb
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestOpen(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_open"
+
+	_, err := Open(namespace, name)
+	if !errors.Is(err, ErrDatabaseNotFound) {
+		t.Fatalf("Expected ErrDatabaseNotFound, got %v", err)
+	}
+	_, dbPath, _ := databasePath(namespace, name)
+	if _, err := os.Stat(dbPath); !os.IsNotExist(err) {
+		t.Fatalf("Expected Open not to create %s", dbPath)
+	}
+
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+	if err := db.Upsert(EntryInput{Type: "note", Key: "a", Value: []byte("a")}); err != nil {
+		t.Fatalf("Failed to upsert: %v", err)
+	}
+	db.Close()
+
+	opened, err := Open(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer opened.Close()
+	entry, err := opened.Get("note", "a")
+	if err != nil {
+		t.Fatalf("Failed to get entry: %v", err)
+	}
+	if entry == nil || string(entry.Value) != "a" {
+		t.Errorf("Expected the existing entry, got %v", entry)
+	}
+}
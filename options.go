@@ -0,0 +1,164 @@
+package sidb
+
+import (
+	"os"
+	"time"
+)
+
+// Options configures a Database. They are built up by the Option functions
+// passed to Init.
+
+type Options struct {
+	thresholds []thresholdWatch
+	signing    *signingConfig
+
+	nullEmptyGrouping bool
+	chunkSize         int
+	syncOnEveryWrite  bool
+	softDelete        bool
+	writeConcurrency  int
+	clock             Clock
+	maxEntries        map[string]int
+	quotas            map[string]quota
+	statusPaths       map[string]string
+	autoVacuum        *AutoVacuum
+	readTimeout       time.Duration
+	writeTimeout      time.Duration
+	retention         []RetentionRule
+	retentionInterval time.Duration
+	pooledValues      bool
+	perf              PerfOptions
+	observers         []LifecycleObserver
+	busyTimeout       time.Duration
+	busyRetries       int
+	busyBackoff       time.Duration
+	idleCloseAfter    time.Duration
+	singleWriter      bool
+	baseDir           string
+	fileMode          os.FileMode
+	pragmas           []string
+	extraColumns      []ExtraColumn
+}
+
+type Option func(*Options)
+
+// WithNullEmptyGrouping stores entries without a grouping as NULL rather than
+// as an empty string. Reads and filters treat both the same way.
+func WithNullEmptyGrouping() Option {
+	return func(options *Options) {
+		options.nullEmptyGrouping = true
+	}
+}
+
+// WithChunkSize sets how many keys BulkGet, BulkDelete and other key list
+// operations bind per statement. Sizes are clamped between 2 and what fits
+// under SQLite's variable limit.
+func WithChunkSize(size int) Option {
+	return func(options *Options) {
+		options.chunkSize = min(max(size, 2), maxSQLVariables-9)
+	}
+}
+
+// WithSyncOnEveryWrite makes every commit fsync before returning, so an
+// acknowledged write survives a power loss. By default commits are only
+// synced at checkpoints, which is several times faster for small writes;
+// call Sync to flush explicitly instead.
+func WithSyncOnEveryWrite() Option {
+	return func(options *Options) {
+		options.syncOnEveryWrite = true
+	}
+}
+
+// WithSoftDelete makes deletes mark entries as deleted instead of removing
+// them. Deleted entries are hidden from reads until they are restored, revived
+// by a write, or removed for good with Purge.
+func WithSoftDelete() Option {
+	return func(options *Options) {
+		options.softDelete = true
+	}
+}
+
+// WithWriteConcurrency schedules writes so single entry writes go ahead of
+// bulk writes, and at most limit bulk writes per type wait for the database
+// at once. Bulk writes can be held back for as long as single writes keep
+// arriving.
+func WithWriteConcurrency(limit int) Option {
+	return func(options *Options) {
+		options.writeConcurrency = limit
+	}
+}
+
+// WithClock makes the database read the current time from clock instead of
+// the system clock.
+func WithClock(clock Clock) Option {
+	return func(options *Options) {
+		options.clock = clock
+	}
+}
+
+// WithBaseDir stores the database under dir instead of ~/.sidb. The namespace
+// is still appended to it, so Init(ns, name, WithBaseDir(dir)) opens
+// dir/ns.../name.db.
+func WithBaseDir(dir string) Option {
+	return func(options *Options) {
+		options.baseDir = dir
+	}
+}
+
+// WithFileMode sets the permissions of the database file, which SQLite also
+// gives to its write-ahead log and shared memory files. Directories Init
+// creates get the same permissions plus search permission where they are
+// readable. An existing file is changed to mode when it is opened.
+func WithFileMode(mode os.FileMode) Option {
+	return func(options *Options) {
+		options.fileMode = mode
+	}
+}
+
+// WithPragmas runs pragmas, written without the PRAGMA keyword such as
+// "temp_store = MEMORY", on every connection the database opens.
+func WithPragmas(pragmas ...string) Option {
+	return func(options *Options) {
+		for _, pragma := range pragmas {
+			options.pragmas = append(options.pragmas, "PRAGMA "+pragma)
+		}
+	}
+}
+
+func (options *Options) databasePath(namespace []string, name string) (string, string, error) {
+	if options.baseDir != "" {
+		dirPath, dbPath := databasePathIn(options.baseDir, namespace, name)
+		return dirPath, dbPath, nil
+	}
+	return databasePath(namespace, name)
+}
+
+func (options *Options) dirMode() os.FileMode {
+	if options.fileMode == 0 {
+		return 0755
+	}
+	// Directories need search permission wherever they can be read
+	return options.fileMode | (options.fileMode&0444)>>2
+}
+
+// applyFileMode creates the database file with the configured mode, or
+// changes an existing one and its companion files to it.
+func (options *Options) applyFileMode(dbPath string) error {
+	if options.fileMode == 0 {
+		return nil
+	}
+
+	file, err := os.OpenFile(dbPath, os.O_RDONLY|os.O_CREATE, options.fileMode)
+	if err != nil {
+		return err
+	}
+	file.Close()
+
+	// The umask may have taken permissions away from a new file
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		if err := os.Chmod(dbPath+suffix, options.fileMode); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package sidb
+
+import (
+	"os"
+	"path"
+	"testing"
+)
+
+func TestFileOptions(t *testing.T) {
+	baseDir := t.TempDir()
+	db, err := Init([]string{"app", "data"}, "notes", WithBaseDir(baseDir), WithFileMode(0600), WithPragmas("temp_store = MEMORY"))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	if db.Path != path.Join(baseDir, "app", "data", "notes.db") {
+		t.Errorf("Unexpected path: %s", db.Path)
+	}
+	if err := db.Upsert(EntryInput{Type: "note", Key: "a", Value: []byte("a")}); err != nil {
+		t.Fatalf("Failed to upsert: %v", err)
+	}
+
+	for _, file := range []string{db.Path, db.Path + "-wal", db.Path + "-shm"} {
+		info, err := os.Stat(file)
+		if err != nil {
+			t.Fatalf("Failed to stat %s: %v", file, err)
+		}
+		if info.Mode().Perm() != 0600 {
+			t.Errorf("Expected %s to have mode 0600, got %v", file, info.Mode().Perm())
+		}
+	}
+	info, err := os.Stat(path.Join(baseDir, "app"))
+	if err != nil {
+		t.Fatalf("Failed to stat directory: %v", err)
+	}
+	if info.Mode().Perm() != 0700 {
+		t.Errorf("Expected the directory to have mode 0700, got %v", info.Mode().Perm())
+	}
+
+	var tempStore int
+	if err := db.connection.QueryRow("PRAGMA temp_store").Scan(&tempStore); err != nil {
+		t.Fatalf("Failed to read temp_store: %v", err)
+	}
+	if tempStore != 2 {
+		t.Errorf("Expected temp_store MEMORY, got %d", tempStore)
+	}
+
+	// Shared handles are looked up under the same base directory
+	shared, err := GetOrInit([]string{"app", "data"}, "notes", WithBaseDir(baseDir))
+	if err != nil {
+		t.Fatalf("Failed GetOrInit: %v", err)
+	}
+	defer shared.Close()
+	if entry, _ := shared.Get("note", "a"); entry == nil {
+		t.Errorf("Expected the shared handle to open the same file")
+	}
+}
+
+func TestRootPathOverrides(t *testing.T) {
+	envRoot := t.TempDir()
+	t.Setenv("SIDB_ROOT", envRoot)
+
+	db, err := Init([]string{"test_namespace"}, "test_root_env")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+	if db.Path != path.Join(envRoot, "test_namespace", "test_root_env.db") {
+		t.Errorf("Unexpected path: %s", db.Path)
+	}
+
+	setRoot := t.TempDir()
+	SetRootPath(setRoot)
+	defer SetRootPath("")
+
+	root, err := RootPath()
+	if err != nil {
+		t.Fatalf("Failed to resolve root path: %v", err)
+	}
+	if root != setRoot {
+		t.Errorf("Expected SetRootPath to take precedence, got %s", root)
+	}
+
+	db2, err := Init([]string{"test_namespace"}, "test_root_set")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db2.Drop()
+	if db2.Path != path.Join(setRoot, "test_namespace", "test_root_set.db") {
+		t.Errorf("Unexpected path: %s", db2.Path)
+	}
+}
+
+func TestRootPathWithoutHome(t *testing.T) {
+	t.Setenv("SIDB_ROOT", "")
+	t.Setenv("HOME", "")
+
+	if _, err := RootPath(); err == nil {
+		t.Error("Expected an error when no home directory is available")
+	}
+	if _, err := Init([]string{"test_namespace"}, "test_root_home"); err == nil {
+		t.Error("Expected Init to fail when no root path can be resolved")
+	}
+}
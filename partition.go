@@ -0,0 +1,472 @@
+package sidb
+
+import (
+	"context"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A PartitionedDatabase stores entries in one database file per period of
+// their timestamp, so old periods are removed by deleting a file instead of
+// with a mass DELETE. Queries fan out to the partitions their time range
+// covers. An entry lives in the partition of its latest timestamp.
+//
+// It offers the subset of the Database API that can span partitions: Upsert,
+// BulkUpsert, Get, Delete, BulkDelete, Count and Query. Writes locate keys by
+// looking them up in each partition, which is a read, and only write to the
+// partitions that hold them.
+
+type PartitionPeriod int
+
+const (
+	PartitionMonthly PartitionPeriod = iota
+	PartitionDaily
+	PartitionYearly
+)
+
+func (period PartitionPeriod) layout() string {
+	switch period {
+	case PartitionDaily:
+		return "2006-01-02"
+	case PartitionYearly:
+		return "2006"
+	}
+	return "2006-01"
+}
+
+// start returns the start of the period containing t, in UTC.
+func (period PartitionPeriod) start(t time.Time) time.Time {
+	t = t.UTC()
+	switch period {
+	case PartitionDaily:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	case PartitionYearly:
+		return time.Date(t.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func (period PartitionPeriod) next(start time.Time) time.Time {
+	switch period {
+	case PartitionDaily:
+		return start.AddDate(0, 0, 1)
+	case PartitionYearly:
+		return start.AddDate(1, 0, 0)
+	}
+	return start.AddDate(0, 1, 0)
+}
+
+type PartitionedDatabase struct {
+	namespace []string
+	name      string
+	period    PartitionPeriod
+	opts      []Option
+	options   Options
+
+	mutex      sync.RWMutex
+	partitions map[time.Time]*Database
+}
+
+// InitPartitioned opens the partitions of name that already exist. Partitions
+// are named name_<period>, e.g. events_2024-05, and are opened with opts.
+func InitPartitioned(namespace []string, name string, period PartitionPeriod, opts ...Option) (*PartitionedDatabase, error) {
+	partitioned := &PartitionedDatabase{
+		namespace:  namespace,
+		name:       name,
+		period:     period,
+		opts:       opts,
+		partitions: make(map[time.Time]*Database),
+	}
+	for _, opt := range opts {
+		opt(&partitioned.options)
+	}
+
+	dirPath, _, err := partitioned.options.databasePath(namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	files, err := os.ReadDir(dirPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, file := range files {
+		label, ok := strings.CutPrefix(strings.TrimSuffix(file.Name(), ".db"), name+"_")
+		if !ok || !strings.HasSuffix(file.Name(), ".db") {
+			continue
+		}
+		start, err := time.Parse(period.layout(), label)
+		if err != nil {
+			continue
+		}
+		if _, err := partitioned.partition(start, true); err != nil {
+			partitioned.Close()
+			return nil, err
+		}
+	}
+	return partitioned, nil
+}
+
+// partition returns the partition starting at start, opening it if create is
+// set. The caller holds the write lock unless create is false.
+func (partitioned *PartitionedDatabase) partition(start time.Time, create bool) (*Database, error) {
+	if db, ok := partitioned.partitions[start]; ok || !create {
+		return db, nil
+	}
+	db, err := Init(partitioned.namespace, partitioned.name+"_"+start.Format(partitioned.period.layout()), partitioned.opts...)
+	if err != nil {
+		return nil, err
+	}
+	partitioned.partitions[start] = db
+	return db, nil
+}
+
+func (partitioned *PartitionedDatabase) now() int64 {
+	if partitioned.options.clock != nil {
+		return partitioned.options.clock()
+	}
+	return SystemClock()
+}
+
+// Partitions returns the start of every partition, oldest first.
+func (partitioned *PartitionedDatabase) Partitions() []time.Time {
+	partitioned.mutex.RLock()
+	defer partitioned.mutex.RUnlock()
+
+	return partitioned.sortedStarts()
+}
+
+func (partitioned *PartitionedDatabase) sortedStarts() []time.Time {
+	var starts []time.Time
+	for start := range partitioned.partitions {
+		starts = append(starts, start)
+	}
+	slices.SortFunc(starts, func(a, b time.Time) int { return a.Compare(b) })
+	return starts
+}
+
+func (partitioned *PartitionedDatabase) Upsert(entry EntryInput) error {
+	return partitioned.BulkUpsert([]EntryInput{entry})
+}
+
+// BulkUpsert writes each entry into the partition of its timestamp. An entry
+// whose key is held by another partition is moved: it is written and removed
+// from the old partition in one transaction. When a key appears more than
+// once, the last entry wins as with consecutive Upserts.
+func (partitioned *PartitionedDatabase) BulkUpsert(entries []EntryInput) error {
+	partitioned.mutex.Lock()
+	defer partitioned.mutex.Unlock()
+
+	now := partitioned.now()
+	positions := make(map[[2]string]int)
+	var deduplicated []EntryInput
+	for _, entry := range entries {
+		if entry.Timestamp == nil {
+			timestamp := now
+			entry.Timestamp = &timestamp
+		}
+		id := [2]string{entry.Type, entry.Key}
+		if position, ok := positions[id]; ok {
+			deduplicated[position] = entry
+			continue
+		}
+		positions[id] = len(deduplicated)
+		deduplicated = append(deduplicated, entry)
+	}
+
+	byPartition := make(map[time.Time][]EntryInput)
+	for _, entry := range deduplicated {
+		start := partitioned.period.start(time.UnixMilli(*entry.Timestamp))
+		byPartition[start] = append(byPartition[start], entry)
+	}
+
+	for start, group := range byPartition {
+		db, err := partitioned.partition(start, true)
+		if err != nil {
+			return err
+		}
+
+		// Entries are grouped by the partition they move out of, nil for
+		// those only in db or new
+		bySource := make(map[*Database][]EntryInput)
+		remaining := group
+		for otherStart, other := range partitioned.partitions {
+			if otherStart.Equal(start) || len(remaining) == 0 {
+				continue
+			}
+			held, err := other.heldKeys(remaining)
+			if err != nil {
+				return err
+			}
+			var rest []EntryInput
+			for _, entry := range remaining {
+				if held[[2]string{entry.Type, entry.Key}] {
+					bySource[other] = append(bySource[other], entry)
+				} else {
+					rest = append(rest, entry)
+				}
+			}
+			remaining = rest
+		}
+
+		if len(remaining) > 0 {
+			if err := db.BulkUpsert(remaining); err != nil {
+				return err
+			}
+		}
+		for source, moved := range bySource {
+			if err := db.moveFrom(source, moved); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// heldKeys returns which of the entries' keys db holds.
+func (db *Database) heldKeys(entries []EntryInput) (map[[2]string]bool, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	if db.connection == nil {
+		return nil, ErrNoDbConnection
+	}
+
+	ctx, done := db.readContext()
+	defer done()
+
+	keys := make(map[string][]string)
+	for _, entry := range entries {
+		keys[entry.Type] = append(keys[entry.Type], entry.Key)
+	}
+
+	held := make(map[[2]string]bool)
+	for entryType, typeKeys := range keys {
+		for _, chunk := range chunks(typeKeys, db.chunkSize()) {
+			args := []interface{}{entryType}
+			for _, key := range chunk {
+				args = append(args, key)
+			}
+			rows, err := db.connection.QueryContext(ctx, "SELECT key FROM entries WHERE type = ? AND deletedAt IS NULL AND key IN ("+placeholders(len(chunk))+")", args...)
+			if err != nil {
+				return nil, err
+			}
+			for rows.Next() {
+				var key string
+				if err := rows.Scan(&key); err != nil {
+					rows.Close()
+					return nil, err
+				}
+				held[[2]string{entryType, key}] = true
+			}
+			rows.Close()
+			if err := rows.Err(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return held, nil
+}
+
+// moveFrom upserts entries into db and deletes them from source in a single
+// transaction, so a failure leaves each entry where it was.
+func (db *Database) moveFrom(source *Database, entries []EntryInput) (err error) {
+	defer translateImmutable(&err)
+
+	defer db.afterWrite(entryTypes(entries)...)
+
+	defer lockWriters(db, source, entryTypes(entries)...)()
+
+	if db.connection == nil || source.connection == nil {
+		return ErrNoDbConnection
+	}
+
+	ctx, done := db.writeContext()
+	defer done()
+
+	// ATTACH only applies to one connection of the pool
+	conn, err := db.connection.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "ATTACH DATABASE ? AS source", source.Path); err != nil {
+		return err
+	}
+	// Not ctx, which may have expired by the time the move returns
+	defer conn.ExecContext(context.Background(), "DETACH DATABASE source")
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := db.now()
+	rowsPerBatch := maxSQLVariables / upsertColumnCount
+	for start := 0; start < len(entries); start += rowsPerBatch {
+		batch := entries[start:min(start+rowsPerBatch, len(entries))]
+
+		args := make([]interface{}, 0, len(batch)*upsertColumnCount)
+		for _, entry := range batch {
+			args = append(args, db.upsertArgs(entry, now)...)
+		}
+		if _, err := tx.ExecContext(ctx, upsertSQLWhere(len(batch), ""), args...); err != nil {
+			return err
+		}
+	}
+
+	for _, entry := range entries {
+		statement, args := source.deleteStatementFrom("source.entries", "type = ? AND key = ?", []interface{}{entry.Type, entry.Key})
+		if _, err := tx.ExecContext(ctx, statement, args...); err != nil {
+			return err
+		}
+	}
+
+	if err := db.checkQuotas(ctx, tx, entryTypes(entries)...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Get looks the key up in every partition, newest first.
+func (partitioned *PartitionedDatabase) Get(entryType string, key string) (*DbEntry, error) {
+	partitioned.mutex.RLock()
+	defer partitioned.mutex.RUnlock()
+
+	starts := partitioned.sortedStarts()
+	for i := len(starts) - 1; i >= 0; i-- {
+		entry, err := partitioned.partitions[starts[i]].Get(entryType, key)
+		if err != nil || entry != nil {
+			return entry, err
+		}
+	}
+	return nil, nil
+}
+
+func (partitioned *PartitionedDatabase) Delete(entryType string, key string) error {
+	return partitioned.BulkDelete(entryType, []string{key})
+}
+
+// BulkDelete deletes the keys from the partitions holding them.
+func (partitioned *PartitionedDatabase) BulkDelete(entryType string, keys []string) error {
+	partitioned.mutex.Lock()
+	defer partitioned.mutex.Unlock()
+
+	entries := make([]EntryInput, len(keys))
+	for i, key := range keys {
+		entries[i] = EntryInput{Type: entryType, Key: key}
+	}
+	for _, db := range partitioned.partitions {
+		held, err := db.heldKeys(entries)
+		if err != nil {
+			return err
+		}
+		var heldKeys []string
+		for _, key := range keys {
+			if held[[2]string{entryType, key}] {
+				heldKeys = append(heldKeys, key)
+			}
+		}
+		if len(heldKeys) == 0 {
+			continue
+		}
+		if err := db.BulkDelete(entryType, heldKeys); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Count returns the number of entries across every partition.
+func (partitioned *PartitionedDatabase) Count() (int64, error) {
+	partitioned.mutex.RLock()
+	defer partitioned.mutex.RUnlock()
+
+	var total int64
+	for _, db := range partitioned.partitions {
+		count, err := db.Count()
+		if err != nil {
+			return 0, err
+		}
+		total += count
+	}
+	return total, nil
+}
+
+// Query runs params on the partitions overlapping From and To and merges the
+// results as QueryMany does, with params.Limit and params.Offset applied to
+// the merged results.
+func (partitioned *PartitionedDatabase) Query(params QueryParams) ([]DbEntry, error) {
+	partitioned.mutex.RLock()
+	defer partitioned.mutex.RUnlock()
+
+	var dbs []*Database
+	for _, start := range partitioned.sortedStarts() {
+		end := partitioned.period.next(start).UnixMilli() - 1
+		if (params.From != nil && end < *params.From) || (params.To != nil && start.UnixMilli() > *params.To) {
+			continue
+		}
+		dbs = append(dbs, partitioned.partitions[start])
+	}
+
+	merged, err := QueryMany(dbs, params, MergeSpec{Limit: params.Limit, Offset: params.Offset})
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]DbEntry, len(merged))
+	for i, entry := range merged {
+		entries[i] = entry.DbEntry
+	}
+	return entries, nil
+}
+
+// DropBefore deletes the partitions that end before t and returns how many
+// were dropped.
+func (partitioned *PartitionedDatabase) DropBefore(t time.Time) (int, error) {
+	partitioned.mutex.Lock()
+	defer partitioned.mutex.Unlock()
+
+	dropped := 0
+	for start, db := range partitioned.partitions {
+		if partitioned.period.next(start).After(t) {
+			continue
+		}
+		if err := db.Drop(); err != nil {
+			return dropped, err
+		}
+		delete(partitioned.partitions, start)
+		dropped++
+	}
+	return dropped, nil
+}
+
+func (partitioned *PartitionedDatabase) Close() error {
+	partitioned.mutex.Lock()
+	defer partitioned.mutex.Unlock()
+
+	for _, db := range partitioned.partitions {
+		if err := db.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Drop deletes every partition.
+func (partitioned *PartitionedDatabase) Drop() error {
+	partitioned.mutex.Lock()
+	defer partitioned.mutex.Unlock()
+
+	for start, db := range partitioned.partitions {
+		if err := db.Drop(); err != nil {
+			return err
+		}
+		delete(partitioned.partitions, start)
+	}
+	return nil
+}
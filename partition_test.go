@@ -0,0 +1,126 @@
+package sidb
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestPartitionedDatabase(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_partitioned"
+	db, err := InitPartitioned(namespace, name, PartitionMonthly)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { db.Drop() }()
+
+	month := func(m time.Month) int64 {
+		return time.Date(2024, m, 15, 0, 0, 0, 0, time.UTC).UnixMilli()
+	}
+	var entries []EntryInput
+	for i, m := range []time.Month{time.January, time.February, time.March} {
+		for j := 0; j < 2; j++ {
+			entries = append(entries, EntryInput{Type: "log", Key: fmt.Sprintf("log_%d_%d", i, j), Value: []byte("data"), Timestamp: ptr(month(m) + int64(j))})
+		}
+	}
+	if err := db.BulkUpsert(entries); err != nil {
+		t.Fatalf("Failed to put entries: %v", err)
+	}
+	if partitions := db.Partitions(); len(partitions) != 3 || partitions[0].Month() != time.January {
+		t.Fatalf("Expected 3 monthly partitions, got %v", partitions)
+	}
+
+	// Moving an entry to another month moves it to that partition
+	if err := db.Upsert(EntryInput{Type: "log", Key: "log_0_0", Value: []byte("moved"), Timestamp: ptr(month(time.March) + 5)}); err != nil {
+		t.Fatalf("Failed to put entry: %v", err)
+	}
+
+	from := month(time.February)
+	limit := 3
+	results, err := db.Query(QueryParams{From: &from, Limit: &limit, SortOrder: Descending})
+	if err != nil {
+		t.Fatalf("Failed to query: %v", err)
+	}
+	if len(results) != 3 || results[0].Key != "log_0_0" || results[1].Key != "log_2_1" {
+		t.Errorf("Unexpected results: %+v", results)
+	}
+
+	dropped, err := db.DropBefore(time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Failed to drop partitions: %v", err)
+	}
+	if dropped != 2 {
+		t.Errorf("Expected 2 dropped partitions, got %d", dropped)
+	}
+	if entry, _ := db.Get("log", "log_0_0"); entry == nil || string(entry.Value) != "moved" {
+		t.Errorf("Expected the moved entry to survive, got %+v", entry)
+	}
+	if entry, _ := db.Get("log", "log_1_0"); entry != nil {
+		t.Errorf("Expected February entries to be dropped")
+	}
+
+	// Reopening finds the remaining partition
+	db.Close()
+	db, err = InitPartitioned(namespace, name, PartitionMonthly)
+	if err != nil {
+		t.Fatalf("Failed to reopen database: %v", err)
+	}
+	if partitions := db.Partitions(); len(partitions) != 1 || partitions[0].Month() != time.March {
+		t.Errorf("Expected the March partition, got %v", partitions)
+	}
+}
+
+func TestPartitionedMoves(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_partitioned_moves"
+	db, err := InitPartitioned(namespace, name, PartitionMonthly)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { db.Drop() }()
+
+	month := func(m time.Month) int64 {
+		return time.Date(2024, m, 15, 0, 0, 0, 0, time.UTC).UnixMilli()
+	}
+
+	// The last entry for a key wins, whatever the periods involved
+	err = db.BulkUpsert([]EntryInput{
+		{Type: "log", Key: "a", Value: []byte("first"), Timestamp: ptr(month(time.March))},
+		{Type: "log", Key: "a", Value: []byte("last"), Timestamp: ptr(month(time.January))},
+	})
+	if err != nil {
+		t.Fatalf("Failed to put entries: %v", err)
+	}
+	if count, _ := db.Count(); count != 1 {
+		t.Errorf("Expected 1 entry, got %d", count)
+	}
+	if entry, _ := db.Get("log", "a"); entry == nil || string(entry.Value) != "last" {
+		t.Errorf("Expected the last entry to win, got %+v", entry)
+	}
+
+	// A move that cannot delete the old entry leaves it where it was
+	if err := db.Upsert(EntryInput{Type: "log", Key: "pinned", Value: []byte("data"), Timestamp: ptr(month(time.February)), Immutable: true}); err != nil {
+		t.Fatalf("Failed to put entry: %v", err)
+	}
+	err = db.Upsert(EntryInput{Type: "log", Key: "pinned", Value: []byte("moved"), Timestamp: ptr(month(time.April))})
+	if err != ErrImmutable {
+		t.Errorf("Expected ErrImmutable, got %v", err)
+	}
+	april := time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC)
+	if partition := db.partitions[april]; partition != nil {
+		if entry, _ := partition.Get("log", "pinned"); entry != nil {
+			t.Errorf("Expected the failed move to leave no copy behind")
+		}
+	}
+	if count, _ := db.Count(); count != 2 {
+		t.Errorf("Expected 2 entries, got %d", count)
+	}
+
+	if err := db.BulkDelete("log", []string{"a", "missing"}); err != nil {
+		t.Fatalf("Failed to delete entries: %v", err)
+	}
+	if entry, _ := db.Get("log", "a"); entry != nil {
+		t.Errorf("Expected a to be deleted")
+	}
+}
@@ -0,0 +1,42 @@
+package sidb
+
+import "fmt"
+
+// PerfOptions tunes how SQLite trades memory and durability for speed. Zero
+// values keep SQLite's defaults. The benchmarks in perf_test.go show their
+// effect:
+//
+//	go test -bench . -run ^$
+type PerfOptions struct {
+	// CacheSizeKiB is the page cache of each connection, 2000 KiB by default
+	CacheSizeKiB int
+	// MmapSizeBytes is how much of the file is read through memory mapping
+	// instead of read calls, none by default
+	MmapSizeBytes int64
+	// Synchronous is "OFF", "NORMAL" or "FULL". The default, NORMAL, syncs at
+	// checkpoints only. WithSyncOnEveryWrite takes precedence over it.
+	Synchronous string
+}
+
+func WithPerfOptions(perf PerfOptions) Option {
+	return func(options *Options) {
+		options.perf = perf
+	}
+}
+
+// params returns the DSN parameters and the per connection pragmas that apply
+// the options.
+func (perf PerfOptions) params() ([]string, []string) {
+	var params, pragmas []string
+	if perf.Synchronous != "" {
+		params = append(params, "_sync="+perf.Synchronous)
+	}
+	if perf.CacheSizeKiB > 0 {
+		// A negative size is in KiB rather than pages
+		params = append(params, fmt.Sprintf("_cache_size=-%d", perf.CacheSizeKiB))
+	}
+	if perf.MmapSizeBytes > 0 {
+		pragmas = append(pragmas, fmt.Sprintf("PRAGMA mmap_size = %d", perf.MmapSizeBytes))
+	}
+	return params, pragmas
+}
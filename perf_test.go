@@ -0,0 +1,135 @@
+package sidb
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestPerfOptions(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_perf_options"
+	db, err := Init(namespace, name, WithPerfOptions(PerfOptions{CacheSizeKiB: 8192, MmapSizeBytes: 1 << 20, Synchronous: "OFF"}))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	// Every connection of the pool is tuned, not just the first
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		conn, err := db.connection.Conn(ctx)
+		if err != nil {
+			t.Fatalf("Failed to get connection: %v", err)
+		}
+		defer conn.Close()
+
+		var cacheSize, mmapSize, synchronous int64
+		if err := conn.QueryRowContext(ctx, "PRAGMA cache_size").Scan(&cacheSize); err != nil {
+			t.Fatalf("Failed to read cache_size: %v", err)
+		}
+		if err := conn.QueryRowContext(ctx, "PRAGMA mmap_size").Scan(&mmapSize); err != nil {
+			t.Fatalf("Failed to read mmap_size: %v", err)
+		}
+		if err := conn.QueryRowContext(ctx, "PRAGMA synchronous").Scan(&synchronous); err != nil {
+			t.Fatalf("Failed to read synchronous: %v", err)
+		}
+		if cacheSize != -8192 || mmapSize != 1<<20 || synchronous != 0 {
+			t.Errorf("Unexpected pragmas on connection %d: cache_size %d, mmap_size %d, synchronous %d", i, cacheSize, mmapSize, synchronous)
+		}
+	}
+}
+
+var benchmarkValueSizes = []int{100, 10 * 1024, 1024 * 1024}
+
+var benchmarkPerfOptions = []struct {
+	name string
+	perf PerfOptions
+}{
+	{"default", PerfOptions{}},
+	{"tuned", PerfOptions{CacheSizeKiB: 64 * 1024, MmapSizeBytes: 256 << 20}},
+}
+
+// runPerfBenchmark runs fn against a fresh database for every value size and
+// set of PerfOptions.
+func runPerfBenchmark(b *testing.B, fn func(b *testing.B, db *Database, value []byte)) {
+	for _, options := range benchmarkPerfOptions {
+		for _, size := range benchmarkValueSizes {
+			b.Run(fmt.Sprintf("%s/%dB", options.name, size), func(b *testing.B) {
+				db, err := Init([]string{"test_namespace"}, "bench_perf", WithPerfOptions(options.perf))
+				if err != nil {
+					b.Fatalf("Failed to initialize database: %v", err)
+				}
+				defer db.Drop()
+
+				b.SetBytes(int64(size))
+				b.ReportAllocs()
+				fn(b, db, bytes.Repeat([]byte{'x'}, size))
+			})
+		}
+	}
+}
+
+func fillBenchmarkEntries(b *testing.B, db *Database, value []byte, count int) {
+	var inputs []EntryInput
+	for i := 0; i < count; i++ {
+		inputs = append(inputs, EntryInput{Type: "bench", Key: fmt.Sprintf("key_%d", i), Value: value, Grouping: fmt.Sprintf("group_%d", i%10)})
+	}
+	if err := db.BulkUpsert(inputs); err != nil {
+		b.Fatalf("Failed BulkUpsert: %v", err)
+	}
+}
+
+func BenchmarkGet(b *testing.B) {
+	runPerfBenchmark(b, func(b *testing.B, db *Database, value []byte) {
+		fillBenchmarkEntries(b, db, value, 100)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := db.Get("bench", fmt.Sprintf("key_%d", i%100)); err != nil {
+				b.Fatalf("Failed to get: %v", err)
+			}
+		}
+	})
+}
+
+func BenchmarkUpsert(b *testing.B) {
+	runPerfBenchmark(b, func(b *testing.B, db *Database, value []byte) {
+		for i := 0; i < b.N; i++ {
+			if err := db.Upsert(EntryInput{Type: "bench", Key: fmt.Sprintf("key_%d", i%100), Value: value}); err != nil {
+				b.Fatalf("Failed to upsert: %v", err)
+			}
+		}
+	})
+}
+
+func BenchmarkBulkUpsert(b *testing.B) {
+	runPerfBenchmark(b, func(b *testing.B, db *Database, value []byte) {
+		inputs := make([]EntryInput, 20)
+		for i := range inputs {
+			inputs[i] = EntryInput{Type: "bench", Key: fmt.Sprintf("key_%d", i), Value: value}
+		}
+		b.SetBytes(int64(len(value) * len(inputs)))
+		for i := 0; i < b.N; i++ {
+			if err := db.BulkUpsert(inputs); err != nil {
+				b.Fatalf("Failed BulkUpsert: %v", err)
+			}
+		}
+	})
+}
+
+func BenchmarkQuery(b *testing.B) {
+	runPerfBenchmark(b, func(b *testing.B, db *Database, value []byte) {
+		fillBenchmarkEntries(b, db, value, 100)
+		benchType := "bench"
+		limit := 10
+		b.SetBytes(int64(len(value) * limit))
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			grouping := fmt.Sprintf("group_%d", i%10)
+			if _, err := db.Query(QueryParams{Type: &benchType, Grouping: &grouping, Limit: &limit}); err != nil {
+				b.Fatalf("Failed to query: %v", err)
+			}
+		}
+	})
+}
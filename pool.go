@@ -0,0 +1,180 @@
+package sidb
+
+import (
+	"database/sql"
+	"strings"
+	"sync"
+)
+
+var valuePool = sync.Pool{
+	New: func() any { return new([]byte) },
+}
+
+// WithPooledValues makes reads that return many entries, such as Query,
+// BulkGet and GetMany, copy values into pooled buffers. Calling Release on an
+// entry once its value is no longer needed hands the buffer to the next read,
+// which saves an allocation per entry when large values are read and thrown
+// away. Entries that are never released are simply garbage collected.
+func WithPooledValues() Option {
+	return func(options *Options) {
+		options.pooledValues = true
+	}
+}
+
+// Release returns the entry's value buffer to the pool and clears Value. It
+// is a no-op for entries not read into a pooled buffer. The value must not be
+// used after Release, through this entry or any copy of it.
+func (entry *DbEntry) Release() {
+	if entry.pooled == nil {
+		return
+	}
+	*entry.pooled = entry.Value[:0]
+	valuePool.Put(entry.pooled)
+	entry.pooled = nil
+	entry.Value = nil
+}
+
+// ReleaseEntries releases every entry in entries.
+func ReleaseEntries(entries []DbEntry) {
+	for i := range entries {
+		entries[i].Release()
+	}
+}
+
+func (db *Database) scanPooledEntry(rows *sql.Rows) (DbEntry, error) {
+	var entry DbEntry
+	var value sql.RawBytes
+	signature, err := db.scanEntryInto(rows, &entry, &value)
+	if err != nil {
+		return entry, err
+	}
+
+	if value != nil {
+		entry.pooled = valuePool.Get().(*[]byte)
+		entry.Value = append((*entry.pooled)[:0], value...)
+	}
+
+	if err := db.verify(entry.Type, entry.Key, entry.Value, signature); err != nil {
+		entry.Release()
+		return entry, err
+	}
+	return entry, nil
+}
+
+// forEachPageSize is how many entries ForEachEntry reads between calls to fn.
+const forEachPageSize = 100
+
+// ForEachEntry calls fn for every entry matching params when it starts, in
+// order, until fn returns false or an error. Values are read into pooled
+// buffers that are released when fn returns, whether or not WithPooledValues
+// is set, so fn must copy a value it keeps. Entries are read a page at a time
+// and no lock is held while fn runs, so fn may write to the database, for
+// instance to migrate the entries it is given. An entry changed before fn
+// reaches it is passed as it is then, and one deleted is skipped.
+func (db *Database) ForEachEntry(params QueryParams, fn func(entry DbEntry) (bool, error)) error {
+	ids, err := db.matchingIDs(params)
+	if err != nil {
+		return err
+	}
+
+	for start := 0; start < len(ids); start += forEachPageSize {
+		page := ids[start:min(start+forEachPageSize, len(ids))]
+		entries, err := db.pooledEntries(page)
+		if err != nil {
+			return err
+		}
+
+		for _, id := range page {
+			entry, ok := entries[id]
+			if !ok {
+				continue
+			}
+			delete(entries, id)
+			cont, err := fn(entry)
+			entry.Release()
+			if err != nil || !cont {
+				for _, entry := range entries {
+					entry.Release()
+				}
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// matchingIDs returns the type and key of every entry matching params.
+func (db *Database) matchingIDs(params QueryParams) ([][2]string, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	if db.connection == nil {
+		return nil, ErrNoDbConnection
+	}
+
+	ctx, done := db.readContext()
+	defer done()
+
+	query, args, err := db.selectQuery(params, "type, key")
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.connection.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids [][2]string
+	for rows.Next() {
+		var id [2]string
+		if err := rows.Scan(&id[0], &id[1]); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// pooledEntries reads the entries with the given types and keys into pooled
+// buffers.
+func (db *Database) pooledEntries(ids [][2]string) (map[[2]string]DbEntry, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	if db.connection == nil {
+		return nil, ErrNoDbConnection
+	}
+
+	ctx, done := db.readContext()
+	defer done()
+
+	args := make([]interface{}, 0, 2*len(ids))
+	for _, id := range ids {
+		args = append(args, id[0], id[1])
+	}
+	values := strings.Repeat("(?, ?),", len(ids))
+	rows, err := db.connection.QueryContext(ctx, "SELECT "+db.entryColumns()+" FROM entries WHERE deletedAt IS NULL AND (type, key) IN (VALUES "+values[:len(values)-1]+")", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make(map[[2]string]DbEntry, len(ids))
+	for rows.Next() {
+		entry, err := db.scanPooledEntry(rows)
+		if err != nil {
+			for _, entry := range entries {
+				entry.Release()
+			}
+			return nil, err
+		}
+		entries[[2]string{entry.Type, entry.Key}] = entry
+	}
+	return entries, rows.Err()
+}
+
+func (store *Store[T]) ForEachEntry(params StoreQueryParams, fn func(entry DbEntry) (bool, error)) error {
+	return store.db.ForEachEntry(store.queryParams(params), fn)
+}
@@ -0,0 +1,177 @@
+package sidb
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestPooledValues(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_pooled_values"
+	db, err := Init(namespace, name, WithPooledValues(), WithSigningKey([]byte("secret")))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	for i := 0; i < 5; i++ {
+		err := db.Upsert(EntryInput{Type: "blob", Key: fmt.Sprintf("key_%d", i), Value: []byte(fmt.Sprintf("value_%d", i)), Timestamp: ptr(int64(i))})
+		if err != nil {
+			t.Fatalf("Failed to upsert: %v", err)
+		}
+	}
+
+	blobType := "blob"
+	params := QueryParams{Type: &blobType, SortOrder: Ascending}
+	for round := 0; round < 3; round++ {
+		entries, err := db.Query(params)
+		if err != nil {
+			t.Fatalf("Failed to query: %v", err)
+		}
+		for i, entry := range entries {
+			if string(entry.Value) != fmt.Sprintf("value_%d", i) {
+				t.Fatalf("Unexpected value in round %d: %q", round, entry.Value)
+			}
+		}
+		ReleaseEntries(entries)
+		if entries[0].Value != nil {
+			t.Errorf("Expected Release to clear the value")
+		}
+	}
+
+	// Entries from single reads are not pooled and Release leaves them alone
+	entry, err := db.Get("blob", "key_0")
+	if err != nil {
+		t.Fatalf("Failed to get: %v", err)
+	}
+	entry.Release()
+	if string(entry.Value) != "value_0" {
+		t.Errorf("Expected the value to survive Release, got %q", entry.Value)
+	}
+}
+
+func TestForEachEntry(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_for_each_entry"
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	for i := 0; i < 5; i++ {
+		err := db.Upsert(EntryInput{Type: "blob", Key: fmt.Sprintf("key_%d", i), Value: bytes.Repeat([]byte{byte(i)}, 1024), Timestamp: ptr(int64(i))})
+		if err != nil {
+			t.Fatalf("Failed to upsert: %v", err)
+		}
+	}
+
+	blobType := "blob"
+	var keys []string
+	var total int
+	err = db.ForEachEntry(QueryParams{Type: &blobType, SortOrder: Ascending}, func(entry DbEntry) (bool, error) {
+		if len(entry.Value) != 1024 || entry.Value[0] != byte(len(keys)) {
+			t.Errorf("Unexpected value for %s", entry.Key)
+		}
+		keys = append(keys, entry.Key)
+		total += len(entry.Value)
+		return len(keys) < 3, nil
+	})
+	if err != nil {
+		t.Fatalf("Failed ForEachEntry: %v", err)
+	}
+	if len(keys) != 3 || keys[2] != "key_2" || total != 3*1024 {
+		t.Errorf("Expected to stop after 3 entries, got %v", keys)
+	}
+}
+
+func TestForEachEntryWrites(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_for_each_entry_writes"
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	var inputs []EntryInput
+	for i := 0; i < 250; i++ {
+		inputs = append(inputs, EntryInput{Type: "doc", Key: fmt.Sprintf("key_%03d", i), Value: []byte("v1"), Timestamp: ptr(int64(i))})
+	}
+	if err := db.BulkUpsert(inputs); err != nil {
+		t.Fatalf("Failed to put entries: %v", err)
+	}
+
+	docType := "doc"
+	visited := make(map[string]int)
+	vacuumed := make(chan error, 1)
+	err = db.ForEachEntry(QueryParams{Type: &docType, SortOrder: Ascending}, func(entry DbEntry) (bool, error) {
+		visited[entry.Key]++
+		if entry.Key == "key_000" {
+			// A writer waiting for the exclusive lock must not block fn's writes
+			go func() { vacuumed <- db.Vacuum() }()
+			time.Sleep(10 * time.Millisecond)
+			if err := db.Delete("doc", "key_200"); err != nil {
+				return false, err
+			}
+		}
+		// Rewriting moves the entry to the end of the timestamp order
+		return true, db.Upsert(EntryInput{Type: "doc", Key: entry.Key, Value: []byte("v2")})
+	})
+	if err != nil {
+		t.Fatalf("Failed ForEachEntry: %v", err)
+	}
+	if err := <-vacuumed; err != nil {
+		t.Errorf("Failed to vacuum: %v", err)
+	}
+
+	if len(visited) != 249 || visited["key_200"] != 0 {
+		t.Errorf("Expected every remaining entry to be visited, got %d", len(visited))
+	}
+	for key, count := range visited {
+		if count != 1 {
+			t.Errorf("Expected %s to be visited once, got %d", key, count)
+		}
+	}
+}
+
+func benchmarkLargeValues(b *testing.B, pooled bool) {
+	var options []Option
+	if pooled {
+		options = append(options, WithPooledValues())
+	}
+	db, err := Init([]string{"test_namespace"}, "bench_pooled_values", options...)
+	if err != nil {
+		b.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	var inputs []EntryInput
+	for i := 0; i < 100; i++ {
+		inputs = append(inputs, EntryInput{Type: "blob", Key: fmt.Sprintf("key_%d", i), Value: bytes.Repeat([]byte{'x'}, 64*1024)})
+	}
+	if err := db.BulkUpsert(inputs); err != nil {
+		b.Fatalf("Failed BulkUpsert: %v", err)
+	}
+
+	blobType := "blob"
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		entries, err := db.Query(QueryParams{Type: &blobType})
+		if err != nil {
+			b.Fatalf("Failed to query: %v", err)
+		}
+		ReleaseEntries(entries)
+	}
+}
+
+func BenchmarkLargeValues(b *testing.B) {
+	benchmarkLargeValues(b, false)
+}
+
+func BenchmarkPooledLargeValues(b *testing.B) {
+	benchmarkLargeValues(b, true)
+}
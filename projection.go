@@ -0,0 +1,74 @@
+package sidb
+
+import "encoding/json"
+
+// A Projection reads a small summary of each value in a store, so list views
+// don't decode whole documents. The summary is extracted and stored as JSON
+// in the projection column on every store write. Entries written another way,
+// or before the projection was registered, fall back to decoding the value.
+type Projection[T any, L any] struct {
+	store   *Store[T]
+	extract func(T) L
+}
+
+// WithProjection registers extract as the projection of store. A store has at
+// most one projection; registering another replaces it for later writes.
+func WithProjection[L any, T any](store *Store[T], extract func(T) L) *Projection[T, L] {
+	store.project = func(value T) ([]byte, error) {
+		return json.Marshal(extract(value))
+	}
+	return &Projection[T, L]{store: store, extract: extract}
+}
+
+func (projection *Projection[T, L]) Query(params StoreQueryParams) ([]L, error) {
+	db := projection.store.db
+
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	if db.connection == nil {
+		return nil, ErrNoDbConnection
+	}
+
+	ctx, done := db.readContext()
+	defer done()
+
+	query, args, err := db.selectQuery(projection.store.queryParams(params), "projection, CASE WHEN projection IS NULL THEN value END, key")
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.connection.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []L
+	for rows.Next() {
+		var projected, value []byte
+		var key string
+		if err := rows.Scan(&projected, &value, &key); err != nil {
+			return nil, err
+		}
+
+		var result L
+		if projected != nil {
+			if err := json.Unmarshal(projected, &result); err != nil {
+				return nil, err
+			}
+		} else {
+			decoded, err := projection.store.decode(key, value)
+			if err != nil {
+				return nil, err
+			}
+			result = projection.extract(decoded)
+		}
+		results = append(results, result)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
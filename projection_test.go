@@ -0,0 +1,56 @@
+package sidb
+
+import "testing"
+
+type testItemSummary struct {
+	Name string
+}
+
+func TestProjection(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_projection"
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	store := MakeStore(db, "test_type", serializeTestItem, deserializeTestItem, nil)
+
+	// Written before the projection exists, so read through the fallback
+	err = store.Upsert(StoreEntryInput[testItem]{Key: "key_1", Value: testItem{Name: "first", Value: 1}, Timestamp: ptr(int64(1))})
+	if err != nil {
+		t.Fatalf("Failed to upsert: %v", err)
+	}
+
+	summaries := WithProjection(store, func(item testItem) testItemSummary {
+		return testItemSummary{Name: item.Name}
+	})
+
+	err = store.Upsert(StoreEntryInput[testItem]{Key: "key_2", Value: testItem{Name: "second", Value: 2}, Timestamp: ptr(int64(2))})
+	if err != nil {
+		t.Fatalf("Failed to upsert: %v", err)
+	}
+
+	entry, err := db.RawQuery("SELECT key FROM entries WHERE key = 'key_2' AND projection = ?", []byte(`{"Name":"second"}`))
+	if err != nil {
+		t.Fatalf("Failed to read projection column: %v", err)
+	}
+	if len(entry) != 1 {
+		t.Errorf("Expected the projection to be stored with the entry")
+	}
+
+	results, err := summaries.Query(StoreQueryParams{SortOrder: Ascending})
+	if err != nil {
+		t.Fatalf("Failed to query projections: %v", err)
+	}
+	expected := []testItemSummary{{Name: "first"}, {Name: "second"}}
+	if len(results) != len(expected) {
+		t.Fatalf("Expected %d results, got %d", len(expected), len(results))
+	}
+	for i, result := range results {
+		if result != expected[i] {
+			t.Errorf("At index %d, expected %+v, got %+v", i, expected[i], result)
+		}
+	}
+}
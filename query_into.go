@@ -0,0 +1,128 @@
+package sidb
+
+import "database/sql"
+
+// QueryInto reads the entries matching params into *dst, reusing its
+// capacity. The values are copied into the Value buffers of the entries *dst
+// already holds, so values from an earlier call are overwritten and must be
+// copied first if they are kept. Pass a fresh slice to get new buffers.
+func (db *Database) QueryInto(params QueryParams, dst *[]DbEntry) error {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	if db.connection == nil {
+		return ErrNoDbConnection
+	}
+
+	ctx, done := db.readContext()
+	defer done()
+
+	query, args, err := db.selectQuery(params, db.entryColumns())
+	if err != nil {
+		return err
+	}
+
+	rows, err := db.connection.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	results := (*dst)[:0]
+	var value sql.RawBytes
+	for i := 0; rows.Next(); i++ {
+		var buffer []byte
+		if i < cap(results) {
+			buffer = results[:i+1][i].Value[:0]
+		}
+
+		var entry DbEntry
+		signature, err := db.scanEntryInto(rows, &entry, &value)
+		if err != nil {
+			return err
+		}
+		if value != nil {
+			entry.Value = append(buffer, value...)
+		}
+		if err := db.verify(entry.Type, entry.Key, entry.Value, signature); err != nil {
+			return err
+		}
+		results = append(results, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	*dst = results
+	return nil
+}
+
+func (store *Store[T]) QueryEntriesInto(params StoreQueryParams, dst *[]DbEntry) error {
+	return store.db.QueryInto(store.queryParams(params), dst)
+}
+
+// QueryInto decodes the values matching params into *dst, reusing its
+// capacity, for callers that run the same query often. Values are decoded
+// straight from the row buffer without being copied, so the store's
+// deserialize function must not keep the bytes it is given (json.Unmarshal
+// and most decoders copy what they need).
+func (store *Store[T]) QueryInto(params StoreQueryParams, dst *[]T) error {
+	db := store.db
+
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	if db.connection == nil {
+		return ErrNoDbConnection
+	}
+
+	ctx, done := db.readContext()
+	defer done()
+
+	signed := db.signs(store.entryType)
+	columns := "value, key"
+	if signed {
+		columns = "value, key, signature"
+	}
+	query, args, err := db.selectQuery(store.queryParams(params), columns)
+	if err != nil {
+		return err
+	}
+
+	rows, err := db.connection.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	results := (*dst)[:0]
+	var value, key, signature sql.RawBytes
+	for rows.Next() {
+		if signed {
+			err = rows.Scan(&value, &key, &signature)
+		} else {
+			err = rows.Scan(&value, &key)
+		}
+		if err != nil {
+			return err
+		}
+		if signed {
+			if err := db.verify(store.entryType, string(key), value, signature); err != nil {
+				return err
+			}
+		}
+
+		// The key is only copied out of the row buffer when it is needed
+		decoded, err := store.deserialize(value)
+		if err != nil {
+			return &SerializationError{Type: store.entryType, Key: string(key), Op: "deserialize", Cause: err}
+		}
+		results = append(results, decoded)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	*dst = results
+	return nil
+}
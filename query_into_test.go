@@ -0,0 +1,141 @@
+package sidb
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestQueryInto(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_query_into"
+	db, err := Init(namespace, name, WithSigningKey([]byte("secret")))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	store := MakeStore(db, "test_type", serializeTestItem, deserializeTestItem, nil)
+
+	var inputs []StoreEntryInput[testItem]
+	for i := 0; i < 20; i++ {
+		inputs = append(inputs, StoreEntryInput[testItem]{Key: fmt.Sprintf("key_%d", i), Value: testItem{Name: fmt.Sprintf("item_%d", i), Value: i}, Timestamp: ptr(int64(i))})
+	}
+	if err := store.BulkUpsert(inputs); err != nil {
+		t.Fatalf("Failed BulkUpsert: %v", err)
+	}
+
+	results := make([]testItem, 0, 32)
+	backing := &results[:1][0]
+	limit := 10
+	if err := store.QueryInto(StoreQueryParams{Limit: &limit, SortOrder: Ascending}, &results); err != nil {
+		t.Fatalf("Failed QueryInto: %v", err)
+	}
+	if len(results) != 10 || results[0].Value != 0 || results[9].Value != 9 {
+		t.Fatalf("Unexpected results: %+v", results)
+	}
+	if &results[0] != backing {
+		t.Errorf("Expected the destination slice to be reused")
+	}
+
+	// A second query replaces the previous results
+	limit = 3
+	if err := store.QueryInto(StoreQueryParams{Limit: &limit, SortOrder: Descending}, &results); err != nil {
+		t.Fatalf("Failed QueryInto: %v", err)
+	}
+	if len(results) != 3 || results[0].Value != 19 {
+		t.Errorf("Unexpected results: %+v", results)
+	}
+
+	if _, err := db.RawExec("UPDATE entries SET value = CAST('{}' AS BLOB) WHERE key = 'key_19'"); err != nil {
+		t.Fatalf("Failed to tamper with entry: %v", err)
+	}
+	if err := store.QueryInto(StoreQueryParams{Limit: &limit, SortOrder: Descending}, &results); err != ErrTampered {
+		t.Errorf("Expected ErrTampered, got %v", err)
+	}
+}
+
+func benchmarkStoreQuery(b *testing.B, into bool) {
+	db, err := Init([]string{"test_namespace"}, "bench_query_into")
+	if err != nil {
+		b.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	store := MakeStore(db, "test_type", serializeTestItem, deserializeTestItem, nil)
+	var inputs []StoreEntryInput[testItem]
+	for i := 0; i < 100; i++ {
+		inputs = append(inputs, StoreEntryInput[testItem]{Key: fmt.Sprintf("key_%d", i), Value: testItem{Name: "item", Value: i}})
+	}
+	if err := store.BulkUpsert(inputs); err != nil {
+		b.Fatalf("Failed BulkUpsert: %v", err)
+	}
+
+	var results []testItem
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if into {
+			err = store.QueryInto(StoreQueryParams{}, &results)
+		} else {
+			results, err = store.Query(StoreQueryParams{})
+		}
+		if err != nil {
+			b.Fatalf("Failed to query: %v", err)
+		}
+	}
+}
+
+func BenchmarkStoreQuery(b *testing.B) {
+	benchmarkStoreQuery(b, false)
+}
+
+func BenchmarkStoreQueryInto(b *testing.B) {
+	benchmarkStoreQuery(b, true)
+}
+
+func TestDatabaseQueryInto(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_database_query_into"
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	for i := 0; i < 5; i++ {
+		err := db.Upsert(EntryInput{Type: "item", Key: fmt.Sprintf("key_%d", i), Value: []byte(fmt.Sprintf("value_%d", i)), Timestamp: ptr(int64(i))})
+		if err != nil {
+			t.Fatalf("Failed to upsert: %v", err)
+		}
+	}
+
+	itemType := "item"
+	var entries []DbEntry
+	if err := db.QueryInto(QueryParams{Type: &itemType, SortOrder: Ascending}, &entries); err != nil {
+		t.Fatalf("Failed QueryInto: %v", err)
+	}
+	if len(entries) != 5 || entries[0].Key != "key_0" || string(entries[4].Value) != "value_4" {
+		t.Fatalf("Unexpected entries: %+v", entries)
+	}
+
+	// The second call reuses the value buffers of the first
+	buffer := &entries[0].Value[0]
+	if err := db.QueryInto(QueryParams{Type: &itemType, SortOrder: Descending}, &entries); err != nil {
+		t.Fatalf("Failed QueryInto: %v", err)
+	}
+	if len(entries) != 5 || entries[0].Key != "key_4" || string(entries[0].Value) != "value_4" {
+		t.Fatalf("Unexpected entries: %+v", entries)
+	}
+	if &entries[0].Value[0] != buffer {
+		t.Errorf("Expected the value buffer to be reused")
+	}
+
+	store := MakeStore(db, "item", serializeTestItem, deserializeTestItem, nil)
+	limit := 2
+	if err := store.QueryEntriesInto(StoreQueryParams{Limit: &limit}, &entries); err != nil {
+		t.Fatalf("Failed QueryEntriesInto: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("Expected 2 entries, got %d", len(entries))
+	}
+}
@@ -0,0 +1,129 @@
+package sidb
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// A Queue keeps its items as entries of one type. The key is a ULID, so items
+// are taken in enqueue order, and the sorting index holds the time from which
+// the item may be dequeued. Dequeuing moves that time forward by the
+// visibility timeout and stores a lease token in the subgrouping, so an item
+// that is not acknowledged in time is handed out again.
+type Queue struct {
+	db        *Database
+	entryType string
+}
+
+var ErrLeaseLost = errors.New("queue lease expired or was taken over")
+
+type QueueItem struct {
+	ID      string
+	Payload []byte
+	Lease   string
+}
+
+func MakeQueue(db *Database, entryType string) *Queue {
+	return &Queue{db: db, entryType: entryType}
+}
+
+func (queue *Queue) Enqueue(payload []byte) (string, error) {
+	return queue.EnqueueAfter(payload, 0)
+}
+
+// EnqueueAfter adds an item that cannot be dequeued until delay has passed.
+func (queue *Queue) EnqueueAfter(payload []byte, delay time.Duration) (string, error) {
+	visibleAt := queue.db.now() + delay.Milliseconds()
+	return queue.db.UpsertAutoKey(EntryInput{Type: queue.entryType, Value: payload, SortingIndex: &visibleAt})
+}
+
+// Dequeue claims the oldest visible item for visibility, or returns nil when
+// there is none. The claim has to be ended with Ack or Nack before it expires.
+func (queue *Queue) Dequeue(visibility time.Duration) (*QueueItem, error) {
+	db := queue.db
+	defer db.lockWrite(false, queue.entryType)()
+
+	if db.connection == nil {
+		return nil, ErrNoDbConnection
+	}
+
+	ctx, done := db.writeContext()
+	defer done()
+
+	tx, err := db.connection.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	now := db.now()
+	item := QueueItem{Lease: NewULID(now)}
+	err = tx.QueryRowContext(ctx, "SELECT key, value FROM entries WHERE type = ? AND deletedAt IS NULL AND sortingIndex <= ? ORDER BY sortingIndex, key LIMIT 1",
+		queue.entryType, now).Scan(&item.ID, &item.Payload)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE entries SET sortingIndex = ?, subgrouping = ?, version = version + 1, updatedAt = ? WHERE type = ? AND key = ?",
+		now+visibility.Milliseconds(), item.Lease, now, queue.entryType, item.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// Ack removes a dequeued item. It returns ErrLeaseLost if the claim expired
+// and the item was dequeued again since.
+func (queue *Queue) Ack(item *QueueItem) error {
+	db := queue.db
+	defer db.lockWrite(false, queue.entryType)()
+
+	if db.connection == nil {
+		return ErrNoDbConnection
+	}
+
+	ctx, done := db.writeContext()
+	defer done()
+
+	query, args := db.deleteStatement("type = ? AND key = ? AND subgrouping = ?", []interface{}{queue.entryType, item.ID, item.Lease})
+	return leaseResult(db.connection.ExecContext(ctx, query, args...))
+}
+
+// Nack releases a dequeued item so it can be dequeued again after delay.
+func (queue *Queue) Nack(item *QueueItem, delay time.Duration) error {
+	db := queue.db
+	defer db.lockWrite(false, queue.entryType)()
+
+	if db.connection == nil {
+		return ErrNoDbConnection
+	}
+
+	ctx, done := db.writeContext()
+	defer done()
+
+	now := db.now()
+	return leaseResult(db.connection.ExecContext(ctx, "UPDATE entries SET sortingIndex = ?, subgrouping = '', version = version + 1, updatedAt = ? WHERE type = ? AND key = ? AND subgrouping = ? AND deletedAt IS NULL",
+		now+delay.Milliseconds(), now, queue.entryType, item.ID, item.Lease))
+}
+
+func leaseResult(result sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
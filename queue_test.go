@@ -0,0 +1,76 @@
+package sidb
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQueue(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_queue"
+	now := int64(1000)
+	db, err := Init(namespace, name, WithClock(func() int64 { return now }))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	queue := MakeQueue(db, "jobs")
+	for _, payload := range []string{"first", "second"} {
+		if _, err := queue.Enqueue([]byte(payload)); err != nil {
+			t.Fatalf("Failed to enqueue: %v", err)
+		}
+	}
+
+	first, err := queue.Dequeue(time.Second)
+	if err != nil {
+		t.Fatalf("Failed to dequeue: %v", err)
+	}
+	second, err := queue.Dequeue(time.Second)
+	if err != nil {
+		t.Fatalf("Failed to dequeue: %v", err)
+	}
+	if first == nil || second == nil || string(first.Payload) != "first" || string(second.Payload) != "second" {
+		t.Fatalf("Expected items in enqueue order, got %+v and %+v", first, second)
+	}
+
+	empty, err := queue.Dequeue(time.Second)
+	if err != nil {
+		t.Fatalf("Failed to dequeue: %v", err)
+	}
+	if empty != nil {
+		t.Errorf("Expected no visible items while both are claimed, got %+v", empty)
+	}
+
+	if err := queue.Ack(first); err != nil {
+		t.Fatalf("Failed to ack: %v", err)
+	}
+
+	// The second claim expires and the item is handed out again
+	now += 2000
+	again, err := queue.Dequeue(time.Second)
+	if err != nil {
+		t.Fatalf("Failed to dequeue: %v", err)
+	}
+	if again == nil || again.ID != second.ID {
+		t.Fatalf("Expected the expired item to be dequeued again, got %+v", again)
+	}
+	if err := queue.Ack(second); err != ErrLeaseLost {
+		t.Errorf("Expected ErrLeaseLost acking an expired claim, got %v", err)
+	}
+
+	if err := queue.Nack(again, 500*time.Millisecond); err != nil {
+		t.Fatalf("Failed to nack: %v", err)
+	}
+	if item, _ := queue.Dequeue(time.Second); item != nil {
+		t.Errorf("Expected the nacked item to be delayed, got %+v", item)
+	}
+	now += 500
+	item, err := queue.Dequeue(time.Second)
+	if err != nil {
+		t.Fatalf("Failed to dequeue: %v", err)
+	}
+	if item == nil || item.ID != second.ID {
+		t.Errorf("Expected the nacked item after its delay, got %+v", item)
+	}
+}
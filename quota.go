@@ -0,0 +1,112 @@
+package sidb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// Quotas limit the total size of the values of a type. Rejecting quotas are
+// checked inside the write's transaction, after the write, so a replaced value
+// only counts once. Evicting quotas are enforced after the write like
+// WithMaxEntries.
+
+type QuotaPolicy int
+
+const (
+	QuotaReject QuotaPolicy = iota
+	QuotaEvictOldest
+)
+
+var ErrQuotaExceeded = errors.New("type storage quota exceeded")
+
+type quota struct {
+	maxBytes int64
+	policy   QuotaPolicy
+}
+
+// WithQuota limits the summed value size of a type to maxBytes. With
+// QuotaReject, writes that would exceed it fail with ErrQuotaExceeded. With
+// QuotaEvictOldest, the oldest entries by timestamp are deleted for good until
+// the type fits again; immutable entries are kept but still count.
+func WithQuota(entryType string, maxBytes int64, policy QuotaPolicy) Option {
+	return func(options *Options) {
+		if options.quotas == nil {
+			options.quotas = make(map[string]quota)
+		}
+		options.quotas[entryType] = quota{maxBytes: maxBytes, policy: policy}
+	}
+}
+
+const usageSQL = "SELECT COALESCE(SUM(length(value)), 0) FROM entries WHERE type = ? AND deletedAt IS NULL"
+
+// TypeUsage returns the summed size in bytes of the values of a type.
+func (db *Database) TypeUsage(entryType string) (int64, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	if db.connection == nil {
+		return 0, ErrNoDbConnection
+	}
+
+	ctx, done := db.readContext()
+	defer done()
+
+	var usage int64
+	err := db.connection.QueryRowContext(ctx, usageSQL, entryType).Scan(&usage)
+	return usage, err
+}
+
+// checkQuotas returns ErrQuotaExceeded if a rejecting quota of any of the
+// written types is exceeded within tx.
+func (db *Database) checkQuotas(ctx context.Context, tx *sql.Tx, entryTypes ...string) error {
+	for _, entryType := range entryTypes {
+		quota, ok := db.options.quotas[entryType]
+		if !ok || quota.policy != QuotaReject {
+			continue
+		}
+
+		var usage int64
+		if err := tx.QueryRowContext(ctx, usageSQL, entryType).Scan(&usage); err != nil {
+			return err
+		}
+		if usage > quota.maxBytes {
+			return fmt.Errorf("%w: %s uses %d of %d bytes", ErrQuotaExceeded, entryType, usage, quota.maxBytes)
+		}
+	}
+	return nil
+}
+
+// evictOverQuota deletes the oldest entries of entryType if it is over an
+// evicting quota. The caller holds the write lock.
+func (db *Database) evictOverQuota(ctx context.Context, entryType string) {
+	quota, ok := db.options.quotas[entryType]
+	if !ok || quota.policy != QuotaEvictOldest {
+		return
+	}
+
+	var usage int64
+	if err := db.connection.QueryRowContext(ctx, usageSQL, entryType).Scan(&usage); err != nil || usage <= quota.maxBytes {
+		return
+	}
+
+	// Immutable entries take their share of the quota from the newest end
+	db.connection.ExecContext(ctx, `DELETE FROM entries WHERE type = ? AND key IN (
+		SELECT key FROM (
+			SELECT key, immutable, SUM(length(value)) OVER (ORDER BY immutable DESC, timestamp DESC, key DESC) AS total
+			FROM entries WHERE type = ? AND deletedAt IS NULL
+		) WHERE total > ? AND immutable = 0)`, entryType, entryType, quota.maxBytes)
+}
+
+func entryTypes(entries []EntryInput) []string {
+	seen := make(map[string]bool)
+	var types []string
+	for _, entry := range entries {
+		if !seen[entry.Type] {
+			seen[entry.Type] = true
+			types = append(types, entry.Type)
+		}
+	}
+	return types
+}
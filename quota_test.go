@@ -0,0 +1,82 @@
+package sidb
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestQuotaReject(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_quota_reject"
+	db, err := Init(namespace, name, WithQuota("blob", 10, QuotaReject))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	if err := db.Upsert(EntryInput{Type: "blob", Key: "a", Value: []byte("12345678")}); err != nil {
+		t.Fatalf("Failed to put entry: %v", err)
+	}
+	// Replacing a value only counts its new size
+	if err := db.Upsert(EntryInput{Type: "blob", Key: "a", Value: []byte("1234567890")}); err != nil {
+		t.Fatalf("Failed to replace entry: %v", err)
+	}
+
+	err = db.Upsert(EntryInput{Type: "blob", Key: "b", Value: []byte("1")})
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("Expected ErrQuotaExceeded, got %v", err)
+	}
+	err = db.BulkUpsert([]EntryInput{{Type: "other", Key: "x", Value: []byte("1")}, {Type: "blob", Key: "c", Value: []byte("1")}})
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("Expected ErrQuotaExceeded from bulk write, got %v", err)
+	}
+	if exists, _ := db.Exists("other", "x"); exists {
+		t.Errorf("Expected the rejected bulk write to be rolled back")
+	}
+
+	usage, err := db.TypeUsage("blob")
+	if err != nil {
+		t.Fatalf("Failed to get usage: %v", err)
+	}
+	if usage != 10 {
+		t.Errorf("Expected usage 10, got %d", usage)
+	}
+
+	if err := db.Delete("blob", "a"); err != nil {
+		t.Fatalf("Failed to delete entry: %v", err)
+	}
+	if err := db.Upsert(EntryInput{Type: "blob", Key: "b", Value: []byte("1")}); err != nil {
+		t.Errorf("Expected a write to fit after freeing space, got %v", err)
+	}
+}
+
+func TestQuotaEvictOldest(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_quota_evict"
+	db, err := Init(namespace, name, WithQuota("blob", 10, QuotaEvictOldest))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	for i := 0; i < 5; i++ {
+		err := db.Upsert(EntryInput{Type: "blob", Key: fmt.Sprintf("key_%d", i), Value: []byte("1234"), Timestamp: ptr(int64(i))})
+		if err != nil {
+			t.Fatalf("Failed to put entry: %v", err)
+		}
+	}
+
+	usage, err := db.TypeUsage("blob")
+	if err != nil {
+		t.Fatalf("Failed to get usage: %v", err)
+	}
+	if usage != 8 {
+		t.Errorf("Expected usage 8 after eviction, got %d", usage)
+	}
+	for _, key := range []string{"key_3", "key_4"} {
+		if exists, _ := db.Exists("blob", key); !exists {
+			t.Errorf("Expected newest entry %s to survive", key)
+		}
+	}
+}
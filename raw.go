@@ -0,0 +1,121 @@
+package sidb
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+)
+
+// RawQuery runs arbitrary SQL against the database. Result columns are matched
+// to DbEntry fields by name, so any subset of the entries columns can be
+// selected in any order. Unknown columns are ignored.
+func (db *Database) RawQuery(query string, args ...any) ([]DbEntry, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	if db.connection == nil {
+		return nil, ErrNoDbConnection
+	}
+
+	ctx, done := db.readContext()
+	defer done()
+
+	return rawQuery(ctx, db.connection, query, args)
+}
+
+// RawQueryReadOnly is RawQuery on a connection opened read-only, so any
+// statement that would change the database fails. Use it for SQL that comes
+// from users.
+func (db *Database) RawQueryReadOnly(query string, args ...any) ([]DbEntry, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	if db.connection == nil {
+		return nil, ErrNoDbConnection
+	}
+
+	ctx, done := db.readContext()
+	defer done()
+
+	readOnly, err := sql.Open(driverName, (&url.URL{Scheme: "file", Path: db.Path, RawQuery: "mode=ro"}).String())
+	if err != nil {
+		return nil, err
+	}
+	defer readOnly.Close()
+
+	return rawQuery(ctx, readOnly, query, args)
+}
+
+func rawQuery(ctx context.Context, q querier, query string, args []any) ([]DbEntry, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []DbEntry
+	for rows.Next() {
+		var entry DbEntry
+		var subgrouping sql.NullString
+		targets := make([]any, len(columns))
+		for i, column := range columns {
+			switch column {
+			case "timestamp":
+				targets[i] = &entry.Timestamp
+			case "type":
+				targets[i] = &entry.Type
+			case "key":
+				targets[i] = &entry.Key
+			case "value":
+				targets[i] = &entry.Value
+			case "grouping":
+				targets[i] = &entry.Grouping
+			case "sortingIndex":
+				targets[i] = &entry.SortingIndex
+			case "subgrouping":
+				targets[i] = &subgrouping
+			case "version":
+				targets[i] = &entry.Version
+			case "createdAt":
+				targets[i] = &entry.CreatedAt
+			case "updatedAt":
+				targets[i] = &entry.UpdatedAt
+			case "immutable":
+				targets[i] = &entry.Immutable
+			default:
+				targets[i] = new(sql.RawBytes)
+			}
+		}
+		if err := rows.Scan(targets...); err != nil {
+			return nil, err
+		}
+		entry.Subgrouping = subgrouping.String
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (db *Database) RawExec(query string, args ...any) (sql.Result, error) {
+	defer db.afterWrite()
+
+	defer db.lockWrite(true)()
+
+	if db.connection == nil {
+		return nil, ErrNoDbConnection
+	}
+
+	ctx, done := db.writeContext()
+	defer done()
+
+	return db.connection.ExecContext(ctx, query, args...)
+}
@@ -0,0 +1,82 @@
+package sidb
+
+import "testing"
+
+func TestRawQueryAndExec(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_raw"
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	entryType := "test_type"
+	err = db.BulkUpsert([]EntryInput{
+		{Type: entryType, Value: []byte("data_1"), Key: "key_1"},
+		{Type: entryType, Value: []byte("data_2"), Key: "key_2"},
+		{Type: entryType, Value: []byte("data_3"), Key: "key_3"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to put entries: %v", err)
+	}
+
+	result, err := db.RawExec("DELETE FROM entries WHERE type = ? AND key LIKE ?", entryType, "key_1%")
+	if err != nil {
+		t.Fatalf("Failed to run raw exec: %v", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil || affected != 1 {
+		t.Fatalf("Expected 1 affected row, got %d (%v)", affected, err)
+	}
+
+	entries, err := db.RawQuery("SELECT key, length(value) AS size, value FROM entries WHERE type = ? ORDER BY key DESC", entryType)
+	if err != nil {
+		t.Fatalf("Failed to run raw query: %v", err)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].Key != "key_3" || string(entries[0].Value) != "data_3" {
+		t.Errorf("Unexpected first entry: %+v", entries[0])
+	}
+	if entries[1].Key != "key_2" || string(entries[1].Value) != "data_2" {
+		t.Errorf("Unexpected second entry: %+v", entries[1])
+	}
+}
+
+func TestRawQueryReadOnly(t *testing.T) {
+	db, err := Init([]string{"test_namespace"}, "test_raw_read_only")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	if err := db.Upsert(EntryInput{Type: "test_type", Key: "key_1", Value: []byte("data_1")}); err != nil {
+		t.Fatalf("Failed to put entry: %v", err)
+	}
+
+	entries, err := db.RawQueryReadOnly("SELECT key, value FROM entries WHERE type = ?", "test_type")
+	if err != nil {
+		t.Fatalf("Failed to run read-only query: %v", err)
+	}
+	if len(entries) != 1 || string(entries[0].Value) != "data_1" {
+		t.Errorf("Unexpected entries: %+v", entries)
+	}
+
+	for _, query := range []string{
+		"DELETE FROM entries",
+		"SELECT 1; DELETE FROM entries",
+		"PRAGMA query_only = 0; DROP TABLE entries",
+	} {
+		if _, err := db.RawQueryReadOnly(query); err == nil {
+			t.Errorf("Expected %q to fail", query)
+		}
+	}
+
+	count, err := db.Count()
+	if err != nil || count != 1 {
+		t.Errorf("Expected the entry to survive, got %d, %v", count, err)
+	}
+}
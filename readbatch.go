@@ -0,0 +1,90 @@
+package sidb
+
+import (
+	"context"
+	"database/sql"
+)
+
+// A Reader reads from the snapshot of a ReadBatch. It is only valid inside
+// the batch's function.
+type Reader struct {
+	db  *Database
+	ctx context.Context
+	tx  *sql.Tx
+}
+
+// ReadBatch calls fn with a Reader whose reads all see the same snapshot, so
+// results assembled from several queries are consistent with each other even
+// while writes commit. Writes are not blocked, but the snapshot keeps the
+// write-ahead log from being checkpointed past it, so fn should be short. The
+// whole batch counts against the read timeout.
+//
+// fn must read only through the Reader and must not call the Database: the
+// batch holds the database's read lock, so a call that waits behind Close,
+// Vacuum or NormalizeSchema deadlocks.
+func (db *Database) ReadBatch(fn func(reader *Reader) error) error {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	if db.connection == nil {
+		return ErrNoDbConnection
+	}
+
+	ctx, done := db.readContext()
+	defer done()
+
+	tx, err := db.connection.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// A transaction takes its snapshot at its first read, not at BEGIN
+	var tables int
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master").Scan(&tables); err != nil {
+		return err
+	}
+
+	return fn(&Reader{db: db, ctx: ctx, tx: tx})
+}
+
+func (reader *Reader) Get(entryType string, key string) (*DbEntry, error) {
+	stmt, err := reader.db.prepared(reader.ctx, reader.db.getSQL())
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := reader.db.scanEntry(reader.tx.StmtContext(reader.ctx, stmt).QueryRowContext(reader.ctx, entryType, key))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (reader *Reader) Exists(entryType string, key string) (bool, error) {
+	var found int
+	err := reader.tx.QueryRowContext(reader.ctx, existsSQL, entryType, key).Scan(&found)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (reader *Reader) Query(params QueryParams) ([]DbEntry, error) {
+	return reader.db.queryEntries(reader.ctx, reader.tx, params)
+}
+
+func (reader *Reader) QueryPage(params QueryParams) ([]DbEntry, string, error) {
+	entries, err := reader.Query(params)
+	if err != nil {
+		return nil, "", err
+	}
+	return entries, nextCursor(params, entries), nil
+}
+
+func (reader *Reader) CountWhere(params QueryParams) (int64, error) {
+	return countWhere(reader.ctx, reader.tx, params)
+}
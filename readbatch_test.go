@@ -0,0 +1,76 @@
+package sidb
+
+import "testing"
+
+func TestReadBatch(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_read_batch"
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	err = db.BulkUpsert([]EntryInput{
+		{Type: "task", Key: "a", Value: []byte("a"), Grouping: "inbox"},
+		{Type: "task", Key: "b", Value: []byte("b"), Grouping: "inbox"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to put entries: %v", err)
+	}
+
+	taskType := "task"
+	inbox := "inbox"
+	params := QueryParams{Type: &taskType, Grouping: &inbox}
+	err = db.ReadBatch(func(reader *Reader) error {
+		before, err := reader.CountWhere(params)
+		if err != nil {
+			return err
+		}
+
+		// Writes committed during the batch are not visible to it
+		if err := db.Upsert(EntryInput{Type: "task", Key: "c", Value: []byte("c"), Grouping: "inbox"}); err != nil {
+			return err
+		}
+		if err := db.Delete("task", "a"); err != nil {
+			return err
+		}
+
+		entries, err := reader.Query(params)
+		if err != nil {
+			return err
+		}
+		if before != 2 || len(entries) != 2 || entries[0].Key == "c" {
+			t.Errorf("Expected the snapshot taken before the writes, got %d then %+v", before, entries)
+		}
+		a, err := reader.Get("task", "a")
+		if err != nil {
+			return err
+		}
+		if a == nil || string(a.Value) != "a" {
+			t.Errorf("Expected a in the snapshot, got %+v", a)
+		}
+		exists, err := reader.Exists("task", "c")
+		if err != nil {
+			return err
+		}
+		if exists {
+			t.Errorf("Expected c to be missing from the snapshot")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed ReadBatch: %v", err)
+	}
+
+	count, err := db.CountWhere(params)
+	if err != nil {
+		t.Fatalf("Failed to count: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 entries after the batch, got %d", count)
+	}
+	if a, _ := db.Get("task", "a"); a != nil {
+		t.Errorf("Expected a to be deleted after the batch")
+	}
+}
@@ -0,0 +1,158 @@
+package sidb
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+)
+
+type RecoveryReport struct {
+	Recovered int64
+	Skipped   int64 // Entries whose key was found but whose row could not be read
+}
+
+// Recover copies every entry that can still be read into a fresh database at
+// destPath, which must not already exist. See RecoverFile.
+func (db *Database) Recover(destPath string) (*RecoveryReport, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	if db.connection == nil {
+		return nil, ErrNoDbConnection
+	}
+
+	return recoverEntries(db.connection, destPath)
+}
+
+// RecoverFile salvages entries from a database file that may be too corrupted
+// for Init to open. Keys are collected by scanning the table from both ends and
+// every index, since a scan stops at the first corrupted page, and each entry
+// is then read on its own so one bad row does not lose the rest.
+func RecoverFile(srcPath string, destPath string) (*RecoveryReport, error) {
+	if _, err := os.Stat(srcPath); err != nil {
+		return nil, err
+	}
+
+	src, err := sql.Open(driverName, srcPath)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	return recoverEntries(src, destPath)
+}
+
+func recoverEntries(src *sql.DB, destPath string) (*RecoveryReport, error) {
+	if _, err := os.Stat(destPath); err == nil {
+		return nil, fmt.Errorf("recovery destination %s already exists", destPath)
+	}
+
+	keys := salvageKeys(src)
+
+	dest, err := sql.Open(driverName, destPath)
+	if err != nil {
+		return nil, err
+	}
+	defer dest.Close()
+
+	if _, err := dest.Exec(fmt.Sprintf(entriesTableSQL, "entries")); err != nil {
+		return nil, err
+	}
+	if _, err := migrate(dest); err != nil {
+		return nil, err
+	}
+
+	tx, err := dest.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	report := &RecoveryReport{}
+	for _, key := range keys {
+		columns, values, err := readRow(src, key[0], key[1])
+		if err != nil {
+			report.Skipped++
+			continue
+		}
+
+		insert := fmt.Sprintf("INSERT INTO entries (%s) VALUES (%s)", strings.Join(columns, ", "), placeholders(len(columns)))
+		if _, err := tx.Exec(insert, values...); err != nil {
+			return nil, err
+		}
+		report.Recovered++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// salvageKeys returns the type and key of every entry reachable by any scan,
+// ignoring scans that fail part way.
+func salvageKeys(src *sql.DB) [][2]string {
+	scans := []string{
+		"SELECT type, key FROM entries ORDER BY key ASC, type ASC",
+		"SELECT type, key FROM entries ORDER BY key DESC, type DESC",
+	}
+	for _, index := range entriesIndexes {
+		scans = append(scans,
+			fmt.Sprintf("SELECT type, key FROM entries INDEXED BY %s ORDER BY %s", index.name, index.columns),
+			fmt.Sprintf("SELECT type, key FROM entries INDEXED BY %s ORDER BY %s DESC", index.name, strings.ReplaceAll(index.columns, ",", " DESC,")))
+	}
+
+	seen := make(map[[2]string]bool)
+	var keys [][2]string
+	for _, scan := range scans {
+		rows, err := src.Query(scan)
+		if err != nil {
+			continue
+		}
+		for rows.Next() {
+			var key [2]string
+			if err := rows.Scan(&key[0], &key[1]); err != nil {
+				break
+			}
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+		rows.Close()
+	}
+	return keys
+}
+
+func readRow(src *sql.DB, entryType string, key string) ([]string, []any, error) {
+	rows, err := src.Query("SELECT * FROM entries WHERE type = ? AND key = ?", entryType, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, nil, err
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, sql.ErrNoRows
+	}
+
+	values := make([]any, len(columns))
+	targets := make([]any, len(columns))
+	for i := range values {
+		targets[i] = &values[i]
+	}
+	if err := rows.Scan(targets...); err != nil {
+		return nil, nil, err
+	}
+	for i, column := range columns {
+		columns[i] = `"` + column + `"`
+	}
+	return columns, values, nil
+}
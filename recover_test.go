@@ -0,0 +1,72 @@
+package sidb
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestRecover(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	db, err := Init(namespace, "test_recover_src")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	var entries []EntryInput
+	for i := 0; i < 2000; i++ {
+		entries = append(entries, EntryInput{Type: "blob", Key: fmt.Sprintf("key_%04d", i), Value: bytes.Repeat([]byte("x"), 500)})
+	}
+	if err := db.BulkUpsert(entries); err != nil {
+		t.Fatalf("Failed to put entries: %v", err)
+	}
+
+	_, destPath, _ := databasePath(namespace, "test_recover_dest")
+	os.Remove(destPath)
+	report, err := db.Recover(destPath)
+	if err != nil {
+		t.Fatalf("Failed to recover: %v", err)
+	}
+	if report.Recovered != 2000 || report.Skipped != 0 {
+		t.Errorf("Expected every entry of a healthy database to be recovered, got %+v", report)
+	}
+	if _, err := db.Recover(destPath); err == nil {
+		t.Errorf("Expected recovering onto an existing file to fail")
+	}
+	os.Remove(destPath)
+
+	// Overwrite a few pages in the middle of the file with garbage
+	if err := db.Close(); err != nil {
+		t.Fatalf("Failed to close database: %v", err)
+	}
+	file, err := os.OpenFile(db.Path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatalf("Failed to open database file: %v", err)
+	}
+	info, _ := file.Stat()
+	file.WriteAt(bytes.Repeat([]byte{0xff}, 3*4096), info.Size()/2/4096*4096)
+	file.Close()
+
+	report, err = RecoverFile(db.Path, destPath)
+	if err != nil {
+		t.Fatalf("Failed to recover: %v", err)
+	}
+	if report.Recovered == 0 || report.Recovered >= 2000 {
+		t.Errorf("Expected part of the entries to be recovered, got %+v", report)
+	}
+
+	recovered, err := Init(namespace, "test_recover_dest")
+	if err != nil {
+		t.Fatalf("Failed to open recovered database: %v", err)
+	}
+	defer recovered.Drop()
+	count, err := recovered.Count()
+	if err != nil {
+		t.Fatalf("Failed to count entries: %v", err)
+	}
+	if count != report.Recovered {
+		t.Errorf("Expected %d recovered entries, got %d", report.Recovered, count)
+	}
+}
@@ -0,0 +1,91 @@
+package sidb
+
+// Reference counts are kept as one row per owner, so adding the same owner
+// twice counts once and a crashed owner can be released by name.
+const refsTableSQL = `CREATE TABLE IF NOT EXISTS refs (
+	"type" TEXT NOT NULL,
+	"key" TEXT NOT NULL,
+	"owner" TEXT NOT NULL,
+	PRIMARY KEY ("type", "key", "owner")
+) WITHOUT ROWID`
+
+// AddRef records that owner references the entry. It is a no-op if owner
+// already does.
+func (db *Database) AddRef(entryType string, key string, owner string) error {
+	defer db.lockWrite(false, entryType)()
+
+	if db.connection == nil {
+		return ErrNoDbConnection
+	}
+
+	ctx, done := db.writeContext()
+	defer done()
+
+	_, err := db.connection.ExecContext(ctx, "INSERT OR IGNORE INTO refs (type, key, owner) VALUES (?, ?, ?)", entryType, key, owner)
+	return err
+}
+
+// Release drops owner's reference to the entry and deletes the entry once no
+// references are left. It reports whether the entry was deleted.
+func (db *Database) Release(entryType string, key string, owner string) (deleted bool, err error) {
+	defer translateImmutable(&err)
+
+	defer db.lockWrite(false, entryType)()
+
+	if db.connection == nil {
+		return false, ErrNoDbConnection
+	}
+
+	ctx, done := db.writeContext()
+	defer done()
+
+	tx, err := db.connection.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM refs WHERE type = ? AND key = ? AND owner = ?", entryType, key, owner)
+	if err != nil {
+		return false, err
+	}
+	released, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if released == 0 {
+		return false, nil
+	}
+
+	var remaining int64
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM refs WHERE type = ? AND key = ?", entryType, key).Scan(&remaining); err != nil {
+		return false, err
+	}
+	if remaining == 0 {
+		query, args := db.deleteStatement("key = ? AND type = ?", []interface{}{key, entryType})
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return false, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	return remaining == 0, nil
+}
+
+func (db *Database) RefCount(entryType string, key string) (int64, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	if db.connection == nil {
+		return 0, ErrNoDbConnection
+	}
+
+	ctx, done := db.readContext()
+	defer done()
+
+	var count int64
+	err := db.connection.QueryRowContext(ctx, "SELECT COUNT(*) FROM refs WHERE type = ? AND key = ?", entryType, key).Scan(&count)
+	return count, err
+}
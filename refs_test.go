@@ -0,0 +1,60 @@
+package sidb
+
+import "testing"
+
+func TestRefs(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_refs"
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	entryType := "images"
+	if err := db.Upsert(EntryInput{Type: entryType, Value: []byte("pixels"), Key: "image"}); err != nil {
+		t.Fatalf("Failed to put entry: %v", err)
+	}
+	for _, owner := range []string{"note_1", "note_2", "note_2"} {
+		if err := db.AddRef(entryType, "image", owner); err != nil {
+			t.Fatalf("Failed to add ref: %v", err)
+		}
+	}
+
+	count, err := db.RefCount(entryType, "image")
+	if err != nil {
+		t.Fatalf("Failed to count refs: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 refs, got %d", count)
+	}
+
+	deleted, err := db.Release(entryType, "image", "note_1")
+	if err != nil {
+		t.Fatalf("Failed to release: %v", err)
+	}
+	if deleted {
+		t.Errorf("Expected the entry to survive while note_2 references it")
+	}
+
+	// Releasing an owner that holds no reference changes nothing
+	if deleted, err := db.Release(entryType, "image", "note_1"); err != nil || deleted {
+		t.Errorf("Expected a repeated release to be a no-op, got deleted=%v err=%v", deleted, err)
+	}
+
+	deleted, err = db.Release(entryType, "image", "note_2")
+	if err != nil {
+		t.Fatalf("Failed to release: %v", err)
+	}
+	if !deleted {
+		t.Errorf("Expected the entry to be deleted with its last ref")
+	}
+
+	exists, err := db.Exists(entryType, "image")
+	if err != nil {
+		t.Fatalf("Failed to check entry: %v", err)
+	}
+	if exists {
+		t.Errorf("Expected the entry to be gone")
+	}
+}
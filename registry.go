@@ -0,0 +1,117 @@
+package sidb
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Registry lists the databases stored under a root directory.
+type Registry struct {
+	Root string
+	opts []Option
+}
+
+type DatabaseInfo struct {
+	Namespace []string
+	Name      string
+	Path      string
+	Size      int64 // Including the write-ahead log
+	ModTime   time.Time
+}
+
+// NewRegistry returns a Registry over RootPath, or the WithBaseDir directory
+// if given. opts are also used by Registry.Open.
+func NewRegistry(opts ...Option) (*Registry, error) {
+	var options Options
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	root := options.baseDir
+	if root == "" {
+		var err error
+		if root, err = RootPath(); err != nil {
+			return nil, err
+		}
+	}
+	return &Registry{Root: root, opts: opts}, nil
+}
+
+// Databases walks the root directory and returns every database in it,
+// ordered by namespace and then name. A missing root directory has no databases.
+func (registry *Registry) Databases() ([]DatabaseInfo, error) {
+	var databases []DatabaseInfo
+	err := fs.WalkDir(os.DirFS(registry.Root), ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if name == "." && os.IsNotExist(err) {
+				return fs.SkipAll
+			}
+			return err
+		}
+		if entry.IsDir() || !strings.HasSuffix(name, ".db") {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		dbPath := path.Join(registry.Root, name)
+		size := info.Size()
+		if wal, err := os.Stat(dbPath + "-wal"); err == nil {
+			size += wal.Size()
+		}
+
+		var namespace []string
+		if dir := path.Dir(name); dir != "." {
+			namespace = strings.Split(dir, "/")
+		}
+		databases = append(databases, DatabaseInfo{
+			Namespace: namespace,
+			Name:      strings.TrimSuffix(path.Base(name), ".db"),
+			Path:      dbPath,
+			Size:      size,
+			ModTime:   info.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(databases, func(a, b DatabaseInfo) int {
+		if order := slices.Compare(a.Namespace, b.Namespace); order != 0 {
+			return order
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	return databases, nil
+}
+
+// Namespaces returns the namespaces holding at least one database.
+func (registry *Registry) Namespaces() ([][]string, error) {
+	databases, err := registry.Databases()
+	if err != nil {
+		return nil, err
+	}
+
+	var namespaces [][]string
+	for _, database := range databases {
+		if !slices.ContainsFunc(namespaces, func(namespace []string) bool {
+			return slices.Equal(namespace, database.Namespace)
+		}) {
+			namespaces = append(namespaces, database.Namespace)
+		}
+	}
+	return namespaces, nil
+}
+
+// Open opens an existing database in the registry, failing with
+// ErrDatabaseNotFound rather than creating it.
+func (registry *Registry) Open(namespace []string, name string, opts ...Option) (*Database, error) {
+	opts = append(slices.Clone(registry.opts), opts...)
+	return Open(namespace, name, append(opts, WithBaseDir(registry.Root))...)
+}
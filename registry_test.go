@@ -0,0 +1,83 @@
+package sidb
+
+import (
+	"errors"
+	"path"
+	"slices"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	root := t.TempDir()
+	for _, database := range []struct {
+		namespace []string
+		name      string
+	}{
+		{[]string{"app"}, "notes"},
+		{[]string{"app", "cache"}, "pages"},
+		{nil, "top"},
+	} {
+		db, err := Init(database.namespace, database.name, WithBaseDir(root))
+		if err != nil {
+			t.Fatalf("Failed to initialize database: %v", err)
+		}
+		if err := db.Upsert(EntryInput{Type: "note", Key: "a", Value: []byte("a")}); err != nil {
+			t.Fatalf("Failed to upsert: %v", err)
+		}
+		db.Close()
+	}
+
+	registry, err := NewRegistry(WithBaseDir(root))
+	if err != nil {
+		t.Fatalf("Failed to create registry: %v", err)
+	}
+	databases, err := registry.Databases()
+	if err != nil {
+		t.Fatalf("Failed to list databases: %v", err)
+	}
+	if len(databases) != 3 {
+		t.Fatalf("Expected 3 databases, got %d", len(databases))
+	}
+	notes := databases[1]
+	if !slices.Equal(notes.Namespace, []string{"app"}) || notes.Name != "notes" || notes.Path != path.Join(root, "app", "notes.db") {
+		t.Errorf("Unexpected database info: %+v", notes)
+	}
+	if notes.Size == 0 || notes.ModTime.IsZero() {
+		t.Errorf("Expected size and modified time, got %+v", notes)
+	}
+	if databases[0].Namespace != nil || databases[0].Name != "top" {
+		t.Errorf("Unexpected database info: %+v", databases[0])
+	}
+
+	namespaces, err := registry.Namespaces()
+	if err != nil {
+		t.Fatalf("Failed to list namespaces: %v", err)
+	}
+	if len(namespaces) != 3 || !slices.Equal(namespaces[2], []string{"app", "cache"}) {
+		t.Errorf("Unexpected namespaces: %v", namespaces)
+	}
+
+	db, err := registry.Open([]string{"app", "cache"}, "pages")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	if entry, err := db.Get("note", "a"); err != nil || entry == nil {
+		t.Errorf("Expected the stored entry, got %v, %v", entry, err)
+	}
+
+	if _, err := registry.Open([]string{"app"}, "missing"); !errors.Is(err, ErrDatabaseNotFound) {
+		t.Errorf("Expected ErrDatabaseNotFound, got %v", err)
+	}
+}
+
+func TestRegistryMissingRoot(t *testing.T) {
+	registry, err := NewRegistry(WithBaseDir(path.Join(t.TempDir(), "missing")))
+	if err != nil {
+		t.Fatalf("Failed to create registry: %v", err)
+	}
+	databases, err := registry.Databases()
+	if err != nil || len(databases) != 0 {
+		t.Errorf("Expected no databases, got %v, %v", databases, err)
+	}
+}
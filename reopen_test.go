@@ -0,0 +1,52 @@
+package sidb
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReopen(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_reopen"
+	observer := newRecordingObserver()
+	db, err := Init(namespace, name, WithLifecycleObserver(observer), WithRetention(RetentionRule{Type: "log", MaxAge: time.Hour}), WithRetentionInterval(time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	if err := db.Upsert(EntryInput{Type: "note", Key: "a", Value: []byte("a")}); err != nil {
+		t.Fatalf("Failed to upsert: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+	if _, err := db.Get("note", "a"); err != ErrNoDbConnection {
+		t.Fatalf("Expected ErrNoDbConnection while closed, got %v", err)
+	}
+
+	if err := db.Reopen(); err != nil {
+		t.Fatalf("Failed to reopen: %v", err)
+	}
+	entry, err := db.Get("note", "a")
+	if err != nil {
+		t.Fatalf("Failed to get after reopening: %v", err)
+	}
+	if entry == nil || string(entry.Value) != "a" {
+		t.Errorf("Unexpected entry: %+v", entry)
+	}
+
+	// Reopening an open database does nothing, and it can be closed again
+	if err := db.Reopen(); err != nil {
+		t.Fatalf("Failed to reopen: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+
+	expected := "opened closed opened closed"
+	if kinds := strings.Join(observer.kinds(), " "); kinds != expected {
+		t.Errorf("Expected events %q, got %q", expected, kinds)
+	}
+}
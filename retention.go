@@ -0,0 +1,110 @@
+package sidb
+
+import (
+	"slices"
+	"time"
+)
+
+// A RetentionRule deletes the entries of a type that are older than MaxAge or
+// beyond the newest MaxPerGrouping of their grouping. Zero limits are off.
+// Immutable entries are never deleted, and do not count towards
+// MaxPerGrouping.
+type RetentionRule struct {
+	Type           string
+	MaxAge         time.Duration
+	MaxPerGrouping int
+}
+
+// WithRetention adds a rule applied by ApplyRetention.
+func WithRetention(rule RetentionRule) Option {
+	return func(options *Options) {
+		options.retention = append(options.retention, rule)
+	}
+}
+
+// WithRetentionInterval applies the retention rules every interval in the
+// background, from Init until Close.
+func WithRetentionInterval(interval time.Duration) Option {
+	return func(options *Options) {
+		options.retentionInterval = interval
+	}
+}
+
+// ApplyRetention deletes the entries the retention rules no longer keep and
+// returns how many were deleted. With WithSoftDelete they are soft deleted.
+func (db *Database) ApplyRetention() (deleted int64, err error) {
+	var entryTypes []string
+	for _, rule := range db.options.retention {
+		if !slices.Contains(entryTypes, rule.Type) {
+			entryTypes = append(entryTypes, rule.Type)
+		}
+	}
+	defer db.lockWrite(true, entryTypes...)()
+
+	if db.connection == nil {
+		return 0, ErrNoDbConnection
+	}
+
+	ctx, done := db.writeContext()
+	defer done()
+
+	tx, err := db.connection.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	for _, rule := range db.options.retention {
+		var statements []string
+		var statementArgs [][]interface{}
+
+		if rule.MaxAge > 0 {
+			query, args := db.deleteStatement("type = ? AND immutable = 0 AND timestamp < ?",
+				[]interface{}{rule.Type, db.now() - rule.MaxAge.Milliseconds()})
+			statements = append(statements, query)
+			statementArgs = append(statementArgs, args)
+		}
+		if rule.MaxPerGrouping > 0 {
+			query, args := db.deleteStatement(`type = ? AND key IN (
+				SELECT key FROM (
+					SELECT key, ROW_NUMBER() OVER (PARTITION BY COALESCE(grouping, '') ORDER BY timestamp DESC, key DESC) AS rank
+					FROM entries WHERE type = ? AND deletedAt IS NULL AND immutable = 0
+				) WHERE rank > ?)`, []interface{}{rule.Type, rule.Type, rule.MaxPerGrouping})
+			statements = append(statements, query)
+			statementArgs = append(statementArgs, args)
+		}
+
+		for i, query := range statements {
+			result, err := tx.ExecContext(ctx, query, statementArgs[i]...)
+			if err != nil {
+				return 0, err
+			}
+			affected, err := result.RowsAffected()
+			if err != nil {
+				return 0, err
+			}
+			deleted += affected
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return deleted, nil
+}
+
+// runRetention applies the retention rules every interval until stop is closed.
+// Errors are ignored, the next run tries again.
+func (db *Database) runRetention(interval time.Duration, stop chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			db.ApplyRetention()
+		case <-stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package sidb
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestApplyRetention(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_retention"
+	now := int64(100 * 24 * time.Hour / time.Millisecond)
+	db, err := Init(namespace, name,
+		WithClock(func() int64 { return now }),
+		WithRetention(RetentionRule{Type: "log", MaxAge: 30 * 24 * time.Hour}),
+		WithRetention(RetentionRule{Type: "event", MaxPerGrouping: 2}))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	day := int64(24 * time.Hour / time.Millisecond)
+	var entries []EntryInput
+	for i := int64(0); i < 4; i++ {
+		entries = append(entries, EntryInput{Type: "log", Key: fmt.Sprintf("log_%d", i), Value: []byte("data"), Timestamp: ptr(now - i*20*day)})
+		for _, grouping := range []string{"a", "b"} {
+			entries = append(entries, EntryInput{Type: "event", Key: fmt.Sprintf("%s_%d", grouping, i), Grouping: grouping, Value: []byte("data"), Timestamp: ptr(i)})
+		}
+	}
+	entries = append(entries, EntryInput{Type: "log", Key: "pinned", Value: []byte("data"), Timestamp: ptr(int64(0)), Immutable: true})
+	if err := db.BulkUpsert(entries); err != nil {
+		t.Fatalf("Failed to put entries: %v", err)
+	}
+
+	deleted, err := db.ApplyRetention()
+	if err != nil {
+		t.Fatalf("Failed to apply retention: %v", err)
+	}
+	// log_2 and log_3 are too old, and two events of each grouping are beyond the newest two
+	if deleted != 6 {
+		t.Errorf("Expected 6 deleted entries, got %d", deleted)
+	}
+
+	for _, key := range []string{"log_0", "log_1", "pinned"} {
+		if exists, _ := db.Exists("log", key); !exists {
+			t.Errorf("Expected %s to be kept", key)
+		}
+	}
+	for _, key := range []string{"a_2", "a_3", "b_2", "b_3"} {
+		if exists, _ := db.Exists("event", key); !exists {
+			t.Errorf("Expected %s to be kept", key)
+		}
+	}
+}
+
+func TestRetentionInterval(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_retention_interval"
+	db, err := Init(namespace, name,
+		WithRetention(RetentionRule{Type: "log", MaxAge: time.Hour}),
+		WithRetentionInterval(10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	if err := db.Upsert(EntryInput{Type: "log", Key: "old", Value: []byte("data"), Timestamp: ptr(int64(0))}); err != nil {
+		t.Fatalf("Failed to put entry: %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		exists, err := db.Exists("log", "old")
+		if err != nil {
+			t.Fatalf("Failed to check entry: %v", err)
+		}
+		if !exists {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the background retention to delete the old entry")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
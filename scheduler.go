@@ -0,0 +1,98 @@
+package sidb
+
+import "sync"
+
+// With WithWriteConcurrency, writes pass through a scheduler before taking the
+// database lock. Single entry writes are let through first: a bulk write waits
+// while any are queued, so interactive writes are not stuck behind a burst of
+// bulk imports. Bulk writes to one type are also limited to a number in flight,
+// so a burst on one type cannot crowd out bulk writes to the others.
+type writeScheduler struct {
+	limit        int
+	mutex        sync.Mutex
+	changed      *sync.Cond
+	pendingSmall int
+	bulkInFlight map[string]int
+}
+
+func newWriteScheduler(limit int) *writeScheduler {
+	scheduler := &writeScheduler{limit: limit, bulkInFlight: make(map[string]int)}
+	scheduler.changed = sync.NewCond(&scheduler.mutex)
+	return scheduler
+}
+
+// lockWriter takes the write lock and returns the function releasing it.
+// Writes are serialized in process, so they never wait on SQLite's busy
+// timeout, while reads only hold db.mutex for reading and are not blocked by
+// writes: with WAL they read the last committed state on another connection.
+// Only Close and a few operations that need the database to themselves, like
+// Vacuum, take db.mutex for writing.
+func (db *Database) lockWriter() func() {
+	db.mutex.RLock()
+	db.writeMutex.Lock()
+	return func() {
+		db.writeMutex.Unlock()
+		db.mutex.RUnlock()
+	}
+}
+
+// lockWrite takes the database write lock for a write to entryTypes and
+// returns the function releasing it. A bulk write waits until every type it
+// touches has room for it. Bulk writes whose types are not known up front,
+// like raw statements, are limited as one more type.
+func (db *Database) lockWrite(bulk bool, entryTypes ...string) func() {
+	scheduler := db.scheduler
+	if scheduler == nil {
+		return db.lockWriter()
+	}
+
+	if !bulk {
+		scheduler.mutex.Lock()
+		scheduler.pendingSmall++
+		scheduler.mutex.Unlock()
+
+		unlock := db.lockWriter()
+
+		scheduler.mutex.Lock()
+		scheduler.pendingSmall--
+		scheduler.changed.Broadcast()
+		scheduler.mutex.Unlock()
+		return unlock
+	}
+
+	if len(entryTypes) == 0 {
+		entryTypes = []string{""}
+	}
+
+	scheduler.mutex.Lock()
+	for scheduler.pendingSmall > 0 || scheduler.full(entryTypes) {
+		scheduler.changed.Wait()
+	}
+	for _, entryType := range entryTypes {
+		scheduler.bulkInFlight[entryType]++
+	}
+	scheduler.mutex.Unlock()
+
+	unlock := db.lockWriter()
+	return func() {
+		unlock()
+
+		scheduler.mutex.Lock()
+		for _, entryType := range entryTypes {
+			scheduler.bulkInFlight[entryType]--
+		}
+		scheduler.changed.Broadcast()
+		scheduler.mutex.Unlock()
+	}
+}
+
+// full reports whether any of entryTypes has the limit of bulk writes in
+// flight. It is called with scheduler.mutex held.
+func (scheduler *writeScheduler) full(entryTypes []string) bool {
+	for _, entryType := range entryTypes {
+		if scheduler.bulkInFlight[entryType] >= scheduler.limit {
+			return true
+		}
+	}
+	return false
+}
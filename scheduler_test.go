@@ -0,0 +1,98 @@
+package sidb
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWriteConcurrencyPrioritizesSingleWrites(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_scheduler"
+	db, err := Init(namespace, name, WithWriteConcurrency(1))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	entryType := "test_type"
+
+	// Hold the lock so both writes queue up behind it
+	db.mutex.Lock()
+
+	done := make(chan error, 2)
+	go func() {
+		done <- db.Upsert(EntryInput{Type: entryType, Value: []byte("single"), Key: "key"})
+	}()
+	for {
+		db.scheduler.mutex.Lock()
+		pending := db.scheduler.pendingSmall
+		db.scheduler.mutex.Unlock()
+		if pending == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	go func() {
+		done <- db.BulkUpsert([]EntryInput{{Type: entryType, Value: []byte("bulk"), Key: "key"}})
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	db.mutex.Unlock()
+	for i := 0; i < 2; i++ {
+		if err := <-done; err != nil {
+			t.Fatalf("Failed to write: %v", err)
+		}
+	}
+
+	// The bulk write arrived while the single write was queued, so it ran last
+	entry, err := db.Get(entryType, "key")
+	if err != nil {
+		t.Fatalf("Failed to get entry: %v", err)
+	}
+	if entry == nil || string(entry.Value) != "bulk" {
+		t.Errorf("Expected the bulk write to land last, got %+v", entry)
+	}
+}
+
+func TestWriteConcurrencyCountsEveryType(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_scheduler_types"
+	db, err := Init(namespace, name, WithWriteConcurrency(1))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	// Take the only bulk slot of one type, as a long bulk write would
+	unlock := db.lockWrite(true, "second")
+
+	done := make(chan error, 2)
+	go func() {
+		done <- db.BulkUpsert([]EntryInput{
+			{Type: "first", Key: "a", Value: []byte("a")},
+			{Type: "second", Key: "b", Value: []byte("b")},
+		})
+	}()
+	go func() {
+		_, err := db.DeleteWhere(QueryParams{Type: ptr("second")})
+		done <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	// Both wait for the slot of the type they share instead of counting
+	// against the other one
+	db.scheduler.mutex.Lock()
+	inFlight := db.scheduler.bulkInFlight["first"]
+	db.scheduler.mutex.Unlock()
+	if inFlight != 0 {
+		t.Errorf("Expected the mixed batch to wait for the second type, got %d in flight for the first", inFlight)
+	}
+
+	unlock()
+	for i := 0; i < 2; i++ {
+		if err := <-done; err != nil {
+			t.Fatalf("Failed to write: %v", err)
+		}
+	}
+}
@@ -0,0 +1,289 @@
+package sidb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// entriesTableSQL creates the entries table under the given name. Indexes are
+// created by migrate, once any added columns they cover exist.
+const entriesTableSQL = `CREATE TABLE IF NOT EXISTS %s (
+	"key" TEXT NOT NULL,
+	"type" TEXT NOT NULL,
+	"timestamp" INTEGER NOT NULL,
+	"grouping" TEXT,
+	"sortingIndex" INTEGER,
+	"value" BLOB,
+	"subgrouping" TEXT,
+	"signature" BLOB,
+	"version" INTEGER NOT NULL DEFAULT 1,
+	"deletedAt" INTEGER,
+	"createdAt" INTEGER,
+	"updatedAt" INTEGER,
+	"projection" BLOB,
+	"immutable" INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY ("key", "type")
+) WITHOUT ROWID`
+
+var entriesIndexes = []struct {
+	name    string
+	columns string
+}{
+	{"idx_entries_key", "type"},
+	{"idx_entries_grouping", "type, grouping"},
+	{"idx_entries_sorting_index", "type, sortingIndex"},
+	{"idx_entries_timestamp", "type, timestamp"},
+	{"idx_entries_subgrouping", "type, grouping, subgrouping"},
+}
+
+func indexesSQL() string {
+	var statements []string
+	for _, index := range entriesIndexes {
+		statements = append(statements, fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON entries(%s);", index.name, index.columns))
+	}
+	return strings.Join(statements, "\n")
+}
+
+// Columns added after the original schema. Init adds any that are missing so
+// databases created by older versions keep working.
+var addedColumns = []struct {
+	name       string
+	definition string
+	backfill   string // Expression existing rows are set to, if any
+}{
+	{"subgrouping", "TEXT", ""},
+	{"signature", "BLOB", ""},
+	{"version", "INTEGER NOT NULL DEFAULT 1", ""},
+	{"deletedAt", "INTEGER", ""},
+	{"createdAt", "INTEGER", "timestamp"},
+	{"updatedAt", "INTEGER", "timestamp"},
+	{"projection", "BLOB", ""},
+	{"immutable", "INTEGER NOT NULL DEFAULT 0", ""},
+}
+
+// migrate brings the schema up to date and returns the columns it added.
+func migrate(connection *sql.DB) ([]string, error) {
+	rows, err := connection.Query("SELECT name FROM pragma_table_info('entries')")
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		existing[name] = true
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var added []string
+	for _, column := range addedColumns {
+		if existing[column.name] {
+			continue
+		}
+		if _, err := connection.Exec(fmt.Sprintf(`ALTER TABLE entries ADD COLUMN "%s" %s`, column.name, column.definition)); err != nil {
+			return nil, err
+		}
+		if column.backfill != "" {
+			if _, err := connection.Exec(fmt.Sprintf(`UPDATE entries SET "%s" = %s`, column.name, column.backfill)); err != nil {
+				return nil, err
+			}
+		}
+		added = append(added, column.name)
+	}
+
+	if _, err := connection.Exec(indexesSQL() + immutableTriggersSQL); err != nil {
+		return nil, err
+	}
+
+	for _, table := range []string{locksTableSQL, refsTableSQL, templatesTableSQL, countsTableSQL, merkleTablesSQL} {
+		if _, err := connection.Exec(table); err != nil {
+			return nil, err
+		}
+	}
+	if err := migrateLocks(connection); err != nil {
+		return nil, err
+	}
+	return added, nil
+}
+
+// SchemaReport describes how the entries table differs from the schema this
+// version creates. Mismatched columns are described as "name: found, expected".
+type SchemaReport struct {
+	MissingColumns    []string
+	MismatchedColumns []string
+	ExtraColumns      []string
+	MissingIndexes    []string
+	RowidTable        bool
+	Rebuilt           bool
+}
+
+func (report *SchemaReport) Drifted() bool {
+	return len(report.MissingColumns)+len(report.MismatchedColumns)+len(report.MissingIndexes) > 0 || report.RowidTable
+}
+
+type columnInfo struct {
+	name       string
+	columnType string
+	notNull    bool
+	pk         int
+}
+
+func (column columnInfo) String() string {
+	description := column.columnType
+	if column.notNull {
+		description += " NOT NULL"
+	}
+	if column.pk > 0 {
+		description += fmt.Sprintf(" PK %d", column.pk)
+	}
+	return description
+}
+
+func tableColumns(ctx context.Context, tx *sql.Tx, table string) ([]columnInfo, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT name, type, "notnull", pk FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []columnInfo
+	for rows.Next() {
+		var column columnInfo
+		if err := rows.Scan(&column.name, &column.columnType, &column.notNull, &column.pk); err != nil {
+			return nil, err
+		}
+		column.columnType = strings.ToUpper(column.columnType)
+		columns = append(columns, column)
+	}
+	return columns, rows.Err()
+}
+
+// NormalizeSchema compares the entries table with the current schema and,
+// unless dryRun is set, rebuilds it into that schema when they differ. The
+// rebuild copies every row inside one transaction, converting values to the
+// expected column types, and keeps columns this package does not know about.
+func (db *Database) NormalizeSchema(dryRun bool) (report *SchemaReport, err error) {
+	started := time.Now()
+	defer func() {
+		if err == nil && report.Rebuilt {
+			db.emit(EventMigrated, started, "rebuilt the entries table")
+		}
+	}()
+
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if db.connection == nil {
+		return nil, ErrNoDbConnection
+	}
+
+	ctx, done := db.writeContext()
+	defer done()
+
+	tx, err := db.connection.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// The rebuild target doubles as the reference for the expected columns
+	if _, err := tx.ExecContext(ctx, "DROP TABLE IF EXISTS entries_rebuild"); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(entriesTableSQL, "entries_rebuild")); err != nil {
+		return nil, err
+	}
+
+	expected, err := tableColumns(ctx, tx, "entries_rebuild")
+	if err != nil {
+		return nil, err
+	}
+	found, err := tableColumns(ctx, tx, "entries")
+	if err != nil {
+		return nil, err
+	}
+
+	report = &SchemaReport{}
+	foundByName := make(map[string]columnInfo)
+	for _, column := range found {
+		foundByName[column.name] = column
+	}
+	expectedNames := make(map[string]bool)
+	var copied []string
+	for _, column := range expected {
+		expectedNames[column.name] = true
+		actual, ok := foundByName[column.name]
+		if !ok {
+			report.MissingColumns = append(report.MissingColumns, column.name)
+			continue
+		}
+		copied = append(copied, `"`+column.name+`"`)
+		if actual != column {
+			report.MismatchedColumns = append(report.MismatchedColumns, fmt.Sprintf("%s: %s, expected %s", column.name, actual, column))
+		}
+	}
+	for _, column := range found {
+		if !expectedNames[column.name] {
+			report.ExtraColumns = append(report.ExtraColumns, column.name)
+		}
+	}
+
+	for _, index := range entriesIndexes {
+		var exists int
+		err := tx.QueryRowContext(ctx, "SELECT 1 FROM sqlite_master WHERE type = 'index' AND name = ? AND tbl_name = 'entries'", index.name).Scan(&exists)
+		if err == sql.ErrNoRows {
+			report.MissingIndexes = append(report.MissingIndexes, index.name)
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	var withoutRowid bool
+	if err := tx.QueryRowContext(ctx, "SELECT wr FROM pragma_table_list WHERE schema = 'main' AND name = 'entries'").Scan(&withoutRowid); err != nil {
+		return nil, err
+	}
+	report.RowidTable = !withoutRowid
+
+	if dryRun || !report.Drifted() {
+		return report, nil
+	}
+
+	for _, name := range report.ExtraColumns {
+		column := foundByName[name]
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`ALTER TABLE entries_rebuild ADD COLUMN "%s" %s`, name, column.columnType)); err != nil {
+			return nil, err
+		}
+		copied = append(copied, `"`+name+`"`)
+	}
+
+	columns := strings.Join(copied, ", ")
+	statements := []string{
+		fmt.Sprintf("INSERT INTO entries_rebuild (%s) SELECT %s FROM entries", columns, columns),
+		"DROP TABLE entries",
+		"ALTER TABLE entries_rebuild RENAME TO entries",
+		indexesSQL(),
+		immutableTriggersSQL,
+	}
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	report.Rebuilt = true
+	return report, nil
+}
@@ -0,0 +1,141 @@
+package sidb
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+)
+
+func TestInitMigratesOlderSchema(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_migration"
+	dirPath, dbPath, _ := databasePath(namespace, name)
+	if err := os.MkdirAll(dirPath, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	os.Remove(dbPath)
+
+	// The schema as created by the first release
+	connection, err := sql.Open(driverName, dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	_, err = connection.Exec(`CREATE TABLE entries (
+		"key" TEXT NOT NULL,
+		"type" TEXT NOT NULL,
+		"timestamp" INTEGER NOT NULL,
+		"grouping" TEXT,
+		"sortingIndex" INTEGER,
+		"value" BLOB,
+		PRIMARY KEY ("key", "type")
+	) WITHOUT ROWID;
+	INSERT INTO entries (key, type, timestamp, grouping, value) VALUES ('key_1', 'test_type', 1, '', 'data_1');`)
+	connection.Close()
+	if err != nil {
+		t.Fatalf("Failed to create old schema: %v", err)
+	}
+
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	entry, err := db.Get("test_type", "key_1")
+	if err != nil {
+		t.Fatalf("Failed to get migrated entry: %v", err)
+	}
+	if entry == nil || string(entry.Value) != "data_1" || entry.Subgrouping != "" || entry.CreatedAt != 1 {
+		t.Fatalf("Unexpected migrated entry: %+v", entry)
+	}
+
+	err = db.Upsert(EntryInput{Type: "test_type", Value: []byte("data_2"), Key: "key_2", Subgrouping: "sub"})
+	if err != nil {
+		t.Fatalf("Failed to put entry after migration: %v", err)
+	}
+}
+
+func TestNormalizeSchema(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_normalize"
+	dirPath, dbPath, _ := databasePath(namespace, name)
+	if err := os.MkdirAll(dirPath, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	os.Remove(dbPath)
+
+	// A hand edited table: rowid, no primary key, text timestamps and an extra column
+	connection, err := sql.Open(driverName, dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	_, err = connection.Exec(`CREATE TABLE entries (
+		"key" TEXT NOT NULL,
+		"type" TEXT NOT NULL,
+		"timestamp" TEXT NOT NULL,
+		"grouping" TEXT,
+		"sortingIndex" INTEGER,
+		"value" BLOB,
+		"note" TEXT
+	);
+	INSERT INTO entries (key, type, timestamp, grouping, value, note) VALUES ('key_1', 'test_type', '5', '', 'data_1', 'kept');`)
+	connection.Close()
+	if err != nil {
+		t.Fatalf("Failed to create drifted schema: %v", err)
+	}
+
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	report, err := db.NormalizeSchema(true)
+	if err != nil {
+		t.Fatalf("Failed to check schema: %v", err)
+	}
+	if !report.Drifted() || report.Rebuilt || !report.RowidTable {
+		t.Errorf("Expected a drifted rowid table without rebuild, got %+v", report)
+	}
+	if len(report.MismatchedColumns) != 3 {
+		t.Errorf("Expected key, type and timestamp to mismatch, got %v", report.MismatchedColumns)
+	}
+	if len(report.ExtraColumns) != 1 || report.ExtraColumns[0] != "note" {
+		t.Errorf("Expected extra column note, got %v", report.ExtraColumns)
+	}
+
+	report, err = db.NormalizeSchema(false)
+	if err != nil {
+		t.Fatalf("Failed to normalize schema: %v", err)
+	}
+	if !report.Rebuilt {
+		t.Errorf("Expected the table to be rebuilt")
+	}
+
+	entry, err := db.Get("test_type", "key_1")
+	if err != nil {
+		t.Fatalf("Failed to get entry after rebuild: %v", err)
+	}
+	if entry == nil || entry.Timestamp != 5 || string(entry.Value) != "data_1" {
+		t.Fatalf("Unexpected entry after rebuild: %+v", entry)
+	}
+	var note string
+	if err := db.connection.QueryRow("SELECT note FROM entries").Scan(&note); err != nil {
+		t.Fatalf("Failed to read extra column: %v", err)
+	}
+	if note != "kept" {
+		t.Errorf("Expected the extra column to survive, got %q", note)
+	}
+
+	if err := db.Upsert(EntryInput{Type: "test_type", Value: []byte("data_2"), Key: "key_1"}); err != nil {
+		t.Fatalf("Failed to upsert after rebuild: %v", err)
+	}
+
+	report, err = db.NormalizeSchema(false)
+	if err != nil {
+		t.Fatalf("Failed to check schema: %v", err)
+	}
+	if report.Drifted() || report.Rebuilt {
+		t.Errorf("Expected a normalized schema, got %+v", report)
+	}
+}
@@ -0,0 +1,30 @@
+package sidb
+
+import "fmt"
+
+// SerializationError is returned by Store operations when the store's
+// serialize, deserialize or projection function fails. It names the entry, so
+// the bad input of a bulk write or the bad row of a query can be found.
+type SerializationError struct {
+	Type  string
+	Key   string
+	Op    string // "serialize", "deserialize" or "project"
+	Cause error
+}
+
+func (err *SerializationError) Error() string {
+	return fmt.Sprintf("cannot %s %s entry %q: %v", err.Op, err.Type, err.Key, err.Cause)
+}
+
+func (err *SerializationError) Unwrap() error {
+	return err.Cause
+}
+
+// decode deserializes the value of the entry at key.
+func (store *Store[T]) decode(key string, data []byte) (T, error) {
+	value, err := store.deserialize(data)
+	if err != nil {
+		return value, &SerializationError{Type: store.entryType, Key: key, Op: "deserialize", Cause: err}
+	}
+	return value, nil
+}
@@ -0,0 +1,58 @@
+package sidb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+var errNegative = errors.New("negative values are not allowed")
+
+func TestSerializationError(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_serialization_error"
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	serialize := func(item testItem) ([]byte, error) {
+		if item.Value < 0 {
+			return nil, errNegative
+		}
+		return json.Marshal(item)
+	}
+	store := MakeStore(db, "test_type", serialize, deserializeTestItem, nil)
+
+	var inputs []StoreEntryInput[testItem]
+	for i := 0; i < 1000; i++ {
+		inputs = append(inputs, StoreEntryInput[testItem]{Key: fmt.Sprintf("key_%d", i), Value: testItem{Value: i}})
+	}
+	inputs[734].Value.Value = -1
+
+	err = store.BulkUpsert(inputs)
+	var serializationErr *SerializationError
+	if !errors.As(err, &serializationErr) {
+		t.Fatalf("Expected a SerializationError, got %v", err)
+	}
+	if serializationErr.Key != "key_734" || serializationErr.Type != "test_type" || serializationErr.Op != "serialize" || !errors.Is(err, errNegative) {
+		t.Errorf("Unexpected error: %+v", serializationErr)
+	}
+
+	// A value written behind the store's back fails to decode
+	if err := db.Upsert(EntryInput{Type: "test_type", Key: "corrupt", Value: []byte("not json")}); err != nil {
+		t.Fatalf("Failed to upsert: %v", err)
+	}
+	if _, err := store.Get("corrupt"); !errors.As(err, &serializationErr) || serializationErr.Key != "corrupt" || serializationErr.Op != "deserialize" {
+		t.Errorf("Expected a deserialize error for corrupt from Get, got %v", err)
+	}
+	if _, err := store.Query(StoreQueryParams{}); !errors.As(err, &serializationErr) || serializationErr.Key != "corrupt" {
+		t.Errorf("Expected a deserialize error for corrupt from Query, got %v", err)
+	}
+	var results []testItem
+	if err := store.QueryInto(StoreQueryParams{}, &results); !errors.As(err, &serializationErr) || serializationErr.Key != "corrupt" {
+		t.Errorf("Expected a deserialize error for corrupt from QueryInto, got %v", err)
+	}
+}
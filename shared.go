@@ -0,0 +1,42 @@
+package sidb
+
+import "sync"
+
+var (
+	sharedMutex     sync.Mutex
+	sharedDatabases = make(map[string]*Database)
+)
+
+// GetOrInit returns the process-wide Database for namespace/name, opening it
+// on first use. Options only apply when the database is actually opened.
+func GetOrInit(namespace []string, name string, opts ...Option) (*Database, error) {
+	var options Options
+	for _, opt := range opts {
+		opt(&options)
+	}
+	_, dbPath, err := options.databasePath(namespace, name)
+	if err != nil {
+		return nil, err
+	}
+
+	sharedMutex.Lock()
+	defer sharedMutex.Unlock()
+
+	if db, ok := sharedDatabases[dbPath]; ok {
+		db.mutex.RLock()
+		open := db.connection != nil
+		db.mutex.RUnlock()
+
+		if open {
+			return db, nil
+		}
+	}
+
+	db, err := Init(namespace, name, opts...)
+	if err != nil {
+		return nil, err
+	}
+	sharedDatabases[dbPath] = db
+
+	return db, nil
+}
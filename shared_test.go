@@ -0,0 +1,50 @@
+package sidb
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestGetOrInit(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_shared"
+
+	var wg sync.WaitGroup
+	databases := make([]*Database, 10)
+	errs := make([]error, 10)
+	for i := range databases {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			databases[i], errs[i] = GetOrInit(namespace, name)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, db := range databases {
+		if errs[i] != nil {
+			t.Fatalf("Failed to get shared database: %v", errs[i])
+		}
+		if db != databases[0] {
+			t.Fatalf("Expected every caller to share one database")
+		}
+	}
+
+	db := databases[0]
+	if err := db.Close(); err != nil {
+		t.Fatalf("Failed to close database: %v", err)
+	}
+
+	reopened, err := GetOrInit(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to reopen shared database: %v", err)
+	}
+	defer reopened.Drop()
+
+	if reopened == db {
+		t.Errorf("Expected a fresh database after the shared one was closed")
+	}
+	if _, err := reopened.Count(); err != nil {
+		t.Errorf("Expected reopened database to be usable, got %v", err)
+	}
+}
@@ -0,0 +1,298 @@
+// Package sidbadmin serves a small local web UI for looking into a sidb
+// database while developing or debugging. It has no authentication, so only
+// bind it to localhost; requests addressed to any other host are refused so
+// that pages open in the browser cannot reach it through DNS rebinding.
+package sidbadmin
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/germtb/sidb"
+)
+
+const pageSize = 100
+
+// Serve listens on addr and serves the admin UI for db until it fails.
+func Serve(db *sidb.Database, addr string) error {
+	return http.ListenAndServe(addr, Handler(db))
+}
+
+// Handler returns the admin UI for db, to mount on an existing server.
+func Handler(db *sidb.Database) http.Handler {
+	admin := &admin{db: db}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", admin.types)
+	mux.HandleFunc("GET /types/{type}", admin.entries)
+	mux.HandleFunc("GET /types/{type}/entries/{key}", admin.entry)
+	mux.HandleFunc("GET /query", admin.query)
+	mux.HandleFunc("POST /query", admin.query)
+	mux.HandleFunc("POST /maintenance", admin.maintenance)
+	return localOnly(mux)
+}
+
+// localOnly refuses requests whose Host is not a loopback address, and POSTs
+// sent from another origin, so other sites open in the browser cannot drive
+// the UI.
+func localOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isLoopback(r.Host) {
+			http.Error(w, "forbidden host", http.StatusForbidden)
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			if origin := r.Header.Get("Origin"); origin != "" {
+				parsed, err := url.Parse(origin)
+				if err != nil || parsed.Host != r.Host {
+					http.Error(w, "cross-origin request refused", http.StatusForbidden)
+					return
+				}
+			}
+			if site := r.Header.Get("Sec-Fetch-Site"); site != "" && site != "same-origin" && site != "none" {
+				http.Error(w, "cross-origin request refused", http.StatusForbidden)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func isLoopback(host string) bool {
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+	return ip != nil && ip.IsLoopback()
+}
+
+type admin struct {
+	db *sidb.Database
+}
+
+type typeRow struct {
+	Type string
+	sidb.TypeStats
+}
+
+func (admin *admin) types(w http.ResponseWriter, r *http.Request) {
+	stats, err := admin.db.Stats()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var types []typeRow
+	for entryType, typeStats := range stats.Types {
+		types = append(types, typeRow{Type: entryType, TypeStats: typeStats})
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i].Type < types[j].Type })
+
+	render(w, "types", map[string]any{"Stats": stats, "Types": types, "Message": r.URL.Query().Get("message")})
+}
+
+func (admin *admin) entries(w http.ResponseWriter, r *http.Request) {
+	entryType := r.PathValue("type")
+	groupings, err := admin.db.GroupingStats(entryType)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	limit := pageSize
+	params := sidb.QueryParams{Type: &entryType, SortOrder: sidb.Descending, Limit: &limit, After: r.URL.Query().Get("after")}
+	grouping := r.URL.Query().Get("grouping")
+	if r.URL.Query().Has("grouping") {
+		params.Grouping = &grouping
+	}
+	entries, cursor, err := admin.db.QueryPage(params)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	render(w, "entries", map[string]any{
+		"Type":      entryType,
+		"Groupings": groupings,
+		"Grouping":  grouping,
+		"Filtered":  params.Grouping != nil,
+		"Entries":   entries,
+		"Cursor":    cursor,
+	})
+}
+
+func (admin *admin) entry(w http.ResponseWriter, r *http.Request) {
+	entry, err := admin.db.Get(r.PathValue("type"), r.PathValue("key"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if entry == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	render(w, "entry", map[string]any{"Entry": entry, "Value": prettyValue(entry.Value)})
+}
+
+// query runs SQL posted from the UI through RawQueryReadOnly, so it should
+// select entries columns and cannot change anything.
+func (admin *admin) query(w http.ResponseWriter, r *http.Request) {
+	sql := r.PostFormValue("sql")
+	data := map[string]any{"SQL": sql}
+	if sql != "" {
+		entries, err := admin.db.RawQueryReadOnly(sql)
+		if err != nil {
+			data["Error"] = err.Error()
+		}
+		data["Entries"] = entries
+	}
+	render(w, "query", data)
+}
+
+func (admin *admin) maintenance(w http.ResponseWriter, r *http.Request) {
+	var message string
+	var err error
+	switch r.FormValue("action") {
+	case "sync":
+		err = admin.db.Sync()
+		message = "Synced"
+	case "vacuum":
+		err = admin.db.Vacuum()
+		message = "Vacuumed"
+	case "check-schema":
+		var report *sidb.SchemaReport
+		report, err = admin.db.NormalizeSchema(true)
+		if err == nil {
+			message = "Schema is up to date"
+			if report.Drifted() {
+				message = "Schema has drifted, run NormalizeSchema"
+			}
+		}
+	default:
+		http.Error(w, "unknown action", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		message = err.Error()
+	}
+
+	http.Redirect(w, r, "/?message="+template.URLQueryEscaper(message), http.StatusSeeOther)
+}
+
+// prettyValue indents JSON values and shows anything else as text.
+func prettyValue(value []byte) string {
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, value, "", "  "); err == nil {
+		return indented.String()
+	}
+	return string(value)
+}
+
+func render(w http.ResponseWriter, name string, data any) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := templates.ExecuteTemplate(w, name, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+var templates = template.Must(template.New("").Funcs(template.FuncMap{
+	"preview": func(value []byte) string {
+		if len(value) > 80 {
+			return string(value[:80]) + "…"
+		}
+		return string(value)
+	},
+	"path": url.PathEscape,
+	"itoa": func(value *int64) string {
+		if value == nil {
+			return ""
+		}
+		return strconv.FormatInt(*value, 10)
+	},
+}).Parse(`
+{{define "header"}}<!doctype html>
+<html><head><meta charset="utf-8"><title>sidb admin</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+td, th { border: 1px solid #ddd; padding: 4px 8px; text-align: left; vertical-align: top; }
+pre { background: #f6f6f6; padding: 1em; }
+code { font-size: 0.9em; }
+</style></head>
+<body><p><a href="/">Types</a> · <a href="/query">Query</a></p>{{end}}
+
+{{define "footer"}}</body></html>{{end}}
+
+{{define "entryTable"}}<table>
+<tr><th>Type</th><th>Key</th><th>Grouping</th><th>Subgrouping</th><th>Sorting index</th><th>Timestamp</th><th>Version</th><th>Value</th></tr>
+{{range .}}<tr>
+<td>{{.Type}}</td>
+<td><a href="/types/{{path .Type}}/entries/{{path .Key}}">{{.Key}}</a></td>
+<td>{{.GetGrouping}}</td><td>{{.Subgrouping}}</td><td>{{itoa .SortingIndex}}</td>
+<td>{{.Timestamp}}</td><td>{{.Version}}</td><td><code>{{preview .Value}}</code></td>
+</tr>{{end}}
+</table>{{end}}
+
+{{define "types"}}{{template "header"}}
+{{with .Message}}<p><strong>{{.}}</strong></p>{{end}}
+<h1>Types</h1>
+<table>
+<tr><th>Type</th><th>Entries</th><th>Value bytes</th></tr>
+{{range .Types}}<tr><td><a href="/types/{{path .Type}}">{{.Type}}</a></td><td>{{.Entries}}</td><td>{{.ValueBytes}}</td></tr>{{end}}
+</table>
+<p>File size {{.Stats.FileSizeBytes}} bytes, {{.Stats.PageCount}} pages of {{.Stats.PageSize}} bytes</p>
+<h2>Maintenance</h2>
+<form method="post" action="/maintenance">
+<button name="action" value="sync">Sync</button>
+<button name="action" value="vacuum">Vacuum</button>
+<button name="action" value="check-schema">Check schema</button>
+</form>
+{{template "footer"}}{{end}}
+
+{{define "entries"}}{{template "header"}}
+<h1>{{.Type}}</h1>
+<h2>Groupings</h2>
+<table>
+<tr><th>Grouping</th><th>Entries</th><th>First</th><th>Last</th></tr>
+{{range .Groupings}}<tr><td><a href="/types/{{path $.Type}}?grouping={{.Grouping}}">{{if .Grouping}}{{.Grouping}}{{else}}(none){{end}}</a></td><td>{{.Count}}</td><td>{{.MinTimestamp}}</td><td>{{.MaxTimestamp}}</td></tr>{{end}}
+</table>
+<h2>Entries{{if .Filtered}} in {{if .Grouping}}{{.Grouping}}{{else}}(none){{end}} · <a href="/types/{{path .Type}}">all</a>{{end}}</h2>
+{{template "entryTable" .Entries}}
+{{with .Cursor}}<p><a href="?after={{.}}{{if $.Filtered}}&grouping={{$.Grouping}}{{end}}">Next page</a></p>{{end}}
+{{template "footer"}}{{end}}
+
+{{define "entry"}}{{template "header"}}
+<h1>{{.Entry.Type}} / {{.Entry.Key}}</h1>
+<table>
+<tr><th>Grouping</th><td>{{.Entry.GetGrouping}}</td></tr>
+<tr><th>Subgrouping</th><td>{{.Entry.Subgrouping}}</td></tr>
+<tr><th>Sorting index</th><td>{{itoa .Entry.SortingIndex}}</td></tr>
+<tr><th>Timestamp</th><td>{{.Entry.Timestamp}}</td></tr>
+<tr><th>Created</th><td>{{.Entry.CreatedAt}}</td></tr>
+<tr><th>Updated</th><td>{{.Entry.UpdatedAt}}</td></tr>
+<tr><th>Version</th><td>{{.Entry.Version}}</td></tr>
+<tr><th>Immutable</th><td>{{.Entry.Immutable}}</td></tr>
+</table>
+<pre>{{.Value}}</pre>
+{{template "footer"}}{{end}}
+
+{{define "query"}}{{template "header"}}
+<h1>Query</h1>
+<form method="post" action="/query">
+<textarea name="sql" rows="4" cols="100" placeholder="SELECT * FROM entries WHERE type = 'note' LIMIT 100">{{.SQL}}</textarea><br>
+<button>Run</button>
+</form>
+{{with .Error}}<p><strong>{{.}}</strong></p>{{end}}
+{{with .Entries}}{{template "entryTable" .}}{{end}}
+{{template "footer"}}{{end}}
+`))
@@ -0,0 +1,116 @@
+package sidbadmin
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/germtb/sidb"
+)
+
+func TestHandler(t *testing.T) {
+	db, err := sidb.Init([]string{"test_namespace"}, "test_admin")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	grouping := "inbox"
+	err = db.BulkUpsert([]sidb.EntryInput{
+		{Type: "note", Key: "a/b", Value: []byte(`{"title":"hello"}`), Grouping: grouping},
+		{Type: "note", Key: "c", Value: []byte("plain text")},
+	})
+	if err != nil {
+		t.Fatalf("Failed to put entries: %v", err)
+	}
+
+	server := httptest.NewServer(Handler(db))
+	defer server.Close()
+
+	get := func(path string) string {
+		response, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("Failed to get %s: %v", path, err)
+		}
+		defer response.Body.Close()
+		body, _ := io.ReadAll(response.Body)
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("Expected 200 for %s, got %d: %s", path, response.StatusCode, body)
+		}
+		return string(body)
+	}
+
+	if body := get("/"); !strings.Contains(body, `href="/types/note"`) {
+		t.Errorf("Expected the types page to link to note, got %s", body)
+	}
+	if body := get("/types/note?grouping=inbox"); !strings.Contains(body, "a%2Fb") || strings.Contains(body, `entries/c"`) {
+		t.Errorf("Expected only the inbox entry, got %s", body)
+	}
+	if body := get("/types/note/entries/a%2Fb"); !strings.Contains(body, "{\n  &#34;title&#34;: &#34;hello&#34;\n}") {
+		t.Errorf("Expected a pretty printed value, got %s", body)
+	}
+	post := func(path string, form url.Values) (int, string) {
+		response, err := http.PostForm(server.URL+path, form)
+		if err != nil {
+			t.Fatalf("Failed to post %s: %v", path, err)
+		}
+		defer response.Body.Close()
+		body, _ := io.ReadAll(response.Body)
+		return response.StatusCode, string(body)
+	}
+
+	if _, body := post("/query", url.Values{"sql": {"SELECT type, key, value FROM entries WHERE key = 'c'"}}); !strings.Contains(body, "plain text") {
+		t.Errorf("Expected the query result, got %s", body)
+	}
+	if body := get("/query?sql=" + url.QueryEscape("DELETE FROM entries")); strings.Contains(body, "<strong>") {
+		t.Errorf("Expected GET not to run queries, got %s", body)
+	}
+	if _, body := post("/query", url.Values{"sql": {"DELETE FROM entries"}}); !strings.Contains(body, "<strong>") {
+		t.Errorf("Expected writes to be refused, got %s", body)
+	}
+	if count, err := db.Count(); err != nil || count != 2 {
+		t.Errorf("Expected the entries to survive, got %d, %v", count, err)
+	}
+
+	if _, body := post("/maintenance", url.Values{"action": {"vacuum"}}); !strings.Contains(body, "Vacuumed") {
+		t.Errorf("Expected the vacuum to be reported, got %s", body)
+	}
+}
+
+func TestHandlerRefusesOtherSites(t *testing.T) {
+	db, err := sidb.Init([]string{"test_namespace"}, "test_admin_sites")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	handler := Handler(db)
+	for _, request := range []*http.Request{
+		httptest.NewRequest("GET", "http://attacker.example/", nil),
+		withHeader(httptest.NewRequest("POST", "http://localhost:8080/maintenance", strings.NewReader("action=vacuum")), "Origin", "http://attacker.example"),
+		withHeader(httptest.NewRequest("POST", "http://localhost:8080/maintenance", strings.NewReader("action=vacuum")), "Sec-Fetch-Site", "cross-site"),
+	} {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		if recorder.Code != http.StatusForbidden {
+			t.Errorf("Expected %s %s to be refused, got %d", request.Method, request.Host, recorder.Code)
+		}
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, withHeader(httptest.NewRequest("GET", "http://[::1]:8080/", nil), "Origin", "http://[::1]:8080"))
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected a loopback request to be served, got %d", recorder.Code)
+	}
+}
+
+func withHeader(request *http.Request, name, value string) *http.Request {
+	request.Header.Set(name, value)
+	if request.Method == "POST" {
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	return request
+}
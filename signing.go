@@ -0,0 +1,58 @@
+package sidb
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+)
+
+// Signed types carry an HMAC of their type, key and value, so rows written by
+// anything that does not hold the key (another process, or someone editing the
+// file with sqlite3) fail to read with ErrTampered. RawQuery does not verify.
+
+var ErrTampered = errors.New("entry signature does not match")
+
+type signingConfig struct {
+	key   []byte
+	types map[string]bool // Signs every type when empty
+}
+
+// WithSigningKey signs the values of the given types, or of every type when
+// none are given.
+func WithSigningKey(key []byte, entryTypes ...string) Option {
+	return func(options *Options) {
+		config := &signingConfig{key: key, types: make(map[string]bool)}
+		for _, entryType := range entryTypes {
+			config.types[entryType] = true
+		}
+		options.signing = config
+	}
+}
+
+func (db *Database) signs(entryType string) bool {
+	signing := db.options.signing
+	return signing != nil && (len(signing.types) == 0 || signing.types[entryType])
+}
+
+func (db *Database) sign(entryType string, key string, value []byte) []byte {
+	if !db.signs(entryType) {
+		return nil
+	}
+	mac := hmac.New(sha256.New, db.options.signing.key)
+	mac.Write([]byte(entryType))
+	mac.Write([]byte{0})
+	mac.Write([]byte(key))
+	mac.Write([]byte{0})
+	mac.Write(value)
+	return mac.Sum(nil)
+}
+
+func (db *Database) verify(entryType string, key string, value []byte, signature []byte) error {
+	if !db.signs(entryType) {
+		return nil
+	}
+	if !hmac.Equal(signature, db.sign(entryType, key, value)) {
+		return db.options.reportCorruption(db.Path, ErrTampered)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package sidb
+
+import "testing"
+
+func TestSignedEntries(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_signing"
+	db, err := Init(namespace, name, WithSigningKey([]byte("secret"), "credential"))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	err = db.BulkUpsert([]EntryInput{
+		{Type: "credential", Value: []byte("token_1"), Key: "key_1"},
+		{Type: "credential", Value: []byte("token_2"), Key: "key_2"},
+		{Type: "note", Value: []byte("data"), Key: "key_1"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to put entries: %v", err)
+	}
+
+	if err := db.Update(EntryInput{Type: "credential", Value: []byte("token_1b"), Key: "key_1"}); err != nil {
+		t.Fatalf("Failed to update entry: %v", err)
+	}
+
+	entry, err := db.Get("credential", "key_1")
+	if err != nil {
+		t.Fatalf("Failed to get signed entry: %v", err)
+	}
+	if string(entry.Value) != "token_1b" {
+		t.Errorf("Expected token_1b, got %s", string(entry.Value))
+	}
+
+	// Simulate another writer editing the file directly
+	if _, err := db.RawExec("UPDATE entries SET value = 'forged' WHERE type = 'credential' AND key = 'key_2'"); err != nil {
+		t.Fatalf("Failed to tamper with entry: %v", err)
+	}
+	if _, err := db.RawExec("UPDATE entries SET value = 'edited' WHERE type = 'note'"); err != nil {
+		t.Fatalf("Failed to edit unsigned entry: %v", err)
+	}
+
+	if _, err := db.Get("credential", "key_2"); err != ErrTampered {
+		t.Errorf("Expected ErrTampered from Get, got %v", err)
+	}
+	if _, err := db.Query(QueryParams{Type: ptr("credential")}); err != ErrTampered {
+		t.Errorf("Expected ErrTampered from Query, got %v", err)
+	}
+	if entry, err := db.Get("note", "key_1"); err != nil || string(entry.Value) != "edited" {
+		t.Errorf("Expected unsigned entry to read normally, got %+v, %v", entry, err)
+	}
+
+	// Opening without the key must not accept forged entries as signed ones
+	other, err := Init(namespace, name, WithSigningKey([]byte("wrong"), "credential"))
+	if err != nil {
+		t.Fatalf("Failed to reopen database: %v", err)
+	}
+	defer other.Close()
+	if _, err := other.Get("credential", "key_1"); err != ErrTampered {
+		t.Errorf("Expected ErrTampered with the wrong key, got %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package sidb
+
+// With WithSoftDelete, a deleted entry keeps its row with deletedAt set to the
+// time of deletion, and every read filters on deletedAt IS NULL. Deleting
+// bumps the version like any other write, so tombstones can be synced.
+
+// deleteStatement returns the statement removing the entries matching where,
+// or marking them deleted when soft delete is enabled.
+func (db *Database) deleteStatement(where string, args []interface{}) (string, []interface{}) {
+	return db.deleteStatementFrom("entries", where, args)
+}
+
+// deleteStatementFrom is deleteStatement for an entries table given by name,
+// such as that of an attached database.
+func (db *Database) deleteStatementFrom(table string, where string, args []interface{}) (string, []interface{}) {
+	if !db.options.softDelete {
+		return "DELETE FROM " + table + " WHERE " + where, args
+	}
+	return "UPDATE " + table + " SET deletedAt = ?, version = version + 1 WHERE deletedAt IS NULL AND " + where,
+		append([]interface{}{db.now()}, args...)
+}
+
+type Tombstone struct {
+	Type      string
+	Key       string
+	DeletedAt int64
+	Version   int64
+}
+
+// Restore undeletes a soft deleted entry. It returns ErrEntryNotFound if
+// there is no deleted entry for the key.
+func (db *Database) Restore(entryType string, key string) error {
+	defer db.afterWrite(entryType)
+
+	defer db.lockWrite(false, entryType)()
+
+	if db.connection == nil {
+		return ErrNoDbConnection
+	}
+
+	ctx, done := db.writeContext()
+	defer done()
+
+	result, err := db.connection.ExecContext(ctx, "UPDATE entries SET deletedAt = NULL, version = version + 1 WHERE type = ? AND key = ? AND deletedAt IS NOT NULL", entryType, key)
+	if err != nil {
+		return err
+	}
+	restored, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if restored == 0 {
+		return ErrEntryNotFound
+	}
+	return nil
+}
+
+// Tombstones returns the entries of a type deleted after since, oldest first.
+func (db *Database) Tombstones(entryType string, since int64) ([]Tombstone, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	if db.connection == nil {
+		return nil, ErrNoDbConnection
+	}
+
+	ctx, done := db.readContext()
+	defer done()
+
+	rows, err := db.connection.QueryContext(ctx, "SELECT type, key, deletedAt, version FROM entries WHERE type = ? AND deletedAt > ? ORDER BY deletedAt, key", entryType, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tombstones []Tombstone
+	for rows.Next() {
+		var tombstone Tombstone
+		if err := rows.Scan(&tombstone.Type, &tombstone.Key, &tombstone.DeletedAt, &tombstone.Version); err != nil {
+			return nil, err
+		}
+		tombstones = append(tombstones, tombstone)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return tombstones, nil
+}
+
+// Purge permanently removes entries deleted before the given time and
+// returns how many were removed.
+func (db *Database) Purge(deletedBefore int64) (int64, error) {
+	defer db.lockWrite(true)()
+
+	if db.connection == nil {
+		return 0, ErrNoDbConnection
+	}
+
+	ctx, done := db.writeContext()
+	defer done()
+
+	result, err := db.connection.ExecContext(ctx, "DELETE FROM entries WHERE deletedAt IS NOT NULL AND deletedAt < ?", deletedBefore)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,94 @@
+package sidb
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSoftDelete(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_soft_delete"
+	db, err := Init(namespace, name, WithSoftDelete())
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	entryType := "test_type"
+	err = db.BulkUpsert([]EntryInput{
+		{Type: entryType, Value: []byte("data_1"), Key: "key_1", Grouping: "g1"},
+		{Type: entryType, Value: []byte("data_2"), Key: "key_2", Grouping: "g1"},
+		{Type: entryType, Value: []byte("data_3"), Key: "key_3", Grouping: "g2"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to put entries: %v", err)
+	}
+
+	if err := db.Delete(entryType, "key_1"); err != nil {
+		t.Fatalf("Failed to delete entry: %v", err)
+	}
+	if err := db.DeleteByGrouping(entryType, "g2"); err != nil {
+		t.Fatalf("Failed to delete grouping: %v", err)
+	}
+
+	entry, err := db.Get(entryType, "key_1")
+	if err != nil {
+		t.Fatalf("Failed to get entry: %v", err)
+	}
+	if entry != nil {
+		t.Errorf("Expected deleted entry to be hidden, got %+v", entry)
+	}
+	entries, err := db.Query(QueryParams{Type: &entryType})
+	if err != nil {
+		t.Fatalf("Failed to query entries: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Key != "key_2" {
+		t.Errorf("Expected only key_2, got %+v", entries)
+	}
+
+	tombstones, err := db.Tombstones(entryType, 0)
+	if err != nil {
+		t.Fatalf("Failed to list tombstones: %v", err)
+	}
+	if len(tombstones) != 2 {
+		t.Fatalf("Expected 2 tombstones, got %d", len(tombstones))
+	}
+	if tombstones[0].Version != 2 {
+		t.Errorf("Expected deletion to bump version to 2, got %d", tombstones[0].Version)
+	}
+
+	if err := db.Restore(entryType, "key_1"); err != nil {
+		t.Fatalf("Failed to restore entry: %v", err)
+	}
+	if err := db.Restore(entryType, "key_1"); err != ErrEntryNotFound {
+		t.Errorf("Expected ErrEntryNotFound restoring a live entry, got %v", err)
+	}
+
+	// Creating over a tombstone revives the key
+	_, inserted, err := db.GetOrSet(EntryInput{Type: entryType, Value: []byte("new"), Key: "key_3"})
+	if err != nil {
+		t.Fatalf("Failed to get or set entry: %v", err)
+	}
+	if !inserted {
+		t.Errorf("Expected GetOrSet to insert over a tombstone")
+	}
+
+	if err := db.Delete(entryType, "key_2"); err != nil {
+		t.Fatalf("Failed to delete entry: %v", err)
+	}
+	purged, err := db.Purge(time.Now().UnixMilli() + 1)
+	if err != nil {
+		t.Fatalf("Failed to purge: %v", err)
+	}
+	if purged != 1 {
+		t.Errorf("Expected 1 purged entry, got %d", purged)
+	}
+
+	count, err := db.Count()
+	if err != nil {
+		t.Fatalf("Failed to count entries: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 live entries, got %d", count)
+	}
+}
@@ -0,0 +1,75 @@
+package sidb
+
+import (
+	"context"
+	"database/sql"
+)
+
+// The single entry reads and writes run a handful of statements at a high
+// rate, so they are prepared once per Database instead of on every call.
+// Prepared statements are closed with the database.
+
+const (
+	getSQL    = "SELECT " + entryColumns + " FROM entries WHERE type = ? AND key = ? AND deletedAt IS NULL"
+	existsSQL = "SELECT 1 FROM entries WHERE type = ? AND key = ? AND deletedAt IS NULL LIMIT 1"
+	updateSQL = "UPDATE entries SET value = ?, signature = ?, projection = NULL, version = version + 1, updatedAt = ? WHERE key = ? AND type = ? AND deletedAt IS NULL"
+)
+
+// prepared returns the cached statement for query, preparing it on first use.
+// The caller holds db.mutex.
+func (db *Database) prepared(ctx context.Context, query string) (*sql.Stmt, error) {
+	db.statementsMutex.Lock()
+	defer db.statementsMutex.Unlock()
+
+	if stmt, ok := db.statements[query]; ok {
+		return stmt, nil
+	}
+
+	stmt, err := db.connection.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if db.statements == nil {
+		db.statements = make(map[string]*sql.Stmt)
+	}
+	db.statements[query] = stmt
+	return stmt, nil
+}
+
+func (db *Database) closeStatements() {
+	db.statementsMutex.Lock()
+	defer db.statementsMutex.Unlock()
+
+	for _, stmt := range db.statements {
+		stmt.Close()
+	}
+	db.statements = nil
+}
+
+// execWrite runs a prepared single entry write. When entryType has a
+// rejecting quota it runs in a transaction so the write can be rolled back.
+func (db *Database) execWrite(ctx context.Context, entryType string, query string, args ...interface{}) error {
+	stmt, err := db.prepared(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	if quota, ok := db.options.quotas[entryType]; !ok || quota.policy != QuotaReject {
+		_, err := stmt.ExecContext(ctx, args...)
+		return err
+	}
+
+	tx, err := db.connection.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.StmtContext(ctx, stmt).ExecContext(ctx, args...); err != nil {
+		return err
+	}
+	if err := db.checkQuotas(ctx, tx, entryType); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
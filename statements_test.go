@@ -0,0 +1,63 @@
+package sidb
+
+import (
+	"fmt"
+	"testing"
+)
+
+func BenchmarkSingleWrites(b *testing.B) {
+	db, err := Init([]string{"test_namespace"}, "bench_single_writes")
+	if err != nil {
+		b.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		key := fmt.Sprintf("key_%d", i%100)
+		if err := db.Upsert(EntryInput{Type: "item", Key: key, Value: []byte("data")}); err != nil {
+			b.Fatalf("Failed to upsert: %v", err)
+		}
+		if err := db.Update(EntryInput{Type: "item", Key: key, Value: []byte("more data")}); err != nil {
+			b.Fatalf("Failed to update: %v", err)
+		}
+		if err := db.Delete("item", key); err != nil {
+			b.Fatalf("Failed to delete: %v", err)
+		}
+	}
+}
+
+func TestPreparedStatements(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_prepared_statements"
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { db.Drop() }()
+
+	for i := 0; i < 3; i++ {
+		if err := db.Upsert(EntryInput{Type: "item", Key: "key", Value: []byte("data")}); err != nil {
+			t.Fatalf("Failed to upsert: %v", err)
+		}
+		if _, err := db.Get("item", "key"); err != nil {
+			t.Fatalf("Failed to get: %v", err)
+		}
+	}
+	if len(db.statements) != 2 {
+		t.Errorf("Expected 2 cached statements, got %d", len(db.statements))
+	}
+
+	db.Close()
+	if db.statements != nil {
+		t.Errorf("Expected cached statements to be closed with the database")
+	}
+
+	db, err = Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to reopen database: %v", err)
+	}
+	if entry, err := db.Get("item", "key"); err != nil || entry == nil {
+		t.Errorf("Expected the entry after reopening, got %v (%v)", entry, err)
+	}
+}
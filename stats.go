@@ -0,0 +1,87 @@
+package sidb
+
+import (
+	"os"
+	"strings"
+)
+
+type TypeStats struct {
+	Entries    int64
+	ValueBytes int64
+}
+
+type Stats struct {
+	FileSizeBytes int64
+	PageCount     int64
+	PageSize      int64
+	Types         map[string]TypeStats
+	IndexBytes    map[string]int64 // Nil when SQLite is built without dbstat
+}
+
+// Stats reports the storage used by the database. Deleted entries kept by
+// soft delete are not counted in Types.
+func (db *Database) Stats() (*Stats, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	if db.connection == nil {
+		return nil, ErrNoDbConnection
+	}
+
+	ctx, done := db.readContext()
+	defer done()
+
+	stats := &Stats{Types: make(map[string]TypeStats)}
+
+	if info, err := os.Stat(db.Path); err == nil {
+		stats.FileSizeBytes = info.Size()
+	}
+	if err := db.connection.QueryRowContext(ctx, "PRAGMA page_count").Scan(&stats.PageCount); err != nil {
+		return nil, err
+	}
+	if err := db.connection.QueryRowContext(ctx, "PRAGMA page_size").Scan(&stats.PageSize); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.connection.QueryContext(ctx, "SELECT type, COUNT(*), COALESCE(SUM(length(value)), 0) FROM entries WHERE deletedAt IS NULL GROUP BY type")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entryType string
+		var typeStats TypeStats
+		if err := rows.Scan(&entryType, &typeStats.Entries, &typeStats.ValueBytes); err != nil {
+			return nil, err
+		}
+		stats.Types[entryType] = typeStats
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// dbstat is an optional SQLite extension, its absence is not an error
+	indexRows, err := db.connection.QueryContext(ctx, "SELECT name, SUM(pgsize) FROM dbstat WHERE name IN (SELECT name FROM sqlite_master WHERE type = 'index') GROUP BY name")
+	if err != nil && strings.Contains(err.Error(), "no such table: dbstat") {
+		return stats, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer indexRows.Close()
+
+	stats.IndexBytes = make(map[string]int64)
+	for indexRows.Next() {
+		var name string
+		var size int64
+		if err := indexRows.Scan(&name, &size); err != nil {
+			return nil, err
+		}
+		stats.IndexBytes[name] = size
+	}
+	if err := indexRows.Err(); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
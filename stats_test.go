@@ -0,0 +1,33 @@
+package sidb
+
+import "testing"
+
+func TestStats(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_stats"
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	err = db.BulkUpsert([]EntryInput{
+		{Type: "note", Key: "a", Value: []byte("hello")},
+		{Type: "note", Key: "b", Value: []byte("hi")},
+		{Type: "tag", Key: "c", Value: []byte("x")},
+	})
+	if err != nil {
+		t.Fatalf("Failed to put entries: %v", err)
+	}
+
+	stats, err := db.Stats()
+	if err != nil {
+		t.Fatalf("Failed to get stats: %v", err)
+	}
+	if stats.Types["note"] != (TypeStats{Entries: 2, ValueBytes: 7}) || stats.Types["tag"] != (TypeStats{Entries: 1, ValueBytes: 1}) {
+		t.Errorf("Unexpected type stats: %+v", stats.Types)
+	}
+	if stats.PageCount == 0 || stats.PageSize == 0 || stats.FileSizeBytes == 0 {
+		t.Errorf("Expected page and file sizes, got %+v", stats)
+	}
+}
@@ -0,0 +1,126 @@
+package sidb
+
+import "iter"
+
+const defaultStreamChunkSize = 1000
+
+// BulkUpsertStream writes entries as they are produced, committing every
+// chunkSize entries (1000 when 0) in its own transaction so memory use and
+// transaction length stay bounded. An error from the source stops the import.
+// It returns how many entries were committed, so a failed import can resume.
+func (db *Database) BulkUpsertStream(entries iter.Seq2[EntryInput, error], chunkSize int) (int, error) {
+	if chunkSize <= 0 {
+		chunkSize = defaultStreamChunkSize
+	}
+
+	written := 0
+	chunk := make([]EntryInput, 0, chunkSize)
+	for entry, err := range entries {
+		if err != nil {
+			return written, err
+		}
+
+		chunk = append(chunk, entry)
+		if len(chunk) == chunkSize {
+			if err := db.BulkUpsert(chunk); err != nil {
+				return written, err
+			}
+			written += len(chunk)
+			chunk = chunk[:0]
+		}
+	}
+
+	if len(chunk) > 0 {
+		if err := db.BulkUpsert(chunk); err != nil {
+			return written, err
+		}
+		written += len(chunk)
+	}
+
+	return written, nil
+}
+
+func (store *Store[T]) BulkUpsertStream(entries iter.Seq2[StoreEntryInput[T], error], chunkSize int) (int, error) {
+	return store.db.BulkUpsertStream(func(yield func(EntryInput, error) bool) {
+		for entry, err := range entries {
+			if err != nil {
+				yield(EntryInput{}, err)
+				return
+			}
+			input, err := store.entryInput(entry)
+			if !yield(input, err) || err != nil {
+				return
+			}
+		}
+	}, chunkSize)
+}
+
+type PipeProgress struct {
+	Read    int
+	Written int
+	// Cursor resumes the pipe after the last entry read when passed as After
+	Cursor string
+}
+
+type PipeOptions struct {
+	ChunkSize int // Entries read and written per batch, 1000 when 0
+	Progress  func(PipeProgress)
+}
+
+// Pipe copies the entries matching params from src to dst, passing each
+// through transform. A nil result from transform skips the entry. It returns
+// the number of entries written.
+func Pipe(src, dst *Database, params QueryParams, transform func(DbEntry) (*EntryInput, error)) (int, error) {
+	return PipeWithOptions(src, dst, params, transform, PipeOptions{})
+}
+
+// PipeWithOptions is Pipe with batching and progress control. Entries are read
+// a chunk at a time in timestamp order and each chunk is written in its own
+// transaction, so params cannot set another sort order and its Limit is
+// replaced by the chunk size. Its After starts the pipe at a cursor.
+func PipeWithOptions(src, dst *Database, params QueryParams, transform func(DbEntry) (*EntryInput, error), options PipeOptions) (int, error) {
+	chunkSize := options.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = defaultStreamChunkSize
+	}
+	params.Limit = &chunkSize
+
+	progress := PipeProgress{Cursor: params.After}
+	for {
+		entries, next, err := src.QueryPage(params)
+		if err != nil {
+			return progress.Written, err
+		}
+
+		var inputs []EntryInput
+		for _, entry := range entries {
+			input, err := transform(entry)
+			if err != nil {
+				return progress.Written, err
+			}
+			if input != nil {
+				inputs = append(inputs, *input)
+			}
+		}
+
+		if len(inputs) > 0 {
+			if err := dst.BulkUpsert(inputs); err != nil {
+				return progress.Written, err
+			}
+		}
+
+		progress.Read += len(entries)
+		progress.Written += len(inputs)
+		if len(entries) > 0 {
+			progress.Cursor = CursorFor(entries[len(entries)-1])
+		}
+		if options.Progress != nil {
+			options.Progress(progress)
+		}
+
+		if next == "" {
+			return progress.Written, nil
+		}
+		params.After = next
+	}
+}
@@ -0,0 +1,133 @@
+package sidb
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestBulkUpsertStream(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_stream"
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	entryType := "test_type"
+	source := func(failAt int) func(yield func(EntryInput, error) bool) {
+		return func(yield func(EntryInput, error) bool) {
+			for i := 0; i < 2500; i++ {
+				if i == failAt {
+					yield(EntryInput{}, fmt.Errorf("source failed intentionally"))
+					return
+				}
+				if !yield(EntryInput{Type: entryType, Value: []byte("data"), Key: fmt.Sprintf("key_%d", i)}, nil) {
+					return
+				}
+			}
+		}
+	}
+
+	written, err := db.BulkUpsertStream(source(-1), 1000)
+	if err != nil {
+		t.Fatalf("Failed to stream entries: %v", err)
+	}
+	if written != 2500 {
+		t.Errorf("Expected 2500 written, got %d", written)
+	}
+
+	// Only whole chunks before the failure are committed
+	if _, err := db.RawExec("DELETE FROM entries"); err != nil {
+		t.Fatalf("Failed to clear entries: %v", err)
+	}
+	written, err = db.BulkUpsertStream(source(1500), 1000)
+	if err == nil {
+		t.Fatalf("Expected source error, got nil")
+	}
+	count, _ := db.Count()
+	if written != 1000 || count != 1000 {
+		t.Errorf("Expected 1000 committed entries, got written=%d count=%d", written, count)
+	}
+}
+
+func TestStoreBulkUpsertStream(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_stream"
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to init db: %v", err)
+	}
+	defer db.Drop()
+
+	store := MakeStore(db, "test_type", serializeTestItem, deserializeTestItem, nil)
+	written, err := store.BulkUpsertStream(func(yield func(StoreEntryInput[testItem], error) bool) {
+		for i := 0; i < 10; i++ {
+			if !yield(StoreEntryInput[testItem]{Key: fmt.Sprintf("key_%d", i), Value: testItem{Name: "item", Value: i}}, nil) {
+				return
+			}
+		}
+	}, 3)
+	if err != nil {
+		t.Fatalf("Failed to stream entries: %v", err)
+	}
+
+	count, err := store.Count()
+	if err != nil {
+		t.Fatalf("Failed to count: %v", err)
+	}
+	if written != 10 || count != 10 {
+		t.Errorf("Expected 10 entries, got written=%d count=%d", written, count)
+	}
+}
+
+func TestPipe(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	src, err := Init(namespace, "test_pipe_src")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer src.Drop()
+
+	dst, err := Init(namespace, "test_pipe_dst")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer dst.Drop()
+
+	var inputs []EntryInput
+	for i := 0; i < 25; i++ {
+		inputs = append(inputs, EntryInput{Type: "old_type", Value: []byte("data"), Key: fmt.Sprintf("key_%d", i), Timestamp: ptr(int64(i))})
+	}
+	if err := src.BulkUpsert(inputs); err != nil {
+		t.Fatalf("Failed to put entries: %v", err)
+	}
+
+	oldType := "old_type"
+	var reports []PipeProgress
+	written, err := PipeWithOptions(src, dst, QueryParams{Type: &oldType}, func(entry DbEntry) (*EntryInput, error) {
+		// Skip odd timestamps and re-type the rest
+		if entry.Timestamp%2 == 1 {
+			return nil, nil
+		}
+		return &EntryInput{Type: "new_type", Key: entry.Key, Value: entry.Value, Timestamp: &entry.Timestamp}, nil
+	}, PipeOptions{ChunkSize: 10, Progress: func(progress PipeProgress) { reports = append(reports, progress) }})
+	if err != nil {
+		t.Fatalf("Failed to pipe entries: %v", err)
+	}
+	if written != 13 {
+		t.Errorf("Expected 13 written, got %d", written)
+	}
+	if len(reports) != 3 || reports[2].Read != 25 || reports[2].Written != 13 {
+		t.Errorf("Unexpected progress reports: %+v", reports)
+	}
+
+	newType := "new_type"
+	count, err := dst.CountWhere(QueryParams{Type: &newType})
+	if err != nil {
+		t.Fatalf("Failed to count entries: %v", err)
+	}
+	if count != 13 {
+		t.Errorf("Expected 13 entries in destination, got %d", count)
+	}
+}
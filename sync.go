@@ -0,0 +1,17 @@
+package sidb
+
+// Sync checkpoints the write-ahead log into the database file and fsyncs it,
+// so every write committed so far is durable.
+func (db *Database) Sync() error {
+	defer db.lockWrite(false)()
+
+	if db.connection == nil {
+		return ErrNoDbConnection
+	}
+
+	ctx, done := db.writeContext()
+	defer done()
+
+	_, err := db.connection.ExecContext(ctx, "PRAGMA wal_checkpoint(FULL)")
+	return err
+}
@@ -0,0 +1,34 @@
+package sidb
+
+import "testing"
+
+func TestSync(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_sync"
+	db, err := Init(namespace, name, WithSyncOnEveryWrite())
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	var synchronous int
+	if err := db.connection.QueryRow("PRAGMA synchronous").Scan(&synchronous); err != nil {
+		t.Fatalf("Failed to read synchronous pragma: %v", err)
+	}
+	// 2 is FULL
+	if synchronous != 2 {
+		t.Errorf("Expected synchronous=FULL, got %d", synchronous)
+	}
+
+	if err := db.Upsert(EntryInput{Type: "test_type", Key: "key", Value: []byte("data")}); err != nil {
+		t.Fatalf("Failed to upsert: %v", err)
+	}
+	if err := db.Sync(); err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+
+	db.Close()
+	if err := db.Sync(); err != ErrNoDbConnection {
+		t.Errorf("Expected ErrNoDbConnection after close, got %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package sidb
+
+import (
+	"database/sql"
+	"errors"
+)
+
+var ErrNoTemplate = errors.New("no template set for type")
+
+// Templates are kept outside the entries table so they never show up in
+// queries, counts or quotas of the type they describe.
+const templatesTableSQL = `CREATE TABLE IF NOT EXISTS templates (
+	"type" TEXT NOT NULL PRIMARY KEY,
+	"value" BLOB NOT NULL
+) WITHOUT ROWID`
+
+// SetTemplate stores the value new entries of the type start from, replacing
+// any previous template. A nil value removes it.
+func (db *Database) SetTemplate(entryType string, value []byte) error {
+	defer db.lockWrite(false, entryType)()
+
+	if db.connection == nil {
+		return ErrNoDbConnection
+	}
+
+	ctx, done := db.writeContext()
+	defer done()
+
+	if value == nil {
+		_, err := db.connection.ExecContext(ctx, "DELETE FROM templates WHERE type = ?", entryType)
+		return err
+	}
+	_, err := db.connection.ExecContext(ctx, "INSERT INTO templates (type, value) VALUES (?, ?) ON CONFLICT (type) DO UPDATE SET value = excluded.value", entryType, value)
+	return err
+}
+
+// Template returns the template of the type, or ErrNoTemplate if none is set.
+func (db *Database) Template(entryType string) ([]byte, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	if db.connection == nil {
+		return nil, ErrNoDbConnection
+	}
+
+	ctx, done := db.readContext()
+	defer done()
+
+	var value []byte
+	err := db.connection.QueryRowContext(ctx, "SELECT value FROM templates WHERE type = ?", entryType).Scan(&value)
+	if err == sql.ErrNoRows {
+		return nil, ErrNoTemplate
+	}
+	return value, err
+}
+
+// CreateFromTemplate inserts a new entry at key holding the type's current
+// template and returns it. It fails with ErrKeyExists if the key is taken.
+func (store *Store[T]) CreateFromTemplate(key string) (T, error) {
+	var zero T
+	template, err := store.db.Template(store.entryType)
+	if err != nil {
+		return zero, err
+	}
+	value, err := store.decode(key, template)
+	if err != nil {
+		return zero, err
+	}
+
+	input, err := store.entryInput(StoreEntryInput[T]{Key: key, Value: value})
+	if err != nil {
+		return zero, err
+	}
+	_, inserted, err := store.db.GetOrSet(input)
+	if err != nil {
+		return zero, err
+	}
+	if !inserted {
+		return zero, ErrKeyExists
+	}
+	return value, nil
+}
@@ -0,0 +1,65 @@
+package sidb
+
+import "testing"
+
+func TestTemplates(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_templates"
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	store := MakeStore(db, "test_type", serializeTestItem, deserializeTestItem, nil)
+
+	if _, err := store.CreateFromTemplate("a"); err != ErrNoTemplate {
+		t.Errorf("Expected ErrNoTemplate, got %v", err)
+	}
+
+	template, _ := serializeTestItem(testItem{Name: "untitled", Value: 1})
+	if err := db.SetTemplate("test_type", template); err != nil {
+		t.Fatalf("Failed to set template: %v", err)
+	}
+
+	item, err := store.CreateFromTemplate("a")
+	if err != nil {
+		t.Fatalf("Failed to create from template: %v", err)
+	}
+	if item.Name != "untitled" || item.Value != 1 {
+		t.Errorf("Unexpected item: %+v", item)
+	}
+	if _, err := store.CreateFromTemplate("a"); err != ErrKeyExists {
+		t.Errorf("Expected ErrKeyExists, got %v", err)
+	}
+
+	// Updating the template affects only entries created afterwards
+	template, _ = serializeTestItem(testItem{Name: "draft", Value: 2})
+	if err := db.SetTemplate("test_type", template); err != nil {
+		t.Fatalf("Failed to set template: %v", err)
+	}
+	if _, err := store.CreateFromTemplate("b"); err != nil {
+		t.Fatalf("Failed to create from template: %v", err)
+	}
+	a, _ := store.Get("a")
+	b, _ := store.Get("b")
+	if a.Name != "untitled" || b.Name != "draft" {
+		t.Errorf("Unexpected items: %+v, %+v", a, b)
+	}
+
+	// Templates are not entries of their type
+	count, err := store.Count()
+	if err != nil {
+		t.Fatalf("Failed to count: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 entries, got %d", count)
+	}
+
+	if err := db.SetTemplate("test_type", nil); err != nil {
+		t.Fatalf("Failed to remove template: %v", err)
+	}
+	if _, err := db.Template("test_type"); err != ErrNoTemplate {
+		t.Errorf("Expected ErrNoTemplate, got %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package sidb
+
+import "os"
+
+// A Threshold is crossed when any of its non-zero limits is reached.
+
+type Threshold struct {
+	FileSizeBytes int64
+	EntryCount    int64
+}
+
+type ThresholdEvent struct {
+	Threshold     Threshold
+	FileSizeBytes int64
+	EntryCount    int64
+}
+
+type thresholdWatch struct {
+	threshold Threshold
+	fn        func(ThresholdEvent)
+	crossed   bool
+}
+
+// OnThreshold calls fn after a write takes the database over the threshold.
+// It fires once per crossing and re-arms when the database drops back below.
+func OnThreshold(threshold Threshold, fn func(ThresholdEvent)) Option {
+	return func(options *Options) {
+		options.thresholds = append(options.thresholds, thresholdWatch{threshold: threshold, fn: fn})
+	}
+}
+
+func (db *Database) checkThresholds() {
+	if len(db.thresholds) == 0 {
+		return
+	}
+
+	var fileSize int64
+	if info, err := os.Stat(db.Path); err == nil {
+		fileSize = info.Size()
+	}
+
+	var entryCount int64
+	for _, watch := range db.thresholds {
+		if watch.threshold.EntryCount > 0 {
+			count, err := db.Count()
+			if err != nil {
+				return
+			}
+			entryCount = count
+			break
+		}
+	}
+
+	var fired []thresholdWatch
+
+	db.thresholdMutex.Lock()
+	for i := range db.thresholds {
+		watch := &db.thresholds[i]
+		exceeded := (watch.threshold.FileSizeBytes > 0 && fileSize >= watch.threshold.FileSizeBytes) ||
+			(watch.threshold.EntryCount > 0 && entryCount >= watch.threshold.EntryCount)
+		if exceeded && !watch.crossed {
+			fired = append(fired, *watch)
+		}
+		watch.crossed = exceeded
+	}
+	db.thresholdMutex.Unlock()
+
+	// Callbacks run outside of any lock so they are free to use the database
+	for _, watch := range fired {
+		watch.fn(ThresholdEvent{
+			Threshold:     watch.threshold,
+			FileSizeBytes: fileSize,
+			EntryCount:    entryCount,
+		})
+	}
+}
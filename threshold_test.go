@@ -0,0 +1,49 @@
+package sidb
+
+import "testing"
+
+func TestOnThreshold(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_threshold"
+
+	var events []ThresholdEvent
+	db, err := Init(namespace, name, OnThreshold(Threshold{EntryCount: 2}, func(event ThresholdEvent) {
+		events = append(events, event)
+	}))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	entryType := "test_type"
+	err = db.Upsert(EntryInput{Type: entryType, Value: []byte("data_1"), Key: "key_1"})
+	if err != nil {
+		t.Fatalf("Failed to put entry: %v", err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("Expected no threshold events, got %d", len(events))
+	}
+
+	err = db.BulkUpsert([]EntryInput{
+		{Type: entryType, Value: []byte("data_2"), Key: "key_2"},
+		{Type: entryType, Value: []byte("data_3"), Key: "key_3"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to put entries: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 threshold event, got %d", len(events))
+	}
+	if events[0].EntryCount != 3 {
+		t.Errorf("Expected entry count 3, got %d", events[0].EntryCount)
+	}
+
+	// Staying above the threshold should not fire again
+	err = db.Upsert(EntryInput{Type: entryType, Value: []byte("data_4"), Key: "key_4"})
+	if err != nil {
+		t.Fatalf("Failed to put entry: %v", err)
+	}
+	if len(events) != 1 {
+		t.Errorf("Expected 1 threshold event after staying above, got %d", len(events))
+	}
+}
@@ -0,0 +1,104 @@
+package sidb
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+var ErrInvalidTransition = errors.New("entry is not in the expected state")
+
+const defaultStatusPath = "$.status"
+
+// WithStatusPath sets the JSON path of the status field of a type used by
+// Transition, "$.status" by default.
+func WithStatusPath(entryType string, path string) Option {
+	return func(options *Options) {
+		if options.statusPaths == nil {
+			options.statusPaths = make(map[string]string)
+		}
+		options.statusPaths[entryType] = path
+	}
+}
+
+func (db *Database) statusPath(entryType string) string {
+	if path, ok := db.options.statusPaths[entryType]; ok {
+		return path
+	}
+	return defaultStatusPath
+}
+
+// Transition sets the status of a JSON entry to `to` if it is currently
+// `from`, or returns ErrInvalidTransition (ErrEntryNotFound if the key does
+// not exist). An empty from matches an entry without a status.
+func (db *Database) Transition(entryType string, key string, from string, to string) (err error) {
+	defer translateImmutable(&err)
+
+	defer db.afterWrite(entryType)
+
+	defer db.lockWrite(false, entryType)()
+
+	if db.connection == nil {
+		return ErrNoDbConnection
+	}
+
+	ctx, done := db.writeContext()
+	defer done()
+
+	tx, err := db.connection.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := db.verifyStored(ctx, tx, entryType, key); err != nil {
+		return err
+	}
+
+	path := db.statusPath(entryType)
+	condition := "json_extract(CAST(value AS TEXT), ?) = ?"
+	args := []interface{}{path, to, db.now(), entryType, key, path, from}
+	if from == "" {
+		condition = "json_extract(CAST(value AS TEXT), ?) IS NULL"
+		args = args[:len(args)-1]
+	}
+
+	result, err := tx.ExecContext(ctx, `UPDATE entries SET value = CAST(json_set(CAST(value AS TEXT), ?, ?) AS BLOB), projection = NULL, version = version + 1, updatedAt = ?
+		WHERE type = ? AND key = ? AND deletedAt IS NULL AND `+condition, args...)
+	if err != nil {
+		return err
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if updated == 0 {
+		var current *string
+		err := tx.QueryRowContext(ctx, "SELECT json_extract(CAST(value AS TEXT), ?) FROM entries WHERE type = ? AND key = ? AND deletedAt IS NULL", path, entryType, key).Scan(&current)
+		if err == sql.ErrNoRows {
+			return ErrEntryNotFound
+		}
+		if err != nil {
+			return err
+		}
+		state := "none"
+		if current != nil {
+			state = *current
+		}
+		return fmt.Errorf("%w: %s/%s is %s, not %s", ErrInvalidTransition, entryType, key, state, from)
+	}
+
+	if err := db.checkQuotas(ctx, tx, entryType); err != nil {
+		return err
+	}
+
+	if err := db.resign(ctx, tx, entryType, key); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (store *Store[T]) Transition(key string, from string, to string) error {
+	return store.db.Transition(store.entryType, store.key(key), from, to)
+}
@@ -0,0 +1,75 @@
+package sidb
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTransition(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_transition"
+	db, err := Init(namespace, name, WithStatusPath("job", "$.state.name"))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	if err := db.Upsert(EntryInput{Type: "task", Key: "a", Value: []byte(`{"title":"write"}`)}); err != nil {
+		t.Fatalf("Failed to put entry: %v", err)
+	}
+
+	if err := db.Transition("task", "a", "", "todo"); err != nil {
+		t.Fatalf("Failed to transition from no status: %v", err)
+	}
+	if err := db.Transition("task", "a", "todo", "doing"); err != nil {
+		t.Fatalf("Failed to transition: %v", err)
+	}
+	err = db.Transition("task", "a", "todo", "done")
+	if !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("Expected ErrInvalidTransition, got %v", err)
+	}
+
+	entry, err := db.Get("task", "a")
+	if err != nil {
+		t.Fatalf("Failed to get entry: %v", err)
+	}
+	if string(entry.Value) != `{"title":"write","status":"doing"}` || entry.Version != 3 {
+		t.Errorf("Unexpected entry after transitions: %s version %d", entry.Value, entry.Version)
+	}
+
+	if err := db.Upsert(EntryInput{Type: "job", Key: "b", Value: []byte(`{"state":{"name":"queued"}}`)}); err != nil {
+		t.Fatalf("Failed to put entry: %v", err)
+	}
+	if err := db.Transition("job", "b", "queued", "running"); err != nil {
+		t.Errorf("Failed to transition with a custom status path: %v", err)
+	}
+
+	if err := db.Transition("task", "missing", "", "todo"); err != ErrEntryNotFound {
+		t.Errorf("Expected ErrEntryNotFound for a missing entry, got %v", err)
+	}
+}
+
+func TestTransitionSignedAndQuota(t *testing.T) {
+	db, err := Init([]string{"test_namespace"}, "test_transition_checks", WithSigningKey([]byte("secret"), "task"), WithQuota("task", 40, QuotaReject))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	if err := db.Upsert(EntryInput{Type: "task", Key: "a", Value: []byte(`{"title":"write"}`)}); err != nil {
+		t.Fatalf("Failed to put entry: %v", err)
+	}
+	if err := db.Transition("task", "a", "", "a status long enough to exceed the quota"); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Expected ErrQuotaExceeded, got %v", err)
+	}
+
+	if _, err := db.RawExec(`UPDATE entries SET value = '{"title":"forged"}' WHERE type = 'task'`); err != nil {
+		t.Fatalf("Failed to tamper with entry: %v", err)
+	}
+	if err := db.Transition("task", "a", "", "todo"); err != ErrTampered {
+		t.Errorf("Expected ErrTampered, got %v", err)
+	}
+	if _, err := db.Get("task", "a"); err != ErrTampered {
+		t.Errorf("Expected the entry to still be reported as tampered, got %v", err)
+	}
+}
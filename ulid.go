@@ -0,0 +1,74 @@
+package sidb
+
+import (
+	"crypto/rand"
+	"sync"
+)
+
+const crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// The random part of the last ULID, incremented rather than redrawn for the
+// same millisecond so IDs from one process keep increasing.
+var ulidMutex sync.Mutex
+var lastULIDTime int64 = -1
+var lastULIDRandom [10]byte
+
+// NewULID returns a ULID for the given Unix millisecond time: 26 characters
+// of Crockford base32, a 48 bit timestamp followed by 80 random bits. ULIDs
+// sort by time as plain strings, and ones generated by this process within
+// the same millisecond sort in generation order.
+func NewULID(timestamp int64) string {
+	var id [16]byte
+
+	ulidMutex.Lock()
+	if timestamp == lastULIDTime {
+		for i := len(lastULIDRandom) - 1; i >= 0; i-- {
+			lastULIDRandom[i]++
+			if lastULIDRandom[i] != 0 {
+				break
+			}
+		}
+	} else {
+		rand.Read(lastULIDRandom[:])
+		lastULIDTime = timestamp
+	}
+	copy(id[6:], lastULIDRandom[:])
+	ulidMutex.Unlock()
+
+	for i := 5; i >= 0; i-- {
+		id[i] = byte(timestamp)
+		timestamp >>= 8
+	}
+
+	// 128 bits in 26 characters of 5 bits, with 2 leading zero bits
+	var encoded [26]byte
+	for i := 25; i >= 0; i-- {
+		bit := 128 - 5*(25-i) - 5
+		var value byte
+		for b := 0; b < 5; b++ {
+			position := bit + b
+			if position >= 0 && id[position/8]&(0x80>>(position%8)) != 0 {
+				value |= 0x10 >> b
+			}
+		}
+		encoded[i] = crockfordAlphabet[value]
+	}
+	return string(encoded[:])
+}
+
+// UpsertAutoKey upserts the entry, generating a ULID key from the current
+// time when its key is empty, and returns the key used.
+func (db *Database) UpsertAutoKey(entry EntryInput) (string, error) {
+	if entry.Key == "" {
+		entry.Key = NewULID(db.now())
+	}
+	return entry.Key, db.Upsert(entry)
+}
+
+func (store *Store[T]) UpsertAutoKey(entry StoreEntryInput[T]) (string, error) {
+	input, err := store.entryInput(entry)
+	if err != nil {
+		return "", err
+	}
+	return store.db.UpsertAutoKey(input)
+}
@@ -0,0 +1,65 @@
+package sidb
+
+import "testing"
+
+func TestNewULID(t *testing.T) {
+	// The example from the ULID specification
+	if id := NewULID(1469918176385); id[:10] != "01ARYZ6S41" || len(id) != 26 {
+		t.Errorf("Expected time prefix 01ARYZ6S41, got %s", id)
+	}
+	// The maximum 48 bit timestamp
+	if id := NewULID(1<<48 - 1); id[:10] != "7ZZZZZZZZZ" {
+		t.Errorf("Expected the maximum time prefix, got %s", id)
+	}
+
+	earlier := NewULID(1700000000000)
+	later := NewULID(1700000000001)
+	if earlier >= later {
+		t.Errorf("Expected %s to sort before %s", earlier, later)
+	}
+
+	// Within a millisecond, IDs follow generation order
+	previous := NewULID(1700000000002)
+	for i := 0; i < 100; i++ {
+		next := NewULID(1700000000002)
+		if next <= previous {
+			t.Fatalf("Expected %s to sort after %s", next, previous)
+		}
+		previous = next
+	}
+}
+
+func TestUpsertAutoKey(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_ulid"
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	store := MakeStore(db, "test_type", serializeTestItem, deserializeTestItem, nil)
+	key, err := store.UpsertAutoKey(StoreEntryInput[testItem]{Value: testItem{Name: "item", Value: 1}})
+	if err != nil {
+		t.Fatalf("Failed to upsert: %v", err)
+	}
+	if len(key) != 26 {
+		t.Errorf("Expected a generated ULID, got %q", key)
+	}
+
+	item, err := store.Get(key)
+	if err != nil {
+		t.Fatalf("Failed to get entry: %v", err)
+	}
+	if item.Name != "item" {
+		t.Errorf("Expected item under the generated key, got %+v", item)
+	}
+
+	key, err = db.UpsertAutoKey(EntryInput{Type: "test_type", Key: "given", Value: []byte("data")})
+	if err != nil {
+		t.Fatalf("Failed to upsert: %v", err)
+	}
+	if key != "given" {
+		t.Errorf("Expected the given key to be kept, got %q", key)
+	}
+}
@@ -0,0 +1,101 @@
+package sidb
+
+import (
+	"fmt"
+	"time"
+)
+
+type AutoVacuum int
+
+// The values match SQLite's auto_vacuum pragma.
+const (
+	AutoVacuumNone AutoVacuum = iota
+	AutoVacuumFull
+	AutoVacuumIncremental
+)
+
+// WithAutoVacuum sets how SQLite returns freed pages to the file system. With
+// AutoVacuumFull the file shrinks on every commit, with AutoVacuumIncremental
+// only when IncrementalVacuum is called. Init converts an existing database
+// with a full Vacuum when the mode changes.
+func WithAutoVacuum(mode AutoVacuum) Option {
+	return func(options *Options) {
+		options.autoVacuum = &mode
+	}
+}
+
+// applyAutoVacuum vacuums the database if its mode differs from the option,
+// and returns the event to emit when it did.
+func (db *Database) applyAutoVacuum() ([]LifecycleEvent, error) {
+	if db.options.autoVacuum == nil {
+		return nil, nil
+	}
+
+	var current AutoVacuum
+	if err := db.connection.QueryRow("PRAGMA auto_vacuum").Scan(&current); err != nil {
+		return nil, err
+	}
+	if current == *db.options.autoVacuum {
+		return nil, nil
+	}
+
+	// The connection already asks for the new mode, VACUUM applies it
+	started := time.Now()
+	if _, err := db.connection.Exec("VACUUM"); err != nil {
+		return nil, db.options.reportCorruption(db.Path, err)
+	}
+	return []LifecycleEvent{{Kind: EventVacuumed, Path: db.Path, Duration: time.Since(started)}}, nil
+}
+
+func autoVacuumParam(mode AutoVacuum) string {
+	return fmt.Sprintf("_auto_vacuum=%d", mode)
+}
+
+// Vacuum rebuilds the database file, returning all free pages to the file
+// system. It needs as much free disk space as the database takes and blocks
+// reads and writes until it is done.
+func (db *Database) Vacuum() (err error) {
+	started := time.Now()
+	defer func() {
+		if err == nil {
+			db.emit(EventVacuumed, started, "")
+		}
+	}()
+
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if db.connection == nil {
+		return ErrNoDbConnection
+	}
+
+	ctx, done := db.writeContext()
+	defer done()
+
+	_, err = db.connection.ExecContext(ctx, "VACUUM")
+	return db.options.reportCorruption(db.Path, err)
+}
+
+// IncrementalVacuum returns up to pages free pages to the file system, or all
+// of them when pages is 0. It only has an effect with AutoVacuumIncremental.
+func (db *Database) IncrementalVacuum(pages int) error {
+	defer db.lockWrite(false)()
+
+	if db.connection == nil {
+		return ErrNoDbConnection
+	}
+
+	ctx, done := db.writeContext()
+	defer done()
+
+	// The pragma frees one page per step, so its rows must be read to the end
+	rows, err := db.connection.QueryContext(ctx, fmt.Sprintf("PRAGMA incremental_vacuum(%d)", pages))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+	}
+	return rows.Err()
+}
@@ -0,0 +1,88 @@
+package sidb
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func pageCount(t *testing.T, db *Database) int64 {
+	var count int64
+	if err := db.connection.QueryRow("PRAGMA page_count").Scan(&count); err != nil {
+		t.Fatalf("Failed to read page count: %v", err)
+	}
+	return count
+}
+
+func fillAndDelete(t *testing.T, db *Database) {
+	var entries []EntryInput
+	for i := 0; i < 200; i++ {
+		entries = append(entries, EntryInput{Type: "blob", Key: fmt.Sprintf("key_%d", i), Value: bytes.Repeat([]byte("x"), 4096)})
+	}
+	if err := db.BulkUpsert(entries); err != nil {
+		t.Fatalf("Failed to put entries: %v", err)
+	}
+	blobType := "blob"
+	if _, err := db.DeleteWhere(QueryParams{Type: &blobType}); err != nil {
+		t.Fatalf("Failed to delete entries: %v", err)
+	}
+}
+
+func TestVacuum(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_vacuum"
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	fillAndDelete(t, db)
+	before := pageCount(t, db)
+	if err := db.Vacuum(); err != nil {
+		t.Fatalf("Failed to vacuum: %v", err)
+	}
+	if after := pageCount(t, db); after >= before {
+		t.Errorf("Expected vacuum to shrink the database from %d pages, got %d", before, after)
+	}
+}
+
+func TestIncrementalVacuum(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_incremental_vacuum"
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	db.Close()
+
+	// Reopening converts the existing database
+	db, err = Init(namespace, name, WithAutoVacuum(AutoVacuumIncremental))
+	if err != nil {
+		t.Fatalf("Failed to reopen database: %v", err)
+	}
+	defer db.Drop()
+
+	var mode AutoVacuum
+	if err := db.connection.QueryRow("PRAGMA auto_vacuum").Scan(&mode); err != nil {
+		t.Fatalf("Failed to read auto_vacuum: %v", err)
+	}
+	if mode != AutoVacuumIncremental {
+		t.Fatalf("Expected incremental auto vacuum, got %d", mode)
+	}
+
+	fillAndDelete(t, db)
+	before := pageCount(t, db)
+	if err := db.IncrementalVacuum(10); err != nil {
+		t.Fatalf("Failed to vacuum: %v", err)
+	}
+	if after := pageCount(t, db); after != before-10 {
+		t.Errorf("Expected 10 pages to be freed from %d, got %d", before, after)
+	}
+	if err := db.IncrementalVacuum(0); err != nil {
+		t.Fatalf("Failed to vacuum: %v", err)
+	}
+	if after := pageCount(t, db); after >= before-10 {
+		t.Errorf("Expected the remaining free pages to be freed, got %d", after)
+	}
+}
@@ -0,0 +1,83 @@
+package sidb
+
+import (
+	"database/sql"
+	"errors"
+)
+
+var ErrOutOfRange = errors.New("range is outside the stored value")
+
+// PatchValueRange overwrites the bytes of a value starting at offset with
+// data, for fixed layout binary values such as bitmaps or ring buffers. The
+// range must lie within the stored value, which keeps its length. The splice
+// happens inside SQLite, so the value never travels through Go, but the row
+// is still rewritten: entries is a WITHOUT ROWID table, which SQLite's
+// incremental blob I/O does not support.
+func (db *Database) PatchValueRange(entryType string, key string, offset int64, data []byte) (err error) {
+	defer translateImmutable(&err)
+
+	defer db.afterWrite(entryType)
+
+	defer db.lockWrite(false, entryType)()
+
+	if db.connection == nil {
+		return ErrNoDbConnection
+	}
+
+	if offset < 0 {
+		return ErrOutOfRange
+	}
+
+	ctx, done := db.writeContext()
+	defer done()
+
+	tx, err := db.connection.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := db.verifyStored(ctx, tx, entryType, key); err != nil {
+		return err
+	}
+
+	// substr counts from 1, and the projection was derived from the old value
+	end := offset + int64(len(data))
+	result, err := tx.ExecContext(ctx, `UPDATE entries
+		SET value = CAST(substr(value, 1, ?) || ? || substr(value, ?) AS BLOB), projection = NULL, version = version + 1, updatedAt = ?
+		WHERE type = ? AND key = ? AND deletedAt IS NULL AND length(CAST(value AS BLOB)) >= ?`,
+		offset, data, end+1, db.now(), entryType, key, end)
+	if err != nil {
+		return err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		var exists int
+		err := tx.QueryRowContext(ctx, "SELECT 1 FROM entries WHERE type = ? AND key = ? AND deletedAt IS NULL", entryType, key).Scan(&exists)
+		if err == sql.ErrNoRows {
+			return ErrEntryNotFound
+		}
+		if err != nil {
+			return err
+		}
+		return ErrOutOfRange
+	}
+
+	if err := db.checkQuotas(ctx, tx, entryType); err != nil {
+		return err
+	}
+
+	if err := db.resign(ctx, tx, entryType, key); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (store *Store[T]) PatchValueRange(key string, offset int64, data []byte) error {
+	return store.db.PatchValueRange(store.entryType, store.key(key), offset, data)
+}
@@ -0,0 +1,92 @@
+package sidb
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestPatchValueRange(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_patch_value_range"
+	db, err := Init(namespace, name, WithSigningKey([]byte("secret")))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	// Zero bytes and invalid UTF-8 must survive the splice
+	bitmap := make([]byte, 1<<20)
+	bitmap[0] = 0xff
+	if err := db.Upsert(EntryInput{Type: "bitmap", Key: "a", Value: bitmap}); err != nil {
+		t.Fatalf("Failed to upsert: %v", err)
+	}
+
+	if err := db.PatchValueRange("bitmap", "a", 1000, []byte{0x00, 0xfe, 0x80}); err != nil {
+		t.Fatalf("Failed to patch: %v", err)
+	}
+	if err := db.PatchValueRange("bitmap", "a", int64(len(bitmap)-1), []byte{0x01}); err != nil {
+		t.Fatalf("Failed to patch the last byte: %v", err)
+	}
+	copy(bitmap[1000:], []byte{0x00, 0xfe, 0x80})
+	bitmap[len(bitmap)-1] = 0x01
+
+	entry, err := db.Get("bitmap", "a")
+	if err != nil {
+		t.Fatalf("Failed to get: %v", err)
+	}
+	if !bytes.Equal(entry.Value, bitmap) {
+		t.Errorf("Patched value does not match")
+	}
+	if entry.Version != 3 {
+		t.Errorf("Expected version 3, got %d", entry.Version)
+	}
+
+	if err := db.PatchValueRange("bitmap", "a", int64(len(bitmap)-1), []byte{0x01, 0x02}); err != ErrOutOfRange {
+		t.Errorf("Expected ErrOutOfRange past the end, got %v", err)
+	}
+	if err := db.PatchValueRange("bitmap", "a", -1, []byte{0x01}); err != ErrOutOfRange {
+		t.Errorf("Expected ErrOutOfRange for a negative offset, got %v", err)
+	}
+	if err := db.PatchValueRange("bitmap", "missing", 0, []byte{0x01}); err != ErrEntryNotFound {
+		t.Errorf("Expected ErrEntryNotFound, got %v", err)
+	}
+
+	if err := db.Upsert(EntryInput{Type: "bitmap", Key: "frozen", Value: []byte{0, 0}, Immutable: true}); err != nil {
+		t.Fatalf("Failed to upsert: %v", err)
+	}
+	if err := db.PatchValueRange("bitmap", "frozen", 0, []byte{0x01}); err != ErrImmutable {
+		t.Errorf("Expected ErrImmutable, got %v", err)
+	}
+}
+
+func TestPatchValueRangeChecks(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_patch_value_range_checks"
+	db, err := Init(namespace, name, WithSigningKey([]byte("secret")))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	if err := db.Upsert(EntryInput{Type: "bitmap", Key: "a", Value: make([]byte, 100)}); err != nil {
+		t.Fatalf("Failed to upsert: %v", err)
+	}
+	if _, err := db.RawExec("UPDATE entries SET value = randomblob(100) WHERE type = 'bitmap'"); err != nil {
+		t.Fatalf("Failed to tamper with entry: %v", err)
+	}
+	if err := db.PatchValueRange("bitmap", "a", 0, []byte{0xff}); err != ErrTampered {
+		t.Errorf("Expected ErrTampered, got %v", err)
+	}
+	db.Close()
+
+	// A quota lowered below the current usage rejects further writes
+	limited, err := Init(namespace, name, WithQuota("bitmap", 50, QuotaReject))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer limited.Close()
+	if err := limited.PatchValueRange("bitmap", "a", 0, []byte{0xff}); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Expected ErrQuotaExceeded, got %v", err)
+	}
+}
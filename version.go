@@ -0,0 +1,82 @@
+package sidb
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// A VersionConflictError is returned when an entry's version is not the one
+// the caller expected. It matches ErrConflict with errors.Is.
+type VersionConflictError struct {
+	Type     string
+	Key      string
+	Expected int64
+	Actual   int64 // 0 when the entry does not exist
+}
+
+func (err *VersionConflictError) Error() string {
+	return fmt.Sprintf("version conflict on %s/%s: expected %d, found %d", err.Type, err.Key, err.Expected, err.Actual)
+}
+
+func (err *VersionConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// UpsertIfVersion writes the entry only if its stored version is
+// expectedVersion. An expectedVersion of 0 only creates the entry.
+func (db *Database) UpsertIfVersion(entry EntryInput, expectedVersion int64) error {
+	return db.conditionalUpsert(entry, func(found bool, timestamp int64, version int64) error {
+		if version != expectedVersion {
+			return &VersionConflictError{Type: entry.Type, Key: entry.Key, Expected: expectedVersion, Actual: version}
+		}
+		return nil
+	})
+}
+
+// UpdateIfVersion replaces the value of an existing entry only if its stored
+// version is expectedVersion.
+func (db *Database) UpdateIfVersion(entry EntryInput, expectedVersion int64) (err error) {
+	defer translateImmutable(&err)
+
+	defer db.afterWrite(entry.Type)
+
+	defer db.lockWrite(false, entry.Type)()
+
+	if db.connection == nil {
+		return ErrNoDbConnection
+	}
+
+	ctx, done := db.writeContext()
+	defer done()
+
+	tx, err := db.connection.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "UPDATE entries SET value = ?, signature = ?, projection = NULL, version = version + 1, updatedAt = ? WHERE key = ? AND type = ? AND version = ? AND deletedAt IS NULL",
+		entry.Value, db.sign(entry.Type, entry.Key, entry.Value), db.now(), entry.Key, entry.Type, expectedVersion)
+	if err != nil {
+		return err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		var actual int64
+		err := tx.QueryRowContext(ctx, "SELECT version FROM entries WHERE type = ? AND key = ? AND deletedAt IS NULL", entry.Type, entry.Key).Scan(&actual)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		return &VersionConflictError{Type: entry.Type, Key: entry.Key, Expected: expectedVersion, Actual: actual}
+	}
+
+	if err := db.checkQuotas(ctx, tx, entry.Type); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,58 @@
+package sidb
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestVersionedWrites(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_version"
+	db, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Drop()
+
+	entryType := "test_type"
+	if err := db.UpsertIfVersion(EntryInput{Type: entryType, Value: []byte("v1"), Key: "doc"}, 0); err != nil {
+		t.Fatalf("Failed to create entry: %v", err)
+	}
+
+	entry, err := db.Get(entryType, "doc")
+	if err != nil {
+		t.Fatalf("Failed to get entry: %v", err)
+	}
+	if entry.Version != 1 {
+		t.Fatalf("Expected version 1, got %d", entry.Version)
+	}
+
+	if err := db.Upsert(EntryInput{Type: entryType, Value: []byte("v2"), Key: "doc"}); err != nil {
+		t.Fatalf("Failed to put entry: %v", err)
+	}
+	if err := db.UpdateIfVersion(EntryInput{Type: entryType, Value: []byte("v3"), Key: "doc"}, 2); err != nil {
+		t.Fatalf("Failed to update entry at version 2: %v", err)
+	}
+
+	err = db.UpsertIfVersion(EntryInput{Type: entryType, Value: []byte("stale"), Key: "doc"}, 2)
+	var conflict *VersionConflictError
+	if !errors.As(err, &conflict) || conflict.Actual != 3 || conflict.Expected != 2 {
+		t.Fatalf("Expected a version conflict at 3, got %v", err)
+	}
+	if !errors.Is(err, ErrConflict) {
+		t.Errorf("Expected version conflicts to match ErrConflict")
+	}
+
+	err = db.UpdateIfVersion(EntryInput{Type: entryType, Value: []byte("stale"), Key: "missing"}, 1)
+	if !errors.As(err, &conflict) || conflict.Actual != 0 {
+		t.Errorf("Expected a version conflict for a missing entry, got %v", err)
+	}
+
+	entry, err = db.Get(entryType, "doc")
+	if err != nil {
+		t.Fatalf("Failed to get entry: %v", err)
+	}
+	if string(entry.Value) != "v3" || entry.Version != 3 {
+		t.Errorf("Unexpected entry: %+v", entry)
+	}
+}
@@ -0,0 +1,80 @@
+package sidb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var ErrWriterLocked = errors.New("database is open in another process")
+
+// WriterLockedError is returned by Init when another process opened the
+// database with WithSingleWriter. It matches ErrWriterLocked.
+type WriterLockedError struct {
+	Path string
+	PID  int // The process holding the lock, 0 if unknown
+}
+
+func (err *WriterLockedError) Error() string {
+	if err.PID == 0 {
+		return fmt.Sprintf("%s: %v", err.Path, ErrWriterLocked)
+	}
+	return fmt.Sprintf("%s: %v (pid %d)", err.Path, ErrWriterLocked, err.PID)
+}
+
+func (err *WriterLockedError) Is(target error) bool {
+	return target == ErrWriterLocked
+}
+
+var errFileLocked = errors.New("file is locked")
+
+// WithSingleWriter makes Init take an advisory lock on a .lock file next to
+// the database, failing with a WriterLockedError while another process holds
+// it. The lock is released by Close, or by the OS when the process exits, so
+// a crash never leaves it behind. Processes that open the database without
+// this option are not kept out.
+func WithSingleWriter() Option {
+	return func(options *Options) {
+		options.singleWriter = true
+	}
+}
+
+func writerLockPath(dbPath string) string {
+	return dbPath + ".lock"
+}
+
+func acquireWriterLock(dbPath string, mode os.FileMode) (*os.File, error) {
+	if mode == 0 {
+		mode = 0644
+	}
+	file, err := os.OpenFile(writerLockPath(dbPath), os.O_RDWR|os.O_CREATE, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := lockFile(file); err != nil {
+		defer file.Close()
+		if err != errFileLocked {
+			return nil, err
+		}
+		// The holder wrote its pid, which only serves the error message
+		content, _ := os.ReadFile(file.Name())
+		pid, _ := strconv.Atoi(strings.TrimSpace(string(content)))
+		return nil, &WriterLockedError{Path: dbPath, PID: pid}
+	}
+
+	if err := file.Truncate(0); err == nil {
+		file.WriteAt([]byte(strconv.Itoa(os.Getpid())), 0)
+	}
+	return file, nil
+}
+
+func releaseWriterLock(file *os.File) error {
+	if file == nil {
+		return nil
+	}
+	unlockFile(file)
+	return file.Close()
+}
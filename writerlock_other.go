@@ -0,0 +1,16 @@
+//go:build !unix
+
+package sidb
+
+import (
+	"errors"
+	"os"
+)
+
+func lockFile(file *os.File) error {
+	return errors.New("WithSingleWriter is not supported on this platform")
+}
+
+func unlockFile(file *os.File) error {
+	return nil
+}
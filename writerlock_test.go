@@ -0,0 +1,45 @@
+//go:build unix
+
+package sidb
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestSingleWriter(t *testing.T) {
+	namespace := []string{"test_namespace"}
+	name := "test_single_writer"
+	first, err := Init(namespace, name, WithSingleWriter())
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer first.Drop()
+
+	// The lock is per open file, so a second Init stands in for another process
+	_, err = Init(namespace, name, WithSingleWriter())
+	if !errors.Is(err, ErrWriterLocked) {
+		t.Fatalf("Expected ErrWriterLocked, got %v", err)
+	}
+	var locked *WriterLockedError
+	if !errors.As(err, &locked) || locked.PID != os.Getpid() || locked.Path != first.Path {
+		t.Errorf("Unexpected error: %+v", err)
+	}
+
+	// Opening without the option is not prevented
+	reader, err := Init(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	reader.Close()
+
+	if err := first.Close(); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+	second, err := Init(namespace, name, WithSingleWriter())
+	if err != nil {
+		t.Fatalf("Expected the lock to be released by Close, got %v", err)
+	}
+	second.Close()
+}